@@ -0,0 +1,407 @@
+package coquery
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+// ErrAccessDenied is held by the *CoError returned when a query is denied by
+// the Authorizer of its route.
+var ErrAccessDenied = errors.New("Access Denied")
+
+// Authorizer defines a interface for authorizing the requests generated for a
+// query against a document of a route, given the principal making them which
+// is nil for anonymous callers. Returning an error denies the query, else the
+// Grant returned sets the field rules the query is served under.
+type Authorizer interface {
+	Authorize(context interface{}, p *data.Principal, doc string, reqs RecordRequests) (*Grant, error)
+}
+
+// Grant defines the field rules an authorized query is served under. Hidden
+// fields are stripped from the records replied and queries reading them eg to
+// filter, sort or aggregate records are rejected, while writes to Protected
+// fields are rejected. Fields may be dotted eg address.city, where protecting
+// a field also protects the fields within it.
+type Grant struct {
+	Hidden    []string
+	Protected []string
+}
+
+// PrincipalUser defines a interface for requests which need the principal
+// making them eg to record who changed a record.
+type PrincipalUser interface {
+	UsePrincipal(*data.Principal)
+}
+
+// FieldWriter defines a interface for requests which write record fields,
+// listing the fields they write.
+type FieldWriter interface {
+	WrittenFields() []string
+}
+
+// FieldReader defines a interface for requests whose results depend on the
+// values of record fields, listing the fields they read.
+type FieldReader interface {
+	ReadFields() []string
+}
+
+//==============================================================================
+
+// WrittenFields returns the fields of the records being created.
+func (f *Create) WrittenFields() []string {
+	var fields []string
+
+	for _, rec := range f.Records {
+		for key := range rec {
+			fields = append(fields, key)
+		}
+	}
+
+	return fields
+}
+
+// WrittenFields returns the fields changed by the mutation.
+func (f *Mutate) WrittenFields() []string {
+	var fields []string
+
+	if f.Update == nil {
+		for key := range f.Parameter {
+			fields = append(fields, key)
+		}
+
+		return fields
+	}
+
+	for _, ops := range []map[string]interface{}{f.Update.Set, f.Update.Inc, f.Update.Push, f.Update.Pull} {
+		for key := range ops {
+			fields = append(fields, key)
+		}
+	}
+
+	return append(fields, f.Update.Unset...)
+}
+
+//==============================================================================
+
+// ReadFields returns the field the records are found by.
+func (f *Find) ReadFields() []string {
+	return []string{f.Key}
+}
+
+// ReadFields returns the fields compared by the predicate.
+func (f *Where) ReadFields() []string {
+	return f.Predicate.Fields()
+}
+
+// ReadFields returns the fields the records are ordered by.
+func (f *Sort) ReadFields() []string {
+	return sortFields(f.Fields)
+}
+
+// ReadFields returns the fields the records are filtered and ordered by.
+func (f *Page) ReadFields() []string {
+	return append(sortFields(f.Sort), f.Match.Fields()...)
+}
+
+// ReadFields returns the fields aggregated, grouped and filtered by.
+func (f *Aggregate) ReadFields() []string {
+	var fields []string
+
+	for _, field := range []string{f.Field, f.Group} {
+		if field != "" {
+			fields = append(fields, field)
+		}
+	}
+
+	return append(fields, f.Match.Fields()...)
+}
+
+// ReadFields returns the field holding the values the records are joined by.
+func (f *Join) ReadFields() []string {
+	return []string{f.Local}
+}
+
+// sortFields returns the keys of the sort fields.
+func sortFields(sort []SortField) []string {
+	var fields []string
+
+	for _, field := range sort {
+		fields = append(fields, field.Key)
+	}
+
+	return fields
+}
+
+//==============================================================================
+
+// authorize returns a *CoError denying the requests if the authorizer denies
+// them, they read a field hidden by the grant or write a field protected by
+// it.
+func authorize(context interface{}, rid string, auth Authorizer, p *data.Principal, doc string, reqs RecordRequests) (*Grant, ResponseError) {
+	grant, err := auth.Authorize(context, p, doc, reqs)
+	if err != nil {
+		return nil, &CoError{
+			Rid:    rid,
+			Msg:    err.Error(),
+			IError: ErrAccessDenied,
+		}
+	}
+
+	if grant == nil {
+		return nil, nil
+	}
+
+	for _, req := range reqs {
+		fr, ok := req.(FieldReader)
+		if !ok {
+			continue
+		}
+
+		for _, field := range fr.ReadFields() {
+			for _, hidden := range grant.Hidden {
+				if withinField(field, hidden) || withinField(hidden, field) {
+					return nil, &CoError{
+						Rid:    rid,
+						Msg:    fmt.Sprintf("Field[%s] Is Hidden", hidden),
+						IError: ErrAccessDenied,
+					}
+				}
+			}
+		}
+	}
+
+	for _, req := range reqs {
+		fw, ok := req.(FieldWriter)
+		if !ok {
+			continue
+		}
+
+		for _, field := range fw.WrittenFields() {
+			for _, protected := range grant.Protected {
+				if withinField(field, protected) || withinField(protected, field) {
+					return nil, &CoError{
+						Rid:    rid,
+						Msg:    fmt.Sprintf("Field[%s] Is Protected", protected),
+						IError: ErrAccessDenied,
+					}
+				}
+			}
+		}
+	}
+
+	return grant, nil
+}
+
+// withinField returns true/false if the field is the parent field or a field
+// within it eg address.city is within address.
+func withinField(field string, parent string) bool {
+	return field == parent || strings.HasPrefix(field, parent+".")
+}
+
+//==============================================================================
+
+// hidingWriter provides a ResponseWriter which strips the fields hidden by the
+// grant from the records of the responses written to it, setting the grant of
+// the responses.
+type hidingWriter struct {
+	ResponseWriter
+	grant *Grant
+}
+
+// Write strips the hidden fields from the response's records before writing
+// it, leaving the records given untouched.
+func (h *hidingWriter) Write(context interface{}, res *Response, err ResponseError) error {
+	if res == nil {
+		return h.ResponseWriter.Write(context, res, err)
+	}
+
+	cp := *res
+	cp.Grant = h.grant
+
+	if len(h.grant.Hidden) > 0 {
+		var records data.Parameters
+
+		for _, rec := range res.Data {
+			records = append(records, hideFields(rec, h.grant.Hidden))
+		}
+
+		cp.Data = records
+	}
+
+	return h.ResponseWriter.Write(context, &cp, err)
+}
+
+// hidePatch returns a copy of the patch without the operations changing the
+// hidden fields or the fields within them, stripping the hidden fields from
+// the values of operations changing their parents eg the whole record.
+func hidePatch(patch data.Patch, fields []string) data.Patch {
+	found := data.Patch{}
+
+	for _, op := range patch {
+		field := op.Field()
+
+		var hide bool
+		var within []string
+
+		for _, hidden := range fields {
+			if field != "" && withinField(field, hidden) {
+				hide = true
+				break
+			}
+
+			switch {
+			case field == "":
+				within = append(within, hidden)
+			case withinField(hidden, field):
+				within = append(within, strings.TrimPrefix(hidden, field+"."))
+			}
+		}
+
+		if hide {
+			continue
+		}
+
+		if len(within) > 0 {
+			switch value := op.Value.(type) {
+			case map[string]interface{}:
+				op.Value = hideFields(value, within)
+			case data.Parameter:
+				op.Value = hideFields(value, within)
+			}
+		}
+
+		found = append(found, op)
+	}
+
+	return found
+}
+
+// mergeGrants returns the grant hiding the fields hidden by either grant,
+// which is nil if both are.
+func mergeGrants(grant *Grant, other *Grant) *Grant {
+	if other == nil {
+		return grant
+	}
+
+	if grant == nil {
+		grant = &Grant{}
+	}
+
+	return &Grant{Hidden: append(append([]string{}, grant.Hidden...), other.Hidden...)}
+}
+
+// hideFields returns a copy of the record without the giving fields, dotted
+// fields reaching into its embedded maps.
+func hideFields(rec data.Parameter, fields []string) data.Parameter {
+	cp := storage.CopyMap(rec)
+
+	for _, field := range fields {
+		keys := strings.Split(field, ".")
+		parent := cp
+
+		for _, key := range keys[:len(keys)-1] {
+			switch next := parent[key].(type) {
+			case map[string]interface{}:
+				parent = next
+			case data.Parameter:
+				nm := storage.CopyMap(next)
+				parent[key] = nm
+				parent = nm
+			default:
+				parent = nil
+			}
+
+			if parent == nil {
+				break
+			}
+		}
+
+		if parent != nil {
+			delete(parent, keys[len(keys)-1])
+		}
+	}
+
+	return cp
+}
+
+//==============================================================================
+
+// Rule defines the access of the principals holding a role to a document. A
+// Role of "*" applies to all principals including anonymous ones, as a Doc of
+// "*" applies to all documents. Methods lists the allowed request names eg
+// find, where, mutate, create, remove, aggregate, allowing all when empty.
+type Rule struct {
+	Role      string
+	Doc       string
+	Methods   []string
+	Hidden    []string
+	Protected []string
+}
+
+// RuleAuthorizer provides a Authorizer which applies the first of its rules
+// matching the document and one of the principal's roles, denying queries no
+// rule matches. Queries joining other documents require a rule allowing the
+// joined documents too, whose records are resolved under the rules of their
+// own route.
+type RuleAuthorizer struct {
+	Rules []Rule
+}
+
+// Authorize implements the Authorizer interface.
+func (r *RuleAuthorizer) Authorize(context interface{}, p *data.Principal, doc string, reqs RecordRequests) (*Grant, error) {
+	rule, ok := r.match(p, doc)
+	if !ok {
+		return nil, fmt.Errorf("Document[%s] Not Allowed", doc)
+	}
+
+	for _, req := range reqs {
+		if join, ok := req.(*Join); ok {
+			if _, ok := r.match(p, storage.LastKey(join.Path)); !ok {
+				return nil, fmt.Errorf("Document[%s] Not Allowed", join.Path)
+			}
+		}
+
+		if len(rule.Methods) == 0 {
+			continue
+		}
+
+		name := req.RequestName()
+
+		var allowed bool
+		for _, method := range rule.Methods {
+			if strings.EqualFold(method, name) {
+				allowed = true
+				break
+			}
+		}
+
+		if !allowed {
+			return nil, fmt.Errorf("Method[%s] Not Allowed On Document[%s]", name, doc)
+		}
+	}
+
+	return &Grant{Hidden: rule.Hidden, Protected: rule.Protected}, nil
+}
+
+// match returns the first rule matching the principal and document.
+func (r *RuleAuthorizer) match(p *data.Principal, doc string) (Rule, bool) {
+	for _, rule := range r.Rules {
+		if rule.Doc != "*" && rule.Doc != doc {
+			continue
+		}
+
+		if rule.Role == "*" || p.HasRole(rule.Role) {
+			return rule, true
+		}
+	}
+
+	return Rule{}, false
+}
+
+//==============================================================================
@@ -0,0 +1,142 @@
+package coquery_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+// TestRuleAuthorizer validates the authorization of queries by the rules of
+// their route.
+func TestRuleAuthorizer(t *testing.T) {
+	t.Logf("Given the need to authorize the queries of a route")
+	{
+		store := storage.New("id")
+		eos := coquery.New(events, coquery.NewDiffs(events), store)
+
+		eos.Route(context, "doc").
+			Document(context, "greetings", &coquery.BasicQueries{EventLog: events, Store: store}, &inMemory{}).
+			Document(context, "records", &coquery.BasicQueries{EventLog: events, Store: store}, &creator{store: store}).
+			UseAuthorizer(context, &coquery.RuleAuthorizer{
+				Rules: []coquery.Rule{
+					{Role: "admin", Doc: "*"},
+					{Role: "reader", Doc: "*", Hidden: []string{"greeting"}},
+					{Role: "*", Doc: "greetings", Methods: []string{"find", "findN"}, Hidden: []string{"greeting"}},
+					{Role: "writer", Doc: "records", Methods: []string{"create"}, Protected: []string{"owner"}},
+				},
+			})
+
+		serve := func(p *data.Principal, query string) (*coquery.Response, coquery.ResponseError) {
+			writer := &spyWriter{
+				Out: make(chan *coquery.Response),
+				Err: make(chan coquery.ResponseError),
+			}
+
+			eos.Serve(context, &data.RequestContext{
+				RequestID: "532UFY",
+				Queries:   []string{query},
+				NoJSON:    true,
+				Principal: p,
+			}, writer)
+
+			select {
+			case res := <-writer.Out:
+				return res, nil
+			case err := <-writer.Err:
+				return nil, err
+			}
+		}
+
+		admin := &data.Principal{ID: "ruth", Roles: []string{"admin"}}
+		writer := &data.Principal{ID: "alex", Roles: []string{"writer"}}
+		reader := &data.Principal{ID: "tobi", Roles: []string{"reader"}}
+
+		t.Logf("\tWhen giving a anonymous query")
+		{
+			res, err := serve(nil, "doc.greetings.find(id,1)")
+			if err != nil {
+				t.Fatalf("\t%s\tShould have allowed the query: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have allowed the query", tests.Success)
+
+			if res.Data[0].Has("greeting") || !res.Data[0].Has("id") {
+				t.Fatalf("\t%s\tShould have hidden the greeting field: %+v", tests.Failed, res.Data[0])
+			}
+			t.Logf("\t%s\tShould have hidden the greeting field", tests.Success)
+
+			if _, err := serve(nil, "doc.greetings.count()"); err == nil {
+				t.Fatalf("\t%s\tShould have denied the aggregate method", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have denied the aggregate method", tests.Success)
+
+			if _, err := serve(nil, "doc.records.create({id:1})"); err == nil {
+				t.Fatalf("\t%s\tShould have denied the records document", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have denied the records document", tests.Success)
+		}
+
+		t.Logf("\tWhen giving a query by a writer")
+		{
+			if _, err := serve(writer, "doc.records.create({id:2})"); err != nil {
+				t.Fatalf("\t%s\tShould have allowed the create: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have allowed the create", tests.Success)
+
+			_, err := serve(writer, "doc.records.create({id:3, owner:'alex'})")
+			if cerr, ok := err.(*coquery.CoError); !ok || cerr.IError != coquery.ErrAccessDenied {
+				t.Fatalf("\t%s\tShould have denied the write of the owner field: %#v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have denied the write of the owner field: %s", tests.Success, err)
+
+			if store.Has("3") {
+				t.Fatalf("\t%s\tShould not have created the record", tests.Failed)
+			}
+			t.Logf("\t%s\tShould not have created the record", tests.Success)
+		}
+
+		t.Logf("\tWhen giving queries by a reader which read a hidden field")
+		{
+			for _, query := range []string{
+				"doc.greetings.find(greeting,'Hello World!')",
+				"doc.greetings.where(id == 1 && greeting == 'Hello World!')",
+				"doc.greetings.sort(-greeting)",
+				"doc.greetings.where(greeting == 'x').sort(id).page(10)",
+				"doc.greetings.groupBy(greeting).count()",
+				"doc.greetings.max(greeting)",
+				"doc.greetings.findN(1).join(records,id,greeting)",
+			} {
+				_, err := serve(reader, query)
+				if cerr, ok := err.(*coquery.CoError); !ok || cerr.IError != coquery.ErrAccessDenied {
+					t.Fatalf("\t%s\tShould have denied the query %q: %#v", tests.Failed, query, err)
+				}
+				t.Logf("\t%s\tShould have denied the query %q: %s", tests.Success, query, err)
+			}
+
+			if _, err := serve(reader, "doc.greetings.where(id == 1)"); err != nil {
+				t.Fatalf("\t%s\tShould have allowed the query reading visible fields: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have allowed the query reading visible fields", tests.Success)
+		}
+
+		t.Logf("\tWhen giving a query by an admin")
+		{
+			res, err := serve(admin, "doc.greetings.find(id,1)")
+			if err != nil {
+				t.Fatalf("\t%s\tShould have allowed the query: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have allowed the query", tests.Success)
+
+			if res.Data[0].Get("greeting") != "Hello World!" {
+				t.Fatalf("\t%s\tShould have replied the greeting field: %+v", tests.Failed, res.Data[0])
+			}
+			t.Logf("\t%s\tShould have replied the greeting field", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
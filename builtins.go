@@ -0,0 +1,384 @@
+package coquery
+
+import (
+	"strings"
+
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/parser"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+func init() {
+	for _, m := range BuiltinMethods() {
+		if err := DefaultMethods.Register(m); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// BuiltinMethods returns the query methods provided by coquery, allowing
+// custom registries to include them.
+func BuiltinMethods() []Method {
+	var methods []Method
+
+	methods = append(methods, Method{
+		Name:     "findN",
+		Args:     []Arg{{Name: "amount", Kind: ArgInt, Optional: true}, {Name: "skip", Kind: ArgInt, Optional: true}},
+		Examples: (&FindN{}).Examples(),
+		Build:    buildFindN,
+	}, Method{
+		Name:     "find",
+		Args:     []Arg{{Name: "key", Kind: ArgName}, {Name: "value", Kind: ArgValue}},
+		Examples: (&Find{}).Examples(),
+		Build:    buildFind,
+	}, Method{
+		Name:     "where",
+		Args:     []Arg{{Name: "filter", Kind: ArgFilter}},
+		Examples: (&Where{}).Examples(),
+		Build:    buildWhere,
+	}, Method{
+		Name:     "sort",
+		Args:     []Arg{{Name: "keys", Kind: ArgSort, Variadic: true}},
+		Examples: (&Sort{}).Examples(),
+		Build:    buildSort,
+	}, Method{
+		Name:     "page",
+		Args:     []Arg{{Name: "size", Kind: ArgInt}, {Name: "cursor", Kind: ArgNode, Optional: true}},
+		Examples: (&Page{}).Examples(),
+		Build:    buildPage,
+	}, Method{
+		Name:     "groupBy",
+		Args:     []Arg{{Name: "key", Kind: ArgName}},
+		Examples: []string{"groupBy(address.state).count()"},
+		Build:    buildGroupBy,
+	}, Method{
+		Name:     AggCount,
+		Examples: []string{"count()", "where(age > 21).count()"},
+		Build:    buildAggregate,
+	})
+
+	for _, op := range []string{AggSum, AggAvg, AggMin, AggMax} {
+		methods = append(methods, Method{
+			Name:     op,
+			Args:     []Arg{{Name: "key", Kind: ArgName}},
+			Examples: []string{op + "(age)", "groupBy(address.state)." + op + "(age)"},
+			Build:    buildAggregate,
+		})
+	}
+
+	methods = append(methods, Method{
+		Name:     "create",
+		Args:     []Arg{{Name: "records", Kind: ArgNode, Variadic: true}},
+		Examples: (&Create{}).Examples(),
+		Write:    true,
+		Build:    buildCreate,
+	}, Method{
+		Name:     "remove",
+		Examples: (&Remove{}).Examples(),
+		Write:    true,
+		Build:    buildRemove,
+	}, Method{
+		Name:     "join",
+		Args:     []Arg{{Name: "doc", Kind: ArgName}, {Name: "key", Kind: ArgName}, {Name: "local", Kind: ArgName, Optional: true}},
+		Examples: []string{"join(books,uid)", "join(docs.books,uid,author_id)"},
+		Build:    buildJoin,
+	}, Method{
+		Name:     "expand",
+		Args:     []Arg{{Name: "reference", Kind: ArgNode}},
+		Examples: []string{"expand(author -> docs.users)", "expand(tags -> tags.slug)"},
+		Build:    buildExpand,
+	}, Method{
+		Name:     "collects",
+		Args:     []Arg{{Name: "keys", Kind: ArgName, Variadic: true, Optional: true}},
+		Examples: (&Collects{}).Examples(),
+		Build:    buildCollects,
+	}, Method{
+		Name:     "mutate",
+		Args:     []Arg{{Name: "data", Kind: ArgObject}},
+		Named:    []Arg{{Name: "ifVersion", Kind: ArgInt}},
+		Examples: (&Mutate{}).Examples(),
+		Write:    true,
+		Build:    buildMutate,
+	})
+
+	return methods
+}
+
+//==============================================================================
+
+// buildFindN builds the FindN request of a findN([amount], [skip]) call.
+func buildFindN(call *MethodCall) (RecordRequests, error) {
+	find := FindN{
+		Doc:    call.Doc,
+		RID:    call.RID,
+		Amount: -1,
+	}
+
+	if len(call.Args) > 0 {
+		find.Amount = call.Args[0].(int)
+	}
+
+	if len(call.Args) > 1 {
+		find.Skip = call.Args[1].(int)
+	}
+
+	return append(call.Reqs, &find), nil
+}
+
+// buildFind builds the Find request of a find(key, value) call.
+func buildFind(call *MethodCall) (RecordRequests, error) {
+	return append(call.Reqs, &Find{
+		Doc:   call.Doc,
+		RID:   call.RID,
+		Key:   call.Args[0].(string),
+		Value: call.Args[1],
+	}), nil
+}
+
+// buildWhere builds the Where request of a where(filter) call.
+func buildWhere(call *MethodCall) (RecordRequests, error) {
+	return append(call.Reqs, &Where{
+		RID:       call.RID,
+		Doc:       call.Doc,
+		Predicate: call.Args[0].(*Predicate),
+	}), nil
+}
+
+// buildSort builds the Sort request of a sort(keys...) call.
+func buildSort(call *MethodCall) (RecordRequests, error) {
+	var fields []SortField
+
+	for _, arg := range call.Args {
+		fields = append(fields, arg.(SortField))
+	}
+
+	return append(call.Reqs, &Sort{
+		RID:    call.RID,
+		Doc:    call.Doc,
+		Fields: fields,
+	}), nil
+}
+
+// buildPage builds the Page request of a page(size, [cursor]) call, folding in
+// a preceding sort and the where before it.
+func buildPage(call *MethodCall) (RecordRequests, error) {
+	size := call.Args[0].(int)
+	if size < 1 {
+		return nil, call.Errorf(0, "page size must be greater than zero")
+	}
+
+	page := Page{
+		RID:  call.RID,
+		Doc:  call.Doc,
+		Size: size,
+	}
+
+	reqs := call.Reqs
+
+	// A directly preceding sort sets the page order, it is folded into
+	// the page, so backends can sort and seek in a single step.
+	if sort, ok := call.Last().(*Sort); ok {
+		page.Sort = sort.Fields
+		reqs = reqs[:len(reqs)-1]
+	}
+
+	// The where before them is folded in as well, so backends filter the
+	// records they seek rather than loading every matching record first.
+	if last := len(reqs) - 1; last > -1 {
+		if where, ok := reqs[last].(*Where); ok {
+			page.Match = where.Predicate
+			reqs = reqs[:last]
+		}
+	}
+
+	page.Sort = withKey(page.Sort, call.Key)
+
+	if len(call.Args) > 1 {
+		after, err := cursorArg(call.Call, call.Nodes[1], page.Sort)
+		if err != nil {
+			return nil, err
+		}
+
+		page.After = after
+	}
+
+	return append(reqs, &page), nil
+}
+
+// groupBy holds a groupBy(key) call until the aggregate following it folds
+// it in.
+type groupBy struct {
+	RID  string
+	Key  string
+	call *parser.Call
+}
+
+// RequestID returns the request id for this request object.
+func (g *groupBy) RequestID() string {
+	return g.RID
+}
+
+// RequestName returns the name for the giving request type.
+func (g *groupBy) RequestName() string {
+	return "groupBy"
+}
+
+// buildGroupBy builds the pending group of a groupBy(key) call.
+func buildGroupBy(call *MethodCall) (RecordRequests, error) {
+	return append(call.Reqs, &groupBy{
+		RID:  call.RID,
+		Key:  call.Args[0].(string),
+		call: call.Call,
+	}), nil
+}
+
+// buildAggregate builds the Aggregate request of a count(), sum(key),
+// avg(key), min(key) or max(key) call, folding in a preceding groupBy and
+// the where before it.
+func buildAggregate(call *MethodCall) (RecordRequests, error) {
+	agg := Aggregate{
+		RID: call.RID,
+		Doc: call.Doc,
+		Op:  strings.ToLower(call.Name),
+	}
+
+	if len(call.Args) > 0 {
+		agg.Field = call.Args[0].(string)
+	}
+
+	reqs := call.Reqs
+
+	if group, ok := call.Last().(*groupBy); ok {
+		agg.Group = group.Key
+		reqs = reqs[:len(reqs)-1]
+	}
+
+	if last := len(reqs) - 1; last > -1 {
+		if where, ok := reqs[last].(*Where); ok {
+			agg.Match = where.Predicate
+			reqs = reqs[:last]
+		}
+	}
+
+	return append(reqs, &agg), nil
+}
+
+// buildCreate builds the Create request of a create(records...) call.
+func buildCreate(call *MethodCall) (RecordRequests, error) {
+	if len(call.Reqs) != 0 {
+		return nil, call.Call.Errorf(nil, "create must be the first method of a query")
+	}
+
+	records, err := recordArgs(call.Call, call.Nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(call.Reqs, &Create{
+		RID:     call.RID,
+		Doc:     call.Doc,
+		Records: records,
+	}), nil
+}
+
+// buildRemove builds the Remove request of a remove() call.
+func buildRemove(call *MethodCall) (RecordRequests, error) {
+
+	// Removing without a selector would remove every record, which is
+	// never what is wanted from a query.
+	if len(call.Reqs) == 0 {
+		return nil, call.Call.Errorf(nil, "remove must follow a selector eg find(id,3).remove()")
+	}
+
+	return append(call.Reqs, &Remove{
+		RID: call.RID,
+		Doc: call.Doc,
+	}), nil
+}
+
+// buildJoin builds the Join request of a join(doc, key, [local]) call.
+func buildJoin(call *MethodCall) (RecordRequests, error) {
+	join := Join{
+		RID:   call.RID,
+		Doc:   call.Doc,
+		Path:  call.Args[0].(string),
+		Field: call.Args[1].(string),
+		Local: call.Key,
+	}
+
+	if len(call.Args) > 2 {
+		join.Local = call.Args[2].(string)
+	}
+
+	join.As = storage.LastKey(join.Path)
+
+	return append(call.Reqs, &join), nil
+}
+
+// buildExpand builds the Join request of a expand(local -> path) call.
+func buildExpand(call *MethodCall) (RecordRequests, error) {
+	join, err := expandArg(call.Call, call.Nodes[0], call.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	join.RID = call.RID
+	join.Doc = call.Doc
+
+	return append(call.Reqs, join), nil
+}
+
+// buildCollects builds the Collects request of a collects(keys...) call, the
+// record key is always collected.
+func buildCollects(call *MethodCall) (RecordRequests, error) {
+	keys := []string{call.Key}
+
+	for _, arg := range call.Args {
+		keys = append(keys, arg.(string))
+	}
+
+	return append(call.Reqs, &Collects{
+		RID:  call.RID,
+		Doc:  call.Doc,
+		Keys: keys,
+	}), nil
+}
+
+// buildMutate builds the Mutate request of a mutate(data, [ifVersion=]) call.
+func buildMutate(call *MethodCall) (RecordRequests, error) {
+	pm := call.Args[0].(data.Parameter)
+
+	mux := Mutate{
+		RID:       call.RID,
+		Doc:       call.Doc,
+		Parameter: pm,
+	}
+
+	if storage.IsUpdate(pm) {
+		upd, err := storage.ParseUpdate(pm)
+		if err != nil {
+			return nil, call.Errorf(0, err.Error())
+		}
+
+		mux.Update = upd
+	}
+
+	if version, ok := call.Named["ifVersion"].(int); ok {
+		if len(call.Reqs) == 0 {
+			return nil, call.Call.Errorf(call.Arg("ifVersion"), "ifVersion requires a preceding selector eg find(id,3)")
+		}
+
+		if version < 0 {
+			return nil, call.Call.Errorf(call.Arg("ifVersion").Value, "ifVersion expects a record version eg ifVersion=7")
+		}
+
+		expected := int64(version)
+		mux.IfVersion = &expected
+	}
+
+	return append(call.Reqs, &mux), nil
+}
+
+//==============================================================================
@@ -6,6 +6,8 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -22,6 +24,17 @@ type ServeTransport interface {
 	Do(endpoint string, body io.Reader) (data.ResponsePack, error)
 }
 
+// LiveTransport defines an interface for transports which can subscribe to
+// live queries, whose results the server pushes each time they change eg the
+// websocket transport.
+type LiveTransport interface {
+	Subscribe(endpoint string, rctx data.RequestContext, hl func(data.ResponsePack, error)) (func(), error)
+}
+
+// ErrLiveUnsupported is returned when subscribing to a live query over a
+// transport which does not implement LiveTransport.
+var ErrLiveUnsupported = errors.New("Live Queries Unsupported By Transport")
+
 //==============================================================================
 
 // Events defines event logger that allows us to record events for a specific
@@ -33,6 +46,72 @@ type Events interface {
 
 //==============================================================================
 
+// ConflictError is passed to a query's handler when its mutate request was
+// made against a stale version of a record eg mutate({...}, ifVersion=7).
+// Version holds the current version of the record.
+type ConflictError struct {
+	Err     error
+	Version int64
+}
+
+// Error returns the error message of the conflict.
+func (c *ConflictError) Error() string {
+	return c.Err.Error()
+}
+
+// ValidationError is passed to a query's handler when the records it created
+// or mutated failed the schema of their document. Fields maps the failing
+// field paths to their messages.
+type ValidationError struct {
+	Err    error
+	Fields map[string]string
+}
+
+// Error returns the error message of the validation failure.
+func (v *ValidationError) Error() string {
+	return v.Err.Error()
+}
+
+// LimitError is passed to a query's handler when the query or its reply
+// exceeded a cost limit of the server. Limit names the limit eg MaxRecords and
+// Max holds its value.
+type LimitError struct {
+	Err   error
+	Limit string
+	Max   int
+}
+
+// Error returns the error message of the exceeded limit.
+func (l *LimitError) Error() string {
+	return l.Err.Error()
+}
+
+// RateLimitError is returned by transports when the server rejected a request
+// for exceeding the caller's rate limit. RetryAfter holds the time to wait
+// before retrying, which the Servo waits before its next request.
+type RateLimitError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+// Error returns the error message of the rate limit.
+func (r *RateLimitError) Error() string {
+	return r.Err.Error()
+}
+
+// ParseRetryAfter returns the duration of a Retry-After header value given in
+// seconds, which is zero for invalid values.
+func ParseRetryAfter(value string) time.Duration {
+	secs, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil || secs < 0 {
+		return 0
+	}
+
+	return time.Duration(secs) * time.Second
+}
+
+//==============================================================================
+
 // Handler defines a handler type for receving a per data response.
 type Handler func(error, data.ResponseMeta, data.Parameters)
 
@@ -95,9 +174,10 @@ func (h *UpdateTrigger) UpdateKeys(meta data.ResponseMeta, da data.ResponsePack)
 
 	// Collect all record keys and store them for so we can review the delta
 	// lists incase we need to make requests for updates
+	// Deltas hold the record keys in their string form, hence we store them
+	// in the same form.
 	for _, record := range da.Results {
-		key := record[meta.RecordKey]
-		h.keys[key] = true
+		key := fmt.Sprintf("%+v", record[meta.RecordKey])
 		h.keys[key] = true
 	}
 }
@@ -116,7 +196,9 @@ type Server interface {
 
 // Servo defines a concrete implementation of the Server interface.
 // It handles scheduling query requests and providing the appropriate
-// Response parameter for each requster.
+// Response parameter for each requster. It keeps a copy of each record
+// received, patching it in place with the patches of the records changed
+// since its last request and dropping those which can not be patched.
 type Servo struct {
 	Events
 	addr        string
@@ -130,6 +212,8 @@ type Servo struct {
 	updates     []*UpdateTrigger
 	lastPack    data.ResponsePack
 	locked      int64
+	cl          sync.RWMutex
+	records     map[string]data.Parameter
 }
 
 // NewServo creates a new Servo instance. It takes a coquery server address
@@ -150,11 +234,66 @@ func NewServo(events Events, addr string, wait time.Duration, transport ServeTra
 		uuid:        utils.UUID(),
 		requests:    make([]Handlers, 0),
 		updates:     make([]*UpdateTrigger, 0),
+		records:     make(map[string]data.Parameter),
 	}
 
 	return &svo
 }
 
+// Record returns a copy of the local copy of the record with the giving key,
+// which is up to date as of the Servo's last request.
+func (s *Servo) Record(key string) (data.Parameter, bool) {
+	s.cl.RLock()
+	defer s.cl.RUnlock()
+
+	rec, ok := s.records[key]
+	if !ok {
+		return nil, false
+	}
+
+	return rec.Copy(), true
+}
+
+// patch patches the local copies of the records changed since the last
+// request, dropping those changed without a patch or failing theirs.
+func (s *Servo) patch(reply data.ResponsePack) {
+	s.cl.Lock()
+	defer s.cl.Unlock()
+
+	for _, key := range reply.Deltas {
+		rec, ok := s.records[key]
+		if !ok {
+			continue
+		}
+
+		patch, ok := reply.Patches[key]
+		if !ok {
+			delete(s.records, key)
+			continue
+		}
+
+		if err := patch.Apply(rec); err != nil {
+			s.Events.Error("Servo", "patch", err, "Info : Record[%s] : Dropped", key)
+			delete(s.records, key)
+		}
+	}
+}
+
+// keep stores copies of the received records, keyed by their record key.
+func (s *Servo) keep(meta data.ResponseMeta, records data.Parameters) {
+	s.cl.Lock()
+	defer s.cl.Unlock()
+
+	for _, rec := range records {
+		value, ok := rec[meta.RecordKey]
+		if !ok {
+			continue
+		}
+
+		s.records[fmt.Sprintf("%+v", value)] = rec.Copy()
+	}
+}
+
 // Updates stacks a query requests to the api and calls the given
 // handler with the response for that query when returned.
 func (s *Servo) Updates(query string, hl func()) error {
@@ -173,6 +312,42 @@ func (s *Servo) Updates(query string, hl func()) error {
 	return nil
 }
 
+// Live subscribes to the query as a live query, calling the given handler
+// with its results each time the server pushes them, until the returned
+// function is called to stop it.
+func (s *Servo) Live(query string, hl Handler) (func(), error) {
+	s.Events.Log("Servo", "Live", "Started : Query[%s]", query)
+
+	live, ok := s.transport.(LiveTransport)
+	if !ok {
+		s.Events.Error("Servo", "Live", ErrLiveUnsupported, "Completed")
+		return nil, ErrLiveUnsupported
+	}
+
+	var rctx data.RequestContext
+	rctx.RequestID = s.uuid + ":" + utils.UUID()
+	rctx.Queries = []string{query}
+	rctx.Live = true
+
+	stop, err := live.Subscribe(s.addr, rctx, func(reply data.ResponsePack, err error) {
+		meta := data.ResponseMeta{
+			DeltaID:   reply.DeltaID,
+			RecordKey: reply.RecordKey,
+			RequestID: reply.RequestID,
+		}
+
+		hl(err, meta, reply.Results)
+	})
+
+	if err != nil {
+		s.Events.Error("Servo", "Live", err, "Completed")
+		return nil, err
+	}
+
+	s.Events.Log("Servo", "Live", "Completed")
+	return stop, nil
+}
+
 // Request stacks a query requests to the api and calls the given
 // handler with the response for that query when returned.
 func (s *Servo) Request(query string, hl Handler) error {
@@ -232,8 +407,12 @@ func (s *Servo) Serve() error {
 	s.requests = nil
 	s.rl.Unlock()
 
+	// Rate limited requests hold back the next request until the server
+	// allows it.
+	wait := s.wait
+
 	defer func() {
-		s.pendingTime = time.Now().Add(s.wait)
+		s.pendingTime = time.Now().Add(wait)
 	}()
 
 	diff := s.lastPack.DeltaID
@@ -242,6 +421,7 @@ func (s *Servo) Serve() error {
 	mdata.RequestID = s.uuid
 	mdata.Diffs = true
 	mdata.DiffTag = diff
+	mdata.Patches = true
 
 	for _, hl := range pendings {
 		mdata.Queries = append(mdata.Queries, hl.Qry)
@@ -266,6 +446,10 @@ func (s *Servo) Serve() error {
 	// Deliver body to the transport layer.
 	reply, err = s.transport.Do(s.addr, &buf)
 	if err != nil {
+		if rerr, ok := err.(*RateLimitError); ok && rerr.RetryAfter > wait {
+			wait = rerr.RetryAfter
+		}
+
 		for _, hl := range pendings {
 			hl.Emit(err, meta, reply.Results)
 		}
@@ -279,6 +463,10 @@ func (s *Servo) Serve() error {
 
 	s.lastPack = reply
 
+	// Patch the records changed since the last request before keeping the
+	// records replied.
+	s.patch(reply)
+
 	if len(reply.Results) < len(pendings) {
 		err := errors.New("Inadequate Response Length")
 		s.Events.Error("Servo", "sendNow", err, "Completed")
@@ -289,6 +477,7 @@ func (s *Servo) Serve() error {
 		pending := pendings[ind]
 
 		if !reply.Batched {
+			s.keep(meta, reply.Results)
 			pending.Emit(nil, meta, reply.Results)
 
 			for _, upd := range s.updates {
@@ -308,6 +497,36 @@ func (s *Servo) Serve() error {
 
 		if failed, ok := rez["QueryFailed"].(bool); ok && failed {
 			failedErr := fmt.Errorf("Message{%s} - Error{%s}", rez["Message"], rez["Error"])
+
+			// Version conflicts are reported with the current version of the
+			// record, allowing the handler to reload and retry.
+			if conflict, ok := rez["Conflict"].(bool); ok && conflict {
+				version, _ := rez["Version"].(float64)
+				failedErr = &ConflictError{Err: failedErr, Version: int64(version)}
+			}
+
+			// Validation failures are reported with the messages of the failing
+			// fields.
+			if invalid, ok := rez["Invalid"].(bool); ok && invalid {
+				fields := make(map[string]string)
+
+				items, _ := rez["Fields"].([]interface{})
+				for _, item := range items {
+					if field, ok := item.(map[string]interface{}); ok {
+						fields[fmt.Sprintf("%v", field["field"])] = fmt.Sprintf("%v", field["message"])
+					}
+				}
+
+				failedErr = &ValidationError{Err: failedErr, Fields: fields}
+			}
+
+			// Exceeded limits are reported with the limit hit, allowing the
+			// handler to narrow the query.
+			if limit, ok := rez["Limit"].(string); ok {
+				max, _ := rez["Max"].(float64)
+				failedErr = &LimitError{Err: failedErr, Limit: limit, Max: int(max)}
+			}
+
 			s.Events.Error("Servo", "sendNow", failedErr, "Info : Query [%s] : Failed", qry)
 			pending.Emit(failedErr, meta, localReply.Results)
 			continue
@@ -327,6 +546,7 @@ func (s *Servo) Serve() error {
 			localReply.Results = append(localReply.Results, data.Parameter(pmrec))
 		}
 
+		s.keep(meta, localReply.Results)
 		pending.Emit(nil, meta, localReply.Results)
 
 		for _, upd := range s.updates {
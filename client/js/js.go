@@ -8,6 +8,7 @@ import (
 	"io/ioutil"
 	"time"
 
+	"github.com/influx6/coquery/client"
 	"github.com/influx6/coquery/data"
 
 	"honnef.co/go/js/xhr"
@@ -27,6 +28,10 @@ type jsHTTP struct{}
 // above 2xx.
 var ErrFailedRequest = errors.New("Request Failed")
 
+// ErrRateLimited is held by the *client.RateLimitError returned for requests
+// rejected by the server's rate limit.
+var ErrRateLimited = errors.New("Rate Limited")
+
 // Do issues the requests and collects the response into a pack.
 func (jsHTTP) Do(addr string, body io.Reader) (data.ResponsePack, error) {
 	var d data.ResponsePack
@@ -44,6 +49,13 @@ func (jsHTTP) Do(addr string, body io.Reader) (data.ResponsePack, error) {
 		return d, err
 	}
 
+	if req.Status == 429 {
+		return d, &client.RateLimitError{
+			Err:        ErrRateLimited,
+			RetryAfter: client.ParseRetryAfter(req.ResponseHeader("Retry-After")),
+		}
+	}
+
 	// if req.ReadyState
 	if req.Status < 200 || req.Status >= 300 {
 		return d, ErrFailedRequest
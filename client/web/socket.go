@@ -0,0 +1,315 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/influx6/coquery/data"
+)
+
+// ErrSocketTimeout is returned when the reply to a request sent over a
+// websocket does not arrive within the Socket's Timeout.
+var ErrSocketTimeout = errors.New("Socket Request Timeout")
+
+// Socket provides a transport sending requests over a websocket connection
+// to a coquery websocket server, a connection being dialed per endpoint and
+// redialed once it fails. Requests are matched to their replies by their
+// request id, requests sharing a id being sent one at a time. The deltas
+// pushed by the server are passed to Deltas if set, while live queries are
+// subscribed with Subscribe.
+type Socket struct {
+	Header  http.Header
+	Timeout time.Duration
+	Deltas  func(id string, records []string)
+
+	ml    sync.Mutex
+	conns map[string]*socketConn
+}
+
+// NewSocket returns a new instance of a Socket.
+func NewSocket() *Socket {
+	return &Socket{Timeout: 30 * time.Second}
+}
+
+// socketConn defines a websocket connection and the requests awaiting their
+// replies over it.
+type socketConn struct {
+	ws      *websocket.Conn
+	wl      sync.Mutex
+	pl      sync.Mutex
+	pending map[string]chan *data.SocketMessage
+	busy    map[string]chan struct{}
+	live    map[string]func(data.ResponsePack, error)
+	err     error
+}
+
+// Do issues the requests and collects the response into a pack.
+func (s *Socket) Do(addr string, body io.Reader) (data.ResponsePack, error) {
+	var d data.ResponsePack
+
+	var rctx data.RequestContext
+	if err := json.NewDecoder(body).Decode(&rctx); err != nil {
+		return d, err
+	}
+
+	sc, err := s.conn(addr)
+	if err != nil {
+		return d, err
+	}
+
+	reply, err := sc.acquire(rctx.RequestID)
+	if err != nil {
+		return d, err
+	}
+
+	defer sc.release(rctx.RequestID)
+
+	sc.wl.Lock()
+	err = sc.ws.WriteJSON(&rctx)
+	sc.wl.Unlock()
+
+	if err != nil {
+		s.drop(addr, sc, err)
+		return d, err
+	}
+
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = 30 * time.Second
+	}
+
+	select {
+	case msg, ok := <-reply:
+		if !ok {
+			return d, sc.failure()
+		}
+
+		if msg.Type == data.SocketError {
+			return d, errors.New(msg.Message)
+		}
+
+		if err := json.Unmarshal(msg.Reply, &d); err != nil {
+			return d, err
+		}
+
+		return d, nil
+	case <-time.After(timeout):
+		return d, ErrSocketTimeout
+	}
+}
+
+// Subscribe subscribes to the live query of the request, passing its results
+// to the handler each time the server pushes them, or the error it failed
+// with, until stopped or the
+// connection fails. The handler is called from the connection's reader, so
+// it must not block.
+func (s *Socket) Subscribe(addr string, rctx data.RequestContext, hl func(data.ResponsePack, error)) (func(), error) {
+	sc, err := s.conn(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	rid := rctx.RequestID
+
+	sc.pl.Lock()
+	if sc.err != nil {
+		sc.pl.Unlock()
+		return nil, sc.err
+	}
+	sc.live[rid] = hl
+	sc.pl.Unlock()
+
+	rctx.Live = true
+
+	sc.wl.Lock()
+	err = sc.ws.WriteJSON(&rctx)
+	sc.wl.Unlock()
+
+	if err != nil {
+		s.drop(addr, sc, err)
+		return nil, err
+	}
+
+	var once sync.Once
+
+	stop := func() {
+		once.Do(func() {
+			sc.pl.Lock()
+			delete(sc.live, rid)
+			sc.pl.Unlock()
+
+			sc.wl.Lock()
+			sc.ws.WriteJSON(&data.RequestContext{RequestID: rid, Live: true})
+			sc.wl.Unlock()
+		})
+	}
+
+	return stop, nil
+}
+
+// conn returns the connection to the endpoint, dialing it if needed.
+func (s *Socket) conn(addr string) (*socketConn, error) {
+	s.ml.Lock()
+	defer s.ml.Unlock()
+
+	if sc, ok := s.conns[addr]; ok {
+		return sc, nil
+	}
+
+	endpoint := addr
+	switch {
+	case strings.HasPrefix(endpoint, "https://"):
+		endpoint = "wss://" + strings.TrimPrefix(endpoint, "https://")
+	case strings.HasPrefix(endpoint, "http://"):
+		endpoint = "ws://" + strings.TrimPrefix(endpoint, "http://")
+	}
+
+	ws, _, err := websocket.DefaultDialer.Dial(endpoint, s.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	sc := &socketConn{
+		ws:      ws,
+		pending: make(map[string]chan *data.SocketMessage),
+		busy:    make(map[string]chan struct{}),
+		live:    make(map[string]func(data.ResponsePack, error)),
+	}
+
+	if s.conns == nil {
+		s.conns = make(map[string]*socketConn)
+	}
+
+	s.conns[addr] = sc
+
+	go s.read(addr, sc)
+	return sc, nil
+}
+
+// read delivers the messages of the connection until it fails.
+func (s *Socket) read(addr string, sc *socketConn) {
+	for {
+		var msg data.SocketMessage
+
+		if err := sc.ws.ReadJSON(&msg); err != nil {
+			s.drop(addr, sc, err)
+			return
+		}
+
+		if msg.Type == data.SocketDelta {
+			if s.Deltas != nil {
+				s.Deltas(msg.DeltaID, msg.Deltas)
+			}
+
+			continue
+		}
+
+		sc.pl.Lock()
+		reply, pending := sc.pending[msg.RequestID]
+		if pending {
+			delete(sc.pending, msg.RequestID)
+			reply <- &msg
+		}
+		hl, live := sc.live[msg.RequestID]
+		sc.pl.Unlock()
+
+		if pending || !live {
+			continue
+		}
+
+		switch msg.Type {
+		case data.SocketError:
+			hl(data.ResponsePack{}, errors.New(msg.Message))
+		case data.SocketLive:
+			var pack data.ResponsePack
+			if err := json.Unmarshal(msg.Reply, &pack); err != nil {
+				hl(pack, err)
+				continue
+			}
+
+			hl(pack, nil)
+		}
+	}
+}
+
+// drop closes the failed connection, failing the requests awaiting their
+// replies, so the next request redials the endpoint.
+func (s *Socket) drop(addr string, sc *socketConn, err error) {
+	s.ml.Lock()
+	if s.conns[addr] == sc {
+		delete(s.conns, addr)
+	}
+	s.ml.Unlock()
+
+	sc.ws.Close()
+
+	sc.pl.Lock()
+	defer sc.pl.Unlock()
+
+	if sc.err == nil {
+		sc.err = err
+	}
+
+	for rid, reply := range sc.pending {
+		delete(sc.pending, rid)
+		close(reply)
+	}
+
+	for rid, hl := range sc.live {
+		delete(sc.live, rid)
+		hl(data.ResponsePack{}, sc.err)
+	}
+}
+
+//==============================================================================
+
+// acquire registers a request awaiting its reply, waiting for the request of
+// the same id in flight if any.
+func (sc *socketConn) acquire(rid string) (chan *data.SocketMessage, error) {
+	for {
+		sc.pl.Lock()
+
+		if sc.err != nil {
+			sc.pl.Unlock()
+			return nil, sc.err
+		}
+
+		done, ok := sc.busy[rid]
+		if !ok {
+			reply := make(chan *data.SocketMessage, 1)
+			sc.pending[rid] = reply
+			sc.busy[rid] = make(chan struct{})
+			sc.pl.Unlock()
+			return reply, nil
+		}
+
+		sc.pl.Unlock()
+		<-done
+	}
+}
+
+// release removes the request, allowing the next request of its id.
+func (sc *socketConn) release(rid string) {
+	sc.pl.Lock()
+	defer sc.pl.Unlock()
+
+	delete(sc.pending, rid)
+
+	if done, ok := sc.busy[rid]; ok {
+		delete(sc.busy, rid)
+		close(done)
+	}
+}
+
+// failure returns the error the connection failed with.
+func (sc *socketConn) failure() error {
+	sc.pl.Lock()
+	defer sc.pl.Unlock()
+	return sc.err
+}
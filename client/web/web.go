@@ -2,10 +2,12 @@ package web
 
 import (
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"time"
 
+	"github.com/influx6/coquery/client"
 	"github.com/influx6/coquery/data"
 )
 
@@ -14,20 +16,31 @@ var HTTP webHTTP
 
 type webHTTP struct{}
 
-var client = http.Client{Timeout: 30 * time.Second}
+var httpClient = http.Client{Timeout: 30 * time.Second}
+
+// ErrRateLimited is held by the *client.RateLimitError returned for requests
+// rejected by the server's rate limit.
+var ErrRateLimited = errors.New("Rate Limited")
 
 // Do issues the requests and collects the response into a pack.
 func (webHTTP) Do(addr string, body io.Reader) (data.ResponsePack, error) {
 	var d data.ResponsePack
 
 	// Make a post requests with a application/json body.
-	res, err := client.Post(addr, "application/json", body)
+	res, err := httpClient.Post(addr, "application/json", body)
 	if err != nil {
 		return d, err
 	}
 
 	defer res.Body.Close()
 
+	if res.StatusCode == http.StatusTooManyRequests {
+		return d, &client.RateLimitError{
+			Err:        ErrRateLimited,
+			RetryAfter: client.ParseRetryAfter(res.Header.Get("Retry-After")),
+		}
+	}
+
 	// Attempt to decode information into appropriate structure.
 	if err := json.NewDecoder(res.Body).Decode(&d); err != nil {
 		return d, err
@@ -0,0 +1,197 @@
+package coquery
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+// ErrInvalidCursor is returned when a page cursor can not be decoded or does
+// not match the sort order of its page request.
+var ErrInvalidCursor = errors.New("Invalid Page Cursor")
+
+// Cursor defines the position of the last record of a page. It holds the
+// values of that record for each of the page's sort fields, the last of which
+// is always the record key.
+type Cursor struct {
+	Sort   []SortField
+	Values []interface{}
+}
+
+// cursorPack defines the serialized form of a Cursor.
+type cursorPack struct {
+	Keys   []string      `json:"k"`
+	Values []interface{} `json:"v"`
+}
+
+// NewCursor returns a Cursor positioned at the giving record for the sort
+// fields.
+func NewCursor(record map[string]interface{}, sort []SortField) *Cursor {
+	cursor := Cursor{Sort: sort}
+
+	for _, field := range sort {
+		val, _ := storage.PullKeys(record, field.Key)
+		cursor.Values = append(cursor.Values, val)
+	}
+
+	return &cursor
+}
+
+// Encode returns the opaque string form of the cursor, which is a url safe
+// base64 encoded json document.
+func (c *Cursor) Encode() (string, error) {
+	var pack cursorPack
+
+	for index, field := range c.Sort {
+		pack.Keys = append(pack.Keys, sortKey(field))
+		pack.Values = append(pack.Values, cursorValue(c.Values[index]))
+	}
+
+	raw, err := json.Marshal(pack)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// DecodeCursor returns the Cursor for the giving opaque string, validating
+// that it was created for the giving sort fields.
+func DecodeCursor(cursor string, sort []SortField) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var pack cursorPack
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&pack); err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if len(pack.Keys) != len(sort) || len(pack.Values) != len(sort) {
+		return nil, ErrInvalidCursor
+	}
+
+	c := Cursor{Sort: sort}
+
+	for index, field := range sort {
+		if pack.Keys[index] != sortKey(field) {
+			return nil, ErrInvalidCursor
+		}
+
+		val, err := decodeCursorValue(pack.Values[index])
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+
+		c.Values = append(c.Values, val)
+	}
+
+	return &c, nil
+}
+
+// After returns the predicate matching the records which come after the
+// cursor in its sort order eg for sort(-age) with the record key id:
+//
+//	age < 30 || (age == 30 && id > 4)
+func (c *Cursor) After() *Predicate {
+	var alts []*Predicate
+
+	for index, field := range c.Sort {
+		var conds []*Predicate
+
+		for eq := 0; eq < index; eq++ {
+			conds = append(conds, &Predicate{Op: OpEq, Field: c.Sort[eq].Key, Value: c.Values[eq]})
+		}
+
+		op := OpGt
+		if field.Desc {
+			op = OpLt
+		}
+
+		conds = append(conds, &Predicate{Op: op, Field: field.Key, Value: c.Values[index]})
+
+		if len(conds) == 1 {
+			alts = append(alts, conds[0])
+			continue
+		}
+
+		alts = append(alts, &Predicate{Op: OpAnd, Operands: conds})
+	}
+
+	if len(alts) == 1 {
+		return alts[0]
+	}
+
+	return &Predicate{Op: OpOr, Operands: alts}
+}
+
+//==============================================================================
+
+// sortKey returns the sort field in its '-' prefixed form.
+func sortKey(field SortField) string {
+	if field.Desc {
+		return "-" + field.Key
+	}
+
+	return field.Key
+}
+
+// hexer defines the method provided by object id types eg bson.ObjectId.
+type hexer interface {
+	Hex() string
+}
+
+// cursorValue returns the json form of a cursor value, preserving object ids
+// and times which json can not represent.
+func cursorValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case data.ObjectID:
+		return map[string]interface{}{"$oid": string(value)}
+	case hexer:
+		return map[string]interface{}{"$oid": value.Hex()}
+	case time.Time:
+		return map[string]interface{}{"$date": value.Format(time.RFC3339Nano)}
+	}
+
+	return v
+}
+
+// decodeCursorValue returns the go value of a json decoded cursor value.
+func decodeCursorValue(v interface{}) (interface{}, error) {
+	switch value := v.(type) {
+	case json.Number:
+		if in, err := value.Int64(); err == nil {
+			return in, nil
+		}
+
+		return value.Float64()
+
+	case map[string]interface{}:
+		if oid, ok := value["$oid"].(string); ok {
+			return data.ObjectID(oid), nil
+		}
+
+		if date, ok := value["$date"].(string); ok {
+			return time.Parse(time.RFC3339Nano, date)
+		}
+
+		return nil, fmt.Errorf("unknown cursor value %v", value)
+	}
+
+	return v, nil
+}
+
+//==============================================================================
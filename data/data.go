@@ -1,18 +1,84 @@
 package data
 
+import (
+	"context"
+	"encoding/json"
+)
+
 //==============================================================================
 
 // RequestContext provides a request context which details the needed information
 // a coquery.Request entails. It allows us organize the behaviour and response
 // for a request.
 // NoJSON allows a request avoid wrapping its writer with a JSONResponseWriter.
+// Atomic requires all queries of the request to succeed, else the changes
+// made by those which did are rolled back.
+// Principal identifies the caller making the request, it is set by the
+// server and never decoded from the request.
+// The context.Context of the request (see WithContext) cancels the work done
+// for the request once done eg when the client disconnects.
+// Live subscribes to the results of the request's single query over streaming
+// transports, which push them again whenever they change. A Live request
+// without queries ends the subscription of its RequestID.
+// Patches asks for the JSON Patch operations of the records changed since the
+// DiffTag along with their deltas, allowing the client to patch its copies.
 type RequestContext struct {
-	RequestID string   `json:"request_id"`
-	Queries   []string `json:"queries"`
-	Diffs     bool     `json:"diffing"`
-	DiffTag   string   `json:"diff_tag"`
-	DiffWatch []string `json:"diff_watch"`
-	NoJSON    bool     `json:"no_json"`
+	RequestID string     `json:"request_id"`
+	Queries   []string   `json:"queries"`
+	Diffs     bool       `json:"diffing"`
+	DiffTag   string     `json:"diff_tag"`
+	DiffWatch []string   `json:"diff_watch"`
+	NoJSON    bool       `json:"no_json"`
+	Atomic    bool       `json:"atomic"`
+	Live      bool       `json:"live"`
+	Patches   bool       `json:"patches"`
+	Principal *Principal `json:"-"`
+
+	ctx context.Context
+}
+
+// Context returns the context.Context of the request, which is
+// context.Background if none was set.
+func (r *RequestContext) Context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+
+	return r.ctx
+}
+
+// WithContext returns a shallow copy of the request context using the giving
+// context.Context.
+func (r *RequestContext) WithContext(ctx context.Context) *RequestContext {
+	cp := *r
+	cp.ctx = ctx
+	return &cp
+}
+
+//==============================================================================
+
+// Principal defines the identity of a caller, its roles and the claims made
+// about it when authenticated eg the claims of a JWT.
+type Principal struct {
+	ID     string                 `json:"id"`
+	Roles  []string               `json:"roles,omitempty"`
+	Claims map[string]interface{} `json:"claims,omitempty"`
+}
+
+// HasRole returns true/false if the principal holds the giving role. A nil
+// principal, being anonymous, holds no roles.
+func (p *Principal) HasRole(role string) bool {
+	if p == nil {
+		return false
+	}
+
+	for _, r := range p.Roles {
+		if r == role {
+			return true
+		}
+	}
+
+	return false
 }
 
 //==============================================================================
@@ -37,9 +103,19 @@ func (p Parameter) Get(k string) interface{} {
 	return p[k]
 }
 
+// Copy returns a deep copy of the parameter.
+func (p Parameter) Copy() Parameter {
+	return Parameter(copyValue(p).(map[string]interface{}))
+}
+
 // Parameters defines a lists of Parameter types.
 type Parameters []Parameter
 
+// ObjectID defines a value which is to be treated as a database object id
+// (eg a Mongo ObjectId) rather than a plain string. It holds the hexadecimal
+// form of the id.
+type ObjectID string
+
 //==============================================================================
 
 // ResponseMeta provides a meta record which provides specific information for
@@ -51,13 +127,43 @@ type ResponseMeta struct {
 }
 
 // ResponsePack defines the response to be recieved back from the API.
+// Patches holds the patches of the changed records keyed by record, records
+// within the deltas but not the patches must be reloaded.
 type ResponsePack struct {
-	RecordKey string     `json:"record_key"`
-	RequestID string     `json:"request_id"`
-	Batched   bool       `json:"batch"`
-	DeltaID   string     `json:"delta_id"`
-	Deltas    []string   `json:"delta_id"`
-	Results   Parameters `json:"results"`
+	RecordKey  string           `json:"record_key"`
+	RequestID  string           `json:"request_id"`
+	Batched    bool             `json:"batch"`
+	DeltaID    string           `json:"delta_id"`
+	Deltas     []string         `json:"deltas"`
+	Patches    map[string]Patch `json:"patches"`
+	Results    Parameters       `json:"results"`
+	NextCursor string           `json:"next_cursor"`
+}
+
+//==============================================================================
+
+// contains the types of the SocketMessage sent by servers over websockets.
+const (
+	SocketReply = "reply"
+	SocketError = "error"
+	SocketDelta = "delta"
+	SocketLive  = "live"
+)
+
+// SocketMessage defines the messages servers send over websockets, where
+// clients send RequestContext messages. A reply carries the JSON ResponsePack
+// of the request of RequestID, an error its message and the JSON error, and a
+// delta the record keys changed by the diff of DeltaID as soon as it happened.
+// A live message carries the JSON ResponsePack of a live query each time its
+// results change.
+type SocketMessage struct {
+	Type      string          `json:"type"`
+	RequestID string          `json:"request_id,omitempty"`
+	Reply     json.RawMessage `json:"reply,omitempty"`
+	Message   string          `json:"message,omitempty"`
+	Error     json.RawMessage `json:"error,omitempty"`
+	DeltaID   string          `json:"delta_id,omitempty"`
+	Deltas    []string        `json:"deltas,omitempty"`
 }
 
 //==============================================================================
@@ -0,0 +1,335 @@
+package data
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//==============================================================================
+
+// contains the JSON Patch (RFC 6902) operations generated by Diff and applied
+// by Patch.Apply.
+const (
+	PatchAdd     = "add"
+	PatchRemove  = "remove"
+	PatchReplace = "replace"
+)
+
+// ErrPatchPath is returned when the path of a patch operation does not exist
+// within the record being patched.
+var ErrPatchPath = errors.New("Invalid Patch Path")
+
+// ErrPatchOp is returned when a patch holds a operation other than add,
+// remove and replace.
+var ErrPatchOp = errors.New("Unsupported Patch Operation")
+
+// Operation defines a JSON Patch (RFC 6902) operation, where Path is the JSON
+// Pointer (RFC 6901) of the changed field within the record eg /address/city.
+type Operation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// MarshalJSON returns the operation as JSON, without the value for removals.
+func (o Operation) MarshalJSON() ([]byte, error) {
+	if o.Op == PatchRemove {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{o.Op, o.Path})
+	}
+
+	type operation Operation
+	return json.Marshal(operation(o))
+}
+
+// Field returns the dotted record field changed by the operation eg
+// address.city, which is empty for operations changing the whole record.
+func (o Operation) Field() string {
+	tokens, err := pointer(o.Path)
+	if err != nil {
+		return ""
+	}
+
+	return strings.Join(tokens, ".")
+}
+
+// Patch defines the list of JSON Patch operations changing a record, applied
+// in order.
+type Patch []Operation
+
+//==============================================================================
+
+// Diff returns the patch changing the from record into the to record. Nested
+// objects are diffed field by field while other values, including arrays, are
+// replaced whole. A nil from record gives a patch adding the whole record.
+func Diff(from, to map[string]interface{}) Patch {
+	patch := Patch{}
+
+	if from == nil {
+		return append(patch, Operation{Op: PatchAdd, Path: "", Value: copyValue(to)})
+	}
+
+	diffObjects("", from, to, &patch)
+	return patch
+}
+
+// Apply applies the operations of the patch to the record in order, stopping
+// at the first which fails. The record may be partially patched on failure.
+func (p Patch) Apply(rec map[string]interface{}) error {
+	for _, op := range p {
+		if op.Path == "" {
+			if op.Op != PatchAdd && op.Op != PatchReplace {
+				return ErrPatchPath
+			}
+
+			obj, ok := objectOf(op.Value)
+			if !ok {
+				return ErrPatchPath
+			}
+
+			for key := range rec {
+				delete(rec, key)
+			}
+
+			for key, value := range obj {
+				rec[key] = copyValue(value)
+			}
+
+			continue
+		}
+
+		tokens, err := pointer(op.Path)
+		if err != nil {
+			return err
+		}
+
+		if _, err := patchObject(rec, tokens, op); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+//==============================================================================
+
+// diffObjects adds the operations changing the from object into the to object
+// at the giving path.
+func diffObjects(path string, from, to map[string]interface{}, patch *Patch) {
+	for _, key := range sortedKeys(from) {
+		if _, ok := to[key]; !ok {
+			*patch = append(*patch, Operation{Op: PatchRemove, Path: path + "/" + escape(key)})
+		}
+	}
+
+	for _, key := range sortedKeys(to) {
+		fpath := path + "/" + escape(key)
+		value := to[key]
+
+		old, ok := from[key]
+		if !ok {
+			*patch = append(*patch, Operation{Op: PatchAdd, Path: fpath, Value: copyValue(value)})
+			continue
+		}
+
+		oldObj, isOld := objectOf(old)
+		newObj, isNew := objectOf(value)
+		if isOld && isNew {
+			diffObjects(fpath, oldObj, newObj, patch)
+			continue
+		}
+
+		if !reflect.DeepEqual(old, value) {
+			*patch = append(*patch, Operation{Op: PatchReplace, Path: fpath, Value: copyValue(value)})
+		}
+	}
+}
+
+// patchValue applies the operation at the tokens of the pointer within the
+// target, returning the target which changes for arrays.
+func patchValue(target interface{}, tokens []string, op Operation) (interface{}, error) {
+	switch node := target.(type) {
+	case map[string]interface{}:
+		return patchObject(node, tokens, op)
+	case Parameter:
+		if _, err := patchObject(node, tokens, op); err != nil {
+			return nil, err
+		}
+
+		return node, nil
+	case []interface{}:
+		return patchArray(node, tokens, op)
+	}
+
+	return nil, ErrPatchPath
+}
+
+// patchObject applies the operation at the tokens of the pointer within the
+// object.
+func patchObject(obj map[string]interface{}, tokens []string, op Operation) (interface{}, error) {
+	key := tokens[0]
+	value, ok := obj[key]
+
+	if len(tokens) > 1 {
+		if !ok {
+			return nil, ErrPatchPath
+		}
+
+		updated, err := patchValue(value, tokens[1:], op)
+		if err != nil {
+			return nil, err
+		}
+
+		obj[key] = updated
+		return obj, nil
+	}
+
+	switch op.Op {
+	case PatchAdd:
+		obj[key] = copyValue(op.Value)
+	case PatchReplace:
+		if !ok {
+			return nil, ErrPatchPath
+		}
+
+		obj[key] = copyValue(op.Value)
+	case PatchRemove:
+		if !ok {
+			return nil, ErrPatchPath
+		}
+
+		delete(obj, key)
+	default:
+		return nil, ErrPatchOp
+	}
+
+	return obj, nil
+}
+
+// patchArray applies the operation at the tokens of the pointer within the
+// array, where the index "-" adds to the end of the array.
+func patchArray(arr []interface{}, tokens []string, op Operation) (interface{}, error) {
+	var index int
+
+	switch {
+	case tokens[0] == "-":
+		if len(tokens) > 1 || op.Op != PatchAdd {
+			return nil, ErrPatchPath
+		}
+
+		index = len(arr)
+	default:
+		// Only adds may use the index past the last item.
+		last := len(arr) - 1
+		if len(tokens) == 1 && op.Op == PatchAdd {
+			last = len(arr)
+		}
+
+		var err error
+		if index, err = strconv.Atoi(tokens[0]); err != nil || index < 0 || index > last {
+			return nil, ErrPatchPath
+		}
+	}
+
+	if len(tokens) > 1 {
+		updated, err := patchValue(arr[index], tokens[1:], op)
+		if err != nil {
+			return nil, err
+		}
+
+		arr[index] = updated
+		return arr, nil
+	}
+
+	switch op.Op {
+	case PatchAdd:
+		arr = append(arr, nil)
+		copy(arr[index+1:], arr[index:])
+		arr[index] = copyValue(op.Value)
+	case PatchReplace:
+		arr[index] = copyValue(op.Value)
+	case PatchRemove:
+		arr = append(arr[:index], arr[index+1:]...)
+	default:
+		return nil, ErrPatchOp
+	}
+
+	return arr, nil
+}
+
+//==============================================================================
+
+// pointer returns the unescaped tokens of the JSON Pointer.
+func pointer(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "/") {
+		return nil, ErrPatchPath
+	}
+
+	tokens := strings.Split(path[1:], "/")
+	for index, token := range tokens {
+		tokens[index] = strings.Replace(strings.Replace(token, "~1", "/", -1), "~0", "~", -1)
+	}
+
+	return tokens, nil
+}
+
+// escape returns the key escaped as a token of a JSON Pointer.
+func escape(key string) string {
+	return strings.Replace(strings.Replace(key, "~", "~0", -1), "/", "~1", -1)
+}
+
+// objectOf returns the map of a object value.
+func objectOf(value interface{}) (map[string]interface{}, bool) {
+	switch obj := value.(type) {
+	case map[string]interface{}:
+		return obj, true
+	case Parameter:
+		return obj, true
+	}
+
+	return nil, false
+}
+
+// copyValue returns a deep copy of the objects and arrays of the value, so
+// patches do not share them with the records they were taken from.
+func copyValue(value interface{}) interface{} {
+	if obj, ok := objectOf(value); ok {
+		cp := make(map[string]interface{}, len(obj))
+		for key, item := range obj {
+			cp[key] = copyValue(item)
+		}
+
+		return cp
+	}
+
+	if arr, ok := value.([]interface{}); ok {
+		cp := make([]interface{}, len(arr))
+		for index, item := range arr {
+			cp[index] = copyValue(item)
+		}
+
+		return cp
+	}
+
+	return value
+}
+
+// sortedKeys returns the keys of the object in order, keeping the operations
+// of a diff stable.
+func sortedKeys(obj map[string]interface{}) []string {
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+//==============================================================================
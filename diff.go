@@ -6,6 +6,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/influx6/coquery/data"
 	"github.com/influx6/faux/utils"
 )
 
@@ -23,13 +24,54 @@ type Diffs interface {
 	AnalyzeWith(string, []string) map[string]bool
 }
 
+// DiffWatcher defines a interface for Diffs which notify watchers of the
+// diffs put into them, allowing protocols to push changes to their clients.
+// The watcher receives the key of each diff and its record keys, and is
+// removed by calling the function returned.
+type DiffWatcher interface {
+	Watch(watcher func(id string, records []string)) (unwatch func())
+}
+
+// DiffPatcher defines a interface for Diffs which keep the JSON Patch
+// operations of the changed records of each diff, as recorded by the store,
+// allowing clients to patch their copies of the records in place of
+// reloading them. PatchesFrom returns the patches of the records changed
+// since the giving diff, combined in order, leaving out those changed without
+// a patch eg removed records.
+type DiffPatcher interface {
+	PutPatches(records []string, patches map[string]data.Patch) string
+	PatchesFrom(id string) map[string]data.Patch
+}
+
+// DiffLatest defines a interface for Diffs which find the key of their latest
+// diff without listing the keys of every diff, as LatestDiff does otherwise.
+type DiffLatest interface {
+	Latest() string
+}
+
+// LatestDiff returns the key of the latest diff within the diffs, which is
+// empty for no diffs.
+func LatestDiff(diffs Diffs) string {
+	if latest, ok := diffs.(DiffLatest); ok {
+		return latest.Latest()
+	}
+
+	keys := diffs.Keys()
+	if len(keys) == 0 {
+		return ""
+	}
+
+	return keys[len(keys)-1]
+}
+
 //==============================================================================
 
 // Diff provides a diff object which stores a diff and the timestamp it was
-// added.
+// added, along with the patches of its records if known.
 type Diff struct {
 	Key     string
 	Diff    []string
+	Patches map[string]data.Patch
 	Time    time.Time
 	expired bool
 }
@@ -37,13 +79,17 @@ type Diff struct {
 // DiffStore provides a inmemory diff storage system for coquery, it stores and
 // clears out all its old diff lists after a giving period/duration, only keeping
 // diffs information within the valid period of lifetime.
-// Implements the Diffs interface.
+// Implements the Diffs, DiffWatcher, DiffPatcher and DiffLatest interfaces.
 type DiffStore struct {
 	EventLog
 	maxAge time.Duration
 	dl     sync.RWMutex
 	diffs  []*Diff
 	keys   map[string]int
+
+	wl       sync.RWMutex
+	watchID  int
+	watchers map[int]func(string, []string)
 }
 
 // NewDiffs returns a new instance of a DiffStore which does not expire it
@@ -167,6 +213,50 @@ func (diff *DiffStore) PullFrom(id string) []string {
 	return records
 }
 
+// PatchesFrom implements the DiffPatcher interface, returning the patches of
+// the records changed since the giving diff. Nil is returned if no such diff
+// exists.
+func (diff *DiffStore) PatchesFrom(id string) map[string]data.Patch {
+	diff.Log("DiffStore", "PatchesFrom", "Started : Last Record ID[%s]", id)
+	diff.clean()
+
+	diff.dl.RLock()
+	defer diff.dl.RUnlock()
+
+	index, ok := diff.keys[id]
+	if !ok {
+		diff.Error("DiffStore", "PatchesFrom", ErrRecordNotFound, "Completed")
+		return nil
+	}
+
+	patches := make(map[string]data.Patch)
+	unpatched := make(map[string]bool)
+
+	// Combine the patches of each record in order, a record changed without a
+	// patch can not be patched at all.
+	for i := index + 1; i < len(diff.diffs); i++ {
+		rec := diff.diffs[i]
+
+		for _, key := range rec.Diff {
+			patch, ok := rec.Patches[key]
+			if !ok || unpatched[key] {
+				unpatched[key] = true
+				delete(patches, key)
+				continue
+			}
+
+			if _, ok := patches[key]; !ok {
+				patches[key] = data.Patch{}
+			}
+
+			patches[key] = append(patches[key], patch...)
+		}
+	}
+
+	diff.Log("DiffStore", "PatchesFrom", "Completed")
+	return patches
+}
+
 // Diffs returns a map of all changed record keys.
 func (diff *DiffStore) Diffs() map[string]struct{} {
 	diff.Log("DiffStore", "Keys", "Started")
@@ -212,6 +302,26 @@ func (diff *DiffStore) Keys() []string {
 	return keys
 }
 
+// Latest implements the DiffLatest interface, returning the key of the latest
+// diff within the store, which is empty for no diffs.
+func (diff *DiffStore) Latest() string {
+	diff.Log("DiffStore", "Latest", "Started")
+	diff.clean()
+
+	diff.dl.RLock()
+	defer diff.dl.RUnlock()
+
+	for index := len(diff.diffs) - 1; index >= 0; index-- {
+		if rec := diff.diffs[index]; !rec.expired {
+			diff.Log("DiffStore", "Latest", "Completed")
+			return rec.Key
+		}
+	}
+
+	diff.Log("DiffStore", "Latest", "Completed")
+	return ""
+}
+
 // ErrRecordNotFound is returned when a giving record key has no associted record.
 var ErrRecordNotFound = errors.New("Record Not Found")
 
@@ -237,6 +347,13 @@ func (diff *DiffStore) Get(record string) []string {
 
 // Put stores a list of diffs and returns the associated key for this diff.
 func (diff *DiffStore) Put(record []string) string {
+	return diff.PutPatches(record, nil)
+}
+
+// PutPatches implements the DiffPatcher interface, storing a list of diffs
+// with the patches of its records and returning the associated key for this
+// diff.
+func (diff *DiffStore) PutPatches(record []string, patches map[string]data.Patch) string {
 	diff.Log("DiffStore", "Put", "Started : Adding New Record : %s", fmt.Sprintf("%+v", record))
 	diff.clean()
 
@@ -248,16 +365,49 @@ func (diff *DiffStore) Put(record []string) string {
 	}
 
 	diff.dl.Lock()
-	defer diff.dl.Unlock()
+	{
+		df := &Diff{Key: key, Diff: record, Patches: patches, Time: time.Now()}
+		diff.keys[key] = len(diff.diffs)
+		diff.diffs = append(diff.diffs, df)
+	}
+	diff.dl.Unlock()
 
-	df := &Diff{Key: key, Diff: record, Time: time.Now()}
-	diff.keys[key] = len(diff.diffs)
-	diff.diffs = append(diff.diffs, df)
+	diff.wl.RLock()
+	{
+		for _, watcher := range diff.watchers {
+			watcher(key, record)
+		}
+	}
+	diff.wl.RUnlock()
 
 	diff.Log("DiffStore", "Put", "Completed")
 	return key
 }
 
+// Watch implements the DiffWatcher interface, the watchers being called
+// synchronously by Put.
+func (diff *DiffStore) Watch(watcher func(id string, records []string)) func() {
+	diff.Log("DiffStore", "Watch", "Started")
+
+	diff.wl.Lock()
+	defer diff.wl.Unlock()
+
+	if diff.watchers == nil {
+		diff.watchers = make(map[int]func(string, []string))
+	}
+
+	diff.watchID++
+	id := diff.watchID
+	diff.watchers[id] = watcher
+
+	diff.Log("DiffStore", "Watch", "Completed")
+	return func() {
+		diff.wl.Lock()
+		defer diff.wl.Unlock()
+		delete(diff.watchers, id)
+	}
+}
+
 // Clear clears out all record stores within the diff map.
 func (diff *DiffStore) Clear() {
 	diff.Log("DiffStore", "Clear", "Started")
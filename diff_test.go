@@ -7,6 +7,7 @@ import (
 
 	"github.com/ardanlabs/kit/tests"
 	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
 )
 
 //==============================================================================
@@ -80,7 +81,12 @@ func TestDiff(t *testing.T) {
 			}
 			t.Logf("\t%s\tShould find 3 keys in diff store for key[%s]", tests.Success, id)
 
-			diff.Put([]string{"1", "12", "31"})
+			latest := diff.Put([]string{"1", "12", "31"})
+
+			if key := coquery.LatestDiff(diff); key != latest {
+				t.Fatalf("\t%s\tShould find the latest Diff Key[%s]: %s", tests.Failed, latest, key)
+			}
+			t.Logf("\t%s\tShould find the latest Diff Key[%s]", tests.Success, latest)
 
 			if pl := diff.PullFrom(id); len(pl) < 3 {
 				t.Logf("\t\tPullFrom: %s\n", pl)
@@ -112,3 +118,44 @@ func TestDiff(t *testing.T) {
 		}
 	}
 }
+
+// TestDiffPatches validates the combining of the patches of diffs.
+func TestDiffPatches(t *testing.T) {
+	t.Logf("Given the need to collect the patches of the records changed since a diff")
+	{
+		diff := coquery.NewDiffs(events)
+
+		id := diff.Put([]string{"1"})
+
+		diff.PutPatches([]string{"1", "2"}, map[string]data.Patch{
+			"1": {{Op: data.PatchReplace, Path: "/name", Value: "alex"}},
+			"2": {{Op: data.PatchAdd, Path: "/age", Value: 20}},
+		})
+		diff.PutPatches([]string{"1", "2"}, map[string]data.Patch{
+			"1": {{Op: data.PatchRemove, Path: "/age"}},
+		})
+
+		t.Logf("\tWhen pulling the patches from Diff Key[%s]", id)
+		{
+			patches := diff.PatchesFrom(id)
+
+			if len(patches["1"]) != 2 || patches["1"][1].Op != data.PatchRemove {
+				t.Fatalf("\t%s\tShould have combined the patches of record 1 in order: %+v", tests.Failed, patches)
+			}
+			t.Logf("\t%s\tShould have combined the patches of record 1 in order", tests.Success)
+
+			if _, ok := patches["2"]; ok {
+				t.Fatalf("\t%s\tShould have left out record 2 changed without a patch: %+v", tests.Failed, patches)
+			}
+			t.Logf("\t%s\tShould have left out record 2 changed without a patch", tests.Success)
+		}
+
+		t.Logf("\tWhen pulling the patches from a unknown diff")
+		{
+			if patches := diff.PatchesFrom("4ab3"); patches != nil {
+				t.Fatalf("\t%s\tShould have found no patches: %+v", tests.Failed, patches)
+			}
+			t.Logf("\t%s\tShould have found no patches", tests.Success)
+		}
+	}
+}
@@ -1 +1,660 @@
+// Package boltdb provides a durable coquery.Diffs backend storing its diffs
+// within an embedded BoltDB file, so the delta ids held by clients remain
+// valid across server restarts.
 package boltdb
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/faux/utils"
+	bolt "go.etcd.io/bbolt"
+)
+
+//==============================================================================
+
+// diffsBucket names the bucket holding the diffs, keyed by their sequence.
+var diffsBucket = []byte("diffs")
+
+// OpenTimeout sets the time given to obtain the lock of the BoltDB file,
+// which is held by a single process at a time.
+var OpenTimeout = 5 * time.Second
+
+//==============================================================================
+
+// DiffStore provides a BoltDB diff storage system for coquery. Diffs are keyed
+// by a sequence seeded from the time their bucket was created, so their keys
+// are ordered and do not repeat ids issued before the bucket was cleared or
+// its file recreated. Diffs older than its maximum age are skipped when read
+// and removed as new diffs are put.
+// Implements the Diffs, DiffWatcher, DiffPatcher and DiffLatest interfaces.
+type DiffStore struct {
+	coquery.EventLog
+	db     *bolt.DB
+	maxAge time.Duration
+
+	wl       sync.RWMutex
+	watchID  int
+	watchers map[int]func(string, []string)
+}
+
+// NewDiffs returns a new instance of a DiffStore storing its diffs within the
+// BoltDB file at the giving path, created if needed, which does not expire its
+// records.
+func NewDiffs(el coquery.EventLog, path string) (*DiffStore, error) {
+	return NewExpiringDiffs(el, path, 0)
+}
+
+// NewExpiringDiffs returns a new instance of a DiffStore storing its diffs
+// within the BoltDB file at the giving path, created if needed, which expires
+// its records after a specific lifetime duration.
+func NewExpiringDiffs(el coquery.EventLog, path string, maxAge time.Duration) (*DiffStore, error) {
+	el.Log("BoltDiffStore", "New", "Started : Path[%s] : Max Age[%s]", path, maxAge)
+
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: OpenTimeout})
+	if err != nil {
+		el.Error("BoltDiffStore", "New", err, "Completed")
+		return nil, err
+	}
+
+	diff := DiffStore{
+		EventLog: el,
+		db:       db,
+		maxAge:   maxAge,
+	}
+
+	err = db.Update(func(tx *bolt.Tx) error {
+		if _, err := bucket(tx); err != nil {
+			return err
+		}
+
+		return diff.compact(tx)
+	})
+
+	if err != nil {
+		db.Close()
+		el.Error("BoltDiffStore", "New", err, "Completed")
+		return nil, err
+	}
+
+	el.Log("BoltDiffStore", "New", "Completed")
+	return &diff, nil
+}
+
+// Close closes the BoltDB file of the store.
+func (diff *DiffStore) Close() error {
+	return diff.db.Close()
+}
+
+//==============================================================================
+
+// Analyze analyzes the giving record keys with its internal records and returns
+// a truth map to indicate which record has changed or not.
+func (diff *DiffStore) Analyze(keys []string) map[string]bool {
+	diff.Log("BoltDiffStore", "Analyze", "Started : Records %s", keys)
+
+	truths := make(map[string]bool)
+	changes := diff.Diffs()
+
+	for _, key := range keys {
+		_, ok := changes[key]
+		truths[key] = ok
+	}
+
+	diff.Log("BoltDiffStore", "Analyze", "Completed")
+	return truths
+}
+
+// AnalyzeWith uses the giving last diff Id to create a lists of changes and
+// returns a truth table map (of key:change_status), to indicate which record
+// key indeed was registed to have changed.
+func (diff *DiffStore) AnalyzeWith(lastID string, keys []string) map[string]bool {
+	diff.Log("BoltDiffStore", "AnalyzeWith", "Started : Last Diff Key[%s] : Records %s", lastID, keys)
+
+	truths := make(map[string]bool)
+
+	for _, k := range keys {
+		truths[k] = false
+	}
+
+	for _, id := range diff.PullFrom(lastID) {
+		if _, ok := truths[id]; ok {
+			truths[id] = true
+		}
+	}
+
+	diff.Log("BoltDiffStore", "AnalyzeWith", "Completed")
+	return truths
+}
+
+// PullFrom pulls all the changes that has occured since the giving diff,
+// returning all the records a single list without duplicates. The diffs after
+// it are read with a single range scan of the ordered keys.
+func (diff *DiffStore) PullFrom(id string) []string {
+	diff.Log("BoltDiffStore", "PullFrom", "Started : Last Record ID[%s]", id)
+
+	var records []string
+
+	found, err := diff.after(id, func(df *coquery.Diff) {
+		for _, key := range df.Diff {
+			records = append(records, key)
+		}
+	})
+
+	if err != nil || !found {
+		if err == nil {
+			err = coquery.ErrRecordNotFound
+		}
+
+		diff.Error("BoltDiffStore", "PullFrom", err, "Completed")
+		return nil
+	}
+
+	diff.Log("BoltDiffStore", "PullFrom", "Completed")
+	return unique(records)
+}
+
+// PatchesFrom implements the coquery.DiffPatcher interface, returning the
+// patches of the records changed since the giving diff. Nil is returned if no
+// such diff exists.
+func (diff *DiffStore) PatchesFrom(id string) map[string]data.Patch {
+	diff.Log("BoltDiffStore", "PatchesFrom", "Started : Last Record ID[%s]", id)
+
+	patches := make(map[string]data.Patch)
+	unpatched := make(map[string]bool)
+
+	// Combine the patches of each record in order, a record changed without a
+	// patch can not be patched at all.
+	found, err := diff.after(id, func(df *coquery.Diff) {
+		for _, key := range df.Diff {
+			patch, ok := df.Patches[key]
+			if !ok || unpatched[key] {
+				unpatched[key] = true
+				delete(patches, key)
+				continue
+			}
+
+			if _, ok := patches[key]; !ok {
+				patches[key] = data.Patch{}
+			}
+
+			patches[key] = append(patches[key], patch...)
+		}
+	})
+
+	if err != nil || !found {
+		if err == nil {
+			err = coquery.ErrRecordNotFound
+		}
+
+		diff.Error("BoltDiffStore", "PatchesFrom", err, "Completed")
+		return nil
+	}
+
+	diff.Log("BoltDiffStore", "PatchesFrom", "Completed")
+	return patches
+}
+
+// Diffs returns a map of all changed record keys.
+func (diff *DiffStore) Diffs() map[string]struct{} {
+	diff.Log("BoltDiffStore", "Diffs", "Started")
+
+	changes := make(map[string]struct{})
+
+	err := diff.each(func(df *coquery.Diff) {
+		for _, key := range df.Diff {
+			changes[key] = struct{}{}
+		}
+	})
+
+	if err != nil {
+		diff.Error("BoltDiffStore", "Diffs", err, "Completed")
+		return changes
+	}
+
+	diff.Log("BoltDiffStore", "Diffs", "Completed")
+	return changes
+}
+
+// Keys returns a lists of the keys of the diffs within the store, in the
+// order they were put. The keys are read from the bucket keys, only the
+// expired diffs leading them being decoded.
+func (diff *DiffStore) Keys() []string {
+	diff.Log("BoltDiffStore", "Keys", "Started")
+
+	var keys []string
+
+	err := diff.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(diffsBucket).Cursor()
+
+		k, err := diff.first(c)
+		if err != nil {
+			return err
+		}
+
+		for ; k != nil; k, _ = c.Next() {
+			keys = append(keys, idOf(binary.BigEndian.Uint64(k)))
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		diff.Error("BoltDiffStore", "Keys", err, "Completed")
+		return nil
+	}
+
+	diff.Log("BoltDiffStore", "Keys", "Completed")
+	return keys
+}
+
+// Latest implements the coquery.DiffLatest interface, returning the key of
+// the latest diff within the store, which is empty for no diffs. Only the
+// last key of the bucket is read.
+func (diff *DiffStore) Latest() string {
+	diff.Log("BoltDiffStore", "Latest", "Started")
+
+	var id string
+
+	err := diff.db.View(func(tx *bolt.Tx) error {
+		k, value := tx.Bucket(diffsBucket).Cursor().Last()
+		if k == nil {
+			return nil
+		}
+
+		// The latest diff expiring means every diff has.
+		if diff.maxAge > 0 {
+			var df coquery.Diff
+			if err := json.Unmarshal(value, &df); err != nil {
+				return err
+			}
+
+			if diff.expired(&df) {
+				return nil
+			}
+		}
+
+		id = idOf(binary.BigEndian.Uint64(k))
+		return nil
+	})
+
+	if err != nil {
+		diff.Error("BoltDiffStore", "Latest", err, "Completed")
+		return ""
+	}
+
+	diff.Log("BoltDiffStore", "Latest", "Completed")
+	return id
+}
+
+// Get retrieves a key diff record if it keys.
+func (diff *DiffStore) Get(record string) []string {
+	diff.Log("BoltDiffStore", "Get", "Started : Retrieve Record : Key[%s]", record)
+
+	df, err := diff.get(record)
+	if err != nil {
+		diff.Error("BoltDiffStore", "Get", err, "Completed")
+		return nil
+	}
+
+	diff.Log("BoltDiffStore", "Get", "Completed")
+	return df.Diff
+}
+
+// Has returns true/false if the record key exists within the store.
+func (diff *DiffStore) Has(key string) bool {
+	diff.Log("BoltDiffStore", "Has", "Started : Checking Key[%s]", key)
+
+	if _, err := diff.get(key); err != nil {
+		diff.Error("BoltDiffStore", "Has", err, "Completed")
+		return false
+	}
+
+	diff.Log("BoltDiffStore", "Has", "Completed")
+	return true
+}
+
+// Put stores a list of diffs and returns the associated key for this diff.
+func (diff *DiffStore) Put(record []string) string {
+	return diff.PutPatches(record, nil)
+}
+
+// PutPatches implements the coquery.DiffPatcher interface, storing a list of
+// diffs with the patches of its records and returning the associated key for
+// this diff. Expired diffs are removed within the same transaction.
+func (diff *DiffStore) PutPatches(record []string, patches map[string]data.Patch) string {
+	diff.Log("BoltDiffStore", "Put", "Started : Adding New Record : %s", fmt.Sprintf("%+v", record))
+
+	if len(record) < 1 {
+		diff.Error("BoltDiffStore", "Put", fmt.Errorf("Empty Record"), "Completed")
+		return utils.UUID()
+	}
+
+	var key string
+
+	err := diff.db.Update(func(tx *bolt.Tx) error {
+		if err := diff.compact(tx); err != nil {
+			return err
+		}
+
+		b := tx.Bucket(diffsBucket)
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+
+		key = idOf(seq)
+
+		value, err := json.Marshal(&coquery.Diff{
+			Key:     key,
+			Diff:    record,
+			Patches: patches,
+			Time:    time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		return b.Put(itob(seq), value)
+	})
+
+	if err != nil {
+		diff.Error("BoltDiffStore", "Put", err, "Completed")
+		return utils.UUID()
+	}
+
+	diff.wl.RLock()
+	{
+		for _, watcher := range diff.watchers {
+			watcher(key, record)
+		}
+	}
+	diff.wl.RUnlock()
+
+	diff.Log("BoltDiffStore", "Put", "Completed")
+	return key
+}
+
+// Watch implements the coquery.DiffWatcher interface, the watchers being
+// called synchronously by Put.
+func (diff *DiffStore) Watch(watcher func(id string, records []string)) func() {
+	diff.Log("BoltDiffStore", "Watch", "Started")
+
+	diff.wl.Lock()
+	defer diff.wl.Unlock()
+
+	if diff.watchers == nil {
+		diff.watchers = make(map[int]func(string, []string))
+	}
+
+	diff.watchID++
+	id := diff.watchID
+	diff.watchers[id] = watcher
+
+	diff.Log("BoltDiffStore", "Watch", "Completed")
+	return func() {
+		diff.wl.Lock()
+		defer diff.wl.Unlock()
+		delete(diff.watchers, id)
+	}
+}
+
+// Clear clears out all diffs within the store. The sequence of the new bucket
+// continues past the keys of the cleared diffs.
+func (diff *DiffStore) Clear() {
+	diff.Log("BoltDiffStore", "Clear", "Started")
+
+	err := diff.db.Update(func(tx *bolt.Tx) error {
+		last := tx.Bucket(diffsBucket).Sequence()
+
+		if err := tx.DeleteBucket(diffsBucket); err != nil {
+			return err
+		}
+
+		b, err := bucket(tx)
+		if err != nil {
+			return err
+		}
+
+		if b.Sequence() < last {
+			return b.SetSequence(last)
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		diff.Error("BoltDiffStore", "Clear", err, "Completed")
+		return
+	}
+
+	diff.Log("BoltDiffStore", "Clear", "Completed")
+}
+
+//==============================================================================
+
+// get returns the unexpired diff of the giving key.
+func (diff *DiffStore) get(id string) (*coquery.Diff, error) {
+	key, ok := keyOf(id)
+	if !ok {
+		return nil, coquery.ErrRecordNotFound
+	}
+
+	var df coquery.Diff
+
+	err := diff.db.View(func(tx *bolt.Tx) error {
+		value := tx.Bucket(diffsBucket).Get(key)
+		if value == nil {
+			return coquery.ErrRecordNotFound
+		}
+
+		if err := json.Unmarshal(value, &df); err != nil {
+			return err
+		}
+
+		if diff.expired(&df) {
+			return coquery.ErrRecordNotFound
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &df, nil
+}
+
+// each calls the function with every unexpired diff in order.
+func (diff *DiffStore) each(fn func(*coquery.Diff)) error {
+	return diff.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(diffsBucket).Cursor()
+
+		k, value := c.First()
+		return scan(c, k, value, diff.expired, fn)
+	})
+}
+
+// after calls the function with every diff put after the diff of the giving
+// key in order, returning false if that diff does not exist or expired.
+func (diff *DiffStore) after(id string, fn func(*coquery.Diff)) (bool, error) {
+	key, ok := keyOf(id)
+	if !ok {
+		return false, nil
+	}
+
+	var found bool
+
+	err := diff.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(diffsBucket).Cursor()
+
+		k, value := c.Seek(key)
+		if k == nil || !bytes.Equal(k, key) {
+			return nil
+		}
+
+		var df coquery.Diff
+		if err := json.Unmarshal(value, &df); err != nil {
+			return err
+		}
+
+		if diff.expired(&df) {
+			return nil
+		}
+
+		found = true
+
+		k, value = c.Next()
+		return scan(c, k, value, diff.expired, fn)
+	})
+
+	return found, err
+}
+
+// first moves the cursor to the first unexpired diff, returning its key or
+// nil for none. The expired diffs, being the oldest, are always the first
+// keys of the bucket and the only ones decoded.
+func (diff *DiffStore) first(c *bolt.Cursor) ([]byte, error) {
+	k, value := c.First()
+	if diff.maxAge == 0 {
+		return k, nil
+	}
+
+	for ; k != nil; k, value = c.Next() {
+		var df coquery.Diff
+		if err := json.Unmarshal(value, &df); err != nil {
+			return nil, err
+		}
+
+		if !diff.expired(&df) {
+			return k, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// compact removes the expired diffs, which being the oldest are always the
+// first keys of the bucket.
+func (diff *DiffStore) compact(tx *bolt.Tx) error {
+	if diff.maxAge == 0 {
+		return nil
+	}
+
+	c := tx.Bucket(diffsBucket).Cursor()
+
+	for k, value := c.First(); k != nil; k, value = c.Next() {
+		var df coquery.Diff
+		if err := json.Unmarshal(value, &df); err != nil {
+			return err
+		}
+
+		if !diff.expired(&df) {
+			return nil
+		}
+
+		if err := c.Delete(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// expired returns true/false if the diff is older than the maximum age.
+func (diff *DiffStore) expired(df *coquery.Diff) bool {
+	return diff.maxAge > 0 && time.Since(df.Time) >= diff.maxAge
+}
+
+//==============================================================================
+
+// bucket returns the diffs bucket, creating it with its sequence seeded from
+// the current time if needed.
+func bucket(tx *bolt.Tx) (*bolt.Bucket, error) {
+	if b := tx.Bucket(diffsBucket); b != nil {
+		return b, nil
+	}
+
+	b, err := tx.CreateBucket(diffsBucket)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.SetSequence(uint64(time.Now().UnixNano())); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// scan calls the function with the unexpired diffs from the giving key and
+// value of the cursor onwards.
+func scan(c *bolt.Cursor, k, value []byte, expired func(*coquery.Diff) bool, fn func(*coquery.Diff)) error {
+	for ; k != nil; k, value = c.Next() {
+		var df coquery.Diff
+		if err := json.Unmarshal(value, &df); err != nil {
+			return err
+		}
+
+		if expired(&df) {
+			continue
+		}
+
+		fn(&df)
+	}
+
+	return nil
+}
+
+// itob returns the big endian bytes of the sequence, which sort in order.
+func itob(seq uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, seq)
+	return key
+}
+
+// idOf returns the diff id of the sequence, its fixed width hexadecimal form.
+func idOf(seq uint64) string {
+	return fmt.Sprintf("%016x", seq)
+}
+
+// keyOf returns the bucket key of the diff id.
+func keyOf(id string) ([]byte, bool) {
+	if len(id) != 16 {
+		return nil, false
+	}
+
+	seq, err := strconv.ParseUint(id, 16, 64)
+	if err != nil {
+		return nil, false
+	}
+
+	return itob(seq), true
+}
+
+// unique returns the record keys without duplicates, in the order they were
+// first found.
+func unique(records []string) []string {
+	var found []string
+
+	seen := make(map[string]bool)
+	for _, key := range records {
+		if !seen[key] {
+			seen[key] = true
+			found = append(found, key)
+		}
+	}
+
+	return found
+}
+
+//==============================================================================
@@ -0,0 +1,161 @@
+package boltdb_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/diffs/boltdb"
+)
+
+//==============================================================================
+
+var events eventlog
+
+// eventlog provides a logger which discards its events.
+type eventlog struct{}
+
+// Log discards the standard log reports.
+func (l eventlog) Log(context interface{}, name string, message string, data ...interface{}) {}
+
+// Error discards the error reports.
+func (l eventlog) Error(context interface{}, name string, err error, message string, data ...interface{}) {
+}
+
+//==============================================================================
+
+// TestDiffStore validates the storing of diffs across reopening the store.
+func TestDiffStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coquery")
+	if err != nil {
+		t.Fatalf("\t%s\tShould have created a temporary directory: %s", tests.Failed, err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "diffs.db")
+
+	t.Logf("Given the need to store diffs durably")
+	{
+		diff, err := boltdb.NewDiffs(events, path)
+		if err != nil {
+			t.Fatalf("\t%s\tShould have opened the store: %s", tests.Failed, err)
+		}
+
+		id := diff.Put([]string{"1", "2", "3"})
+		diff.Put([]string{"1", "12"})
+		diff.PutPatches([]string{"31"}, map[string]data.Patch{
+			"31": {{Op: data.PatchReplace, Path: "/name", Value: "alex"}},
+		})
+
+		diff.Close()
+
+		t.Logf("\tWhen reopening the store")
+		{
+			diff, err = boltdb.NewDiffs(events, path)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have reopened the store: %s", tests.Failed, err)
+			}
+			defer diff.Close()
+
+			if !diff.Has(id) || len(diff.Get(id)) != 3 {
+				t.Fatalf("\t%s\tShould have found Diff Key[%s]", tests.Failed, id)
+			}
+			t.Logf("\t%s\tShould have found Diff Key[%s]", tests.Success, id)
+
+			if pl := diff.PullFrom(id); len(pl) != 3 {
+				t.Fatalf("\t%s\tShould find 3 record keys in diff list from Diff Key[%s]: %s", tests.Failed, id, pl)
+			}
+			t.Logf("\t%s\tShould find 3 record keys in diff list from Diff Key[%s]", tests.Success, id)
+
+			if patches := diff.PatchesFrom(id); len(patches) != 1 || len(patches["31"]) != 1 {
+				t.Fatalf("\t%s\tShould find the patch of record 31: %+v", tests.Failed, patches)
+			}
+			t.Logf("\t%s\tShould find the patch of record 31", tests.Success)
+		}
+
+		t.Logf("\tWhen putting diffs after reopening the store")
+		{
+			keys := diff.Keys()
+			next := diff.Put([]string{"4"})
+
+			if len(keys) != 3 || keys[0] != id || next <= keys[2] {
+				t.Fatalf("\t%s\tShould have kept the diffs in order: %s : %s", tests.Failed, keys, next)
+			}
+			t.Logf("\t%s\tShould have kept the diffs in order", tests.Success)
+
+			if latest := diff.Latest(); latest != next {
+				t.Fatalf("\t%s\tShould have found the latest Diff Key[%s]: %s", tests.Failed, next, latest)
+			}
+			t.Logf("\t%s\tShould have found the latest Diff Key[%s]", tests.Success, next)
+		}
+
+		t.Logf("\tWhen clearing the store")
+		{
+			last := diff.Keys()[3]
+			diff.Clear()
+
+			if diff.Has(id) || len(diff.Diffs()) != 0 {
+				t.Fatalf("\t%s\tShould have removed all diffs", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have removed all diffs", tests.Success)
+
+			if next := diff.Put([]string{"5"}); next <= last {
+				t.Fatalf("\t%s\tShould have continued past the cleared diffs: %s : %s", tests.Failed, last, next)
+			}
+			t.Logf("\t%s\tShould have continued past the cleared diffs", tests.Success)
+		}
+	}
+}
+
+// TestExpiringDiffStore validates the expiration of diffs.
+func TestExpiringDiffStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "coquery")
+	if err != nil {
+		t.Fatalf("\t%s\tShould have created a temporary directory: %s", tests.Failed, err)
+	}
+	defer os.RemoveAll(dir)
+
+	t.Logf("Given the need to expire stored diffs")
+	{
+		diff, err := boltdb.NewExpiringDiffs(events, filepath.Join(dir, "diffs.db"), 50*time.Millisecond)
+		if err != nil {
+			t.Fatalf("\t%s\tShould have opened the store: %s", tests.Failed, err)
+		}
+		defer diff.Close()
+
+		id := diff.Put([]string{"1", "2"})
+
+		t.Logf("\tWhen a diff outlives its maximum age")
+		{
+			<-time.After(100 * time.Millisecond)
+
+			if diff.Has(id) || diff.PullFrom(id) != nil {
+				t.Fatalf("\t%s\tShould have expired Diff Key[%s]", tests.Failed, id)
+			}
+			t.Logf("\t%s\tShould have expired Diff Key[%s]", tests.Success, id)
+
+			if latest := diff.Latest(); latest != "" || len(diff.Keys()) != 0 {
+				t.Fatalf("\t%s\tShould have no latest diff: %s", tests.Failed, latest)
+			}
+			t.Logf("\t%s\tShould have no latest diff", tests.Success)
+
+			next := diff.Put([]string{"3"})
+
+			if changes := diff.Diffs(); len(changes) != 1 {
+				t.Fatalf("\t%s\tShould have only the unexpired diff: %+v", tests.Failed, changes)
+			}
+			t.Logf("\t%s\tShould have only the unexpired diff", tests.Success)
+
+			if keys := diff.Keys(); len(keys) != 1 || keys[0] != next || diff.Latest() != next {
+				t.Fatalf("\t%s\tShould have only the key of the unexpired diff: %s", tests.Failed, keys)
+			}
+			t.Logf("\t%s\tShould have only the key of the unexpired diff", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
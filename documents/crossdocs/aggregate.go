@@ -0,0 +1,194 @@
+package crossdocs
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/storage"
+	"github.com/influx6/coquery/utils"
+)
+
+// Aggregate provides a sumex.Proc implementing struct that computes the
+// aggregate of a count(), sum(...), avg(...), min(...) or max(...) request over
+// the records of a previous response.
+type Aggregate struct {
+	Events
+}
+
+// Do provides the member function for processing aggregate requests.
+func (a *Aggregate) Do(req interface{}, err error) (interface{}, error) {
+	a.Log("crossdocs", "Aggregate.Do", "Received Request : %s", utils.Query.Query(req))
+
+	if err != nil {
+		a.Error("crossdocs", "Aggregate.Do", err, "Completed")
+		return nil, err
+	}
+
+	coreq, ok := req.(*coquery.Request)
+	if !ok {
+		a.Error("crossdocs", "Aggregate.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	ar, ok := coreq.R.(*coquery.Aggregate)
+	if !ok {
+		a.Error(coreq.R.RequestID(), "Aggregate.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	if coreq.LastResponse == nil {
+		err := errors.New("Invalid Previous Response: Found Nil")
+		a.Error(ar.RequestID(), "Aggregate.Do", err, "Completed")
+		return nil, &coquery.CoError{Rid: ar.RequestID(), Msg: "No Previous Response", IError: err}
+	}
+
+	records := AggregateRecords(coreq.LastResponse.Data, ar)
+
+	a.Log("crossdocs", "Aggregate.Do", "Completed")
+
+	return &coquery.Response{
+		Req:  ar,
+		Data: records,
+	}, nil
+}
+
+//==============================================================================
+
+// AggregateRecords returns the aggregate records for the giving request, after
+// filtering the records by its Match predicate. Grouped results are ordered by
+// their group value. Without a group a single record is always returned, even
+// when there are no records to aggregate.
+//
+// As with mongodb, sum and avg ignore values which are not numbers and min and
+// max ignore null and missing values.
+func AggregateRecords(records data.Parameters, agg *coquery.Aggregate) data.Parameters {
+	if agg.Match != nil {
+		records = Filter(records, agg.Match)
+	}
+
+	if agg.Group == "" {
+		return data.Parameters{{agg.Op: aggregate(records, agg)}}
+	}
+
+	var keys []interface{}
+	groups := make(map[interface{}]data.Parameters)
+
+	for _, record := range records {
+		val, _ := storage.PullKeys(record, agg.Group)
+		key := groupKey(val)
+
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, val)
+		}
+
+		groups[key] = append(groups[key], record)
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool {
+		cmp, _ := Compare(keys[i], keys[j])
+		return cmp < 0
+	})
+
+	var res data.Parameters
+
+	for _, key := range keys {
+		res = append(res, data.Parameter{
+			"group": key,
+			agg.Op:  aggregate(groups[groupKey(key)], agg),
+		})
+	}
+
+	return res
+}
+
+// groupKey returns a comparable form of the giving group value, numbers of
+// different go types are grouped by their value.
+func groupKey(val interface{}) interface{} {
+	rank, norm := normalize(val)
+
+	switch rank {
+	case rankOther, rankTime:
+		return utils.Query.Query(val)
+	}
+
+	return struct {
+		rank  int
+		value interface{}
+	}{rank, norm}
+}
+
+// aggregate returns the computed value of the aggregate operation over the
+// records.
+func aggregate(records data.Parameters, agg *coquery.Aggregate) interface{} {
+	if agg.Op == coquery.AggCount {
+		return len(records)
+	}
+
+	var values []interface{}
+
+	for _, record := range records {
+		if val, ok := storage.PullKeys(record, agg.Field); ok && val != nil {
+			values = append(values, val)
+		}
+	}
+
+	switch agg.Op {
+	case coquery.AggSum, coquery.AggAvg:
+		var total float64
+		var count int
+
+		ints := true
+
+		for _, val := range values {
+			rank, num := normalize(val)
+			if rank != rankNumber {
+				continue
+			}
+
+			switch val.(type) {
+			case float32, float64:
+				ints = false
+			}
+
+			total += num.(float64)
+			count++
+		}
+
+		if agg.Op == coquery.AggAvg {
+			if count == 0 {
+				return nil
+			}
+
+			return total / float64(count)
+		}
+
+		if ints {
+			return int64(total)
+		}
+
+		return total
+
+	case coquery.AggMin, coquery.AggMax:
+		var found interface{}
+
+		for index, val := range values {
+			if index == 0 {
+				found = val
+				continue
+			}
+
+			cmp, _ := Compare(val, found)
+			if (agg.Op == coquery.AggMin && cmp < 0) || (agg.Op == coquery.AggMax && cmp > 0) {
+				found = val
+			}
+		}
+
+		return found
+	}
+
+	return nil
+}
+
+//==============================================================================
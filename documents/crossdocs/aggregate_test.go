@@ -0,0 +1,64 @@
+package crossdocs_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/documents/crossdocs"
+)
+
+//==============================================================================
+
+// TestAggregateRecords validates the in-memory computation of aggregates.
+func TestAggregateRecords(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	records := data.Parameters{
+		{"state": "NY", "age": 20},
+		{"state": "CA", "age": int64(30)},
+		{"state": "NY", "age": 40.0},
+		{"state": "CA", "age": "unknown"},
+		{"age": 10},
+	}
+
+	cases := []struct {
+		name     string
+		agg      coquery.Aggregate
+		expected data.Parameters
+	}{
+		{"count", coquery.Aggregate{Op: "count"}, data.Parameters{{"count": 5}}},
+		{"sum of ints", coquery.Aggregate{Op: "sum", Field: "age", Match: &coquery.Predicate{Op: "ne", Field: "state", Value: "NY"}}, data.Parameters{{"sum": int64(40)}}},
+		{"avg", coquery.Aggregate{Op: "avg", Field: "age"}, data.Parameters{{"avg": 25.0}}},
+		{"avg of nothing", coquery.Aggregate{Op: "avg", Field: "rate"}, data.Parameters{{"avg": nil}}},
+		{"min", coquery.Aggregate{Op: "min", Field: "age"}, data.Parameters{{"min": 10}}},
+		{"grouped count", coquery.Aggregate{Op: "count", Group: "state"}, data.Parameters{
+			{"group": nil, "count": 1},
+			{"group": "CA", "count": 2},
+			{"group": "NY", "count": 2},
+		}},
+		{"grouped max", coquery.Aggregate{Op: "max", Field: "age", Group: "state"}, data.Parameters{
+			{"group": nil, "max": 10},
+			{"group": "CA", "max": "unknown"},
+			{"group": "NY", "max": 40.0},
+		}},
+	}
+
+	t.Logf("Given the need to aggregate records")
+	{
+		for _, tc := range cases {
+			t.Logf("\tWhen computing the %s", tc.name)
+			{
+				res := crossdocs.AggregateRecords(records, &tc.agg)
+
+				if !reflect.DeepEqual(res, tc.expected) {
+					t.Fatalf("\t%s\tShould have computed %v: %v", tests.Failed, tc.expected, res)
+				}
+				t.Logf("\t%s\tShould have computed %v", tests.Success, tc.expected)
+			}
+		}
+	}
+}
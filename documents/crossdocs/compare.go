@@ -0,0 +1,128 @@
+package crossdocs
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/influx6/coquery/data"
+)
+
+//==============================================================================
+
+// contains the ordering ranks of the kinds of values compared, values of
+// different kinds are ordered by their rank.
+const (
+	rankNull = iota
+	rankNumber
+	rankString
+	rankObjectID
+	rankBool
+	rankTime
+	rankOther
+)
+
+// hexer defines the method provided by object id types eg bson.ObjectId.
+type hexer interface {
+	Hex() string
+}
+
+// normalize returns the rank of the giving value and its comparable form.
+func normalize(v interface{}) (int, interface{}) {
+	switch value := v.(type) {
+	case nil:
+		return rankNull, nil
+	case int:
+		return rankNumber, float64(value)
+	case int8:
+		return rankNumber, float64(value)
+	case int16:
+		return rankNumber, float64(value)
+	case int32:
+		return rankNumber, float64(value)
+	case int64:
+		return rankNumber, float64(value)
+	case uint:
+		return rankNumber, float64(value)
+	case uint8:
+		return rankNumber, float64(value)
+	case uint16:
+		return rankNumber, float64(value)
+	case uint32:
+		return rankNumber, float64(value)
+	case uint64:
+		return rankNumber, float64(value)
+	case float32:
+		return rankNumber, float64(value)
+	case float64:
+		return rankNumber, value
+	case string:
+		return rankString, value
+	case data.ObjectID:
+		return rankObjectID, string(value)
+	case hexer:
+		return rankObjectID, value.Hex()
+	case bool:
+		return rankBool, value
+	case time.Time:
+		return rankTime, value
+	}
+
+	return rankOther, v
+}
+
+// Compare returns -1, 0 or 1 if a is less than, equal to or greater than b,
+// along with true/false if both values are of the same kind. Numbers are
+// compared by value regardless of their go type, object ids are compared by
+// their hexadecimal form and values of different kinds are ordered by kind:
+// null, numbers, strings, object ids, booleans, times and then others.
+func Compare(a, b interface{}) (int, bool) {
+	ra, va := normalize(a)
+	rb, vb := normalize(b)
+
+	if ra != rb {
+		if ra < rb {
+			return -1, false
+		}
+
+		return 1, false
+	}
+
+	switch ra {
+	case rankNull:
+		return 0, true
+
+	case rankNumber:
+		return order(va.(float64) < vb.(float64), va.(float64) > vb.(float64)), true
+
+	case rankString, rankObjectID:
+		return order(va.(string) < vb.(string), va.(string) > vb.(string)), true
+
+	case rankBool:
+		return order(!va.(bool) && vb.(bool), va.(bool) && !vb.(bool)), true
+
+	case rankTime:
+		return order(va.(time.Time).Before(vb.(time.Time)), va.(time.Time).After(vb.(time.Time))), true
+	}
+
+	// Other values such as maps and slices have no ordering and can only be
+	// checked for equality.
+	if reflect.DeepEqual(va, vb) {
+		return 0, true
+	}
+
+	return 0, false
+}
+
+// order returns the comparison result for the giving less and greater checks.
+func order(less, greater bool) int {
+	switch {
+	case less:
+		return -1
+	case greater:
+		return 1
+	}
+
+	return 0
+}
+
+//==============================================================================
@@ -0,0 +1,195 @@
+package crossdocs
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/storage"
+	"github.com/influx6/coquery/utils"
+)
+
+// Join provides a sumex.Proc implementing struct that embeds the records of
+// another document within the records of a previous response for join(...) and
+// expand(...) requests. The records of the other document are retrieved with a
+// single where(field in [...]) request through the request's Resolver,
+// regardless of the number of records embedding them. The request is resolved
+// for the principal of the join and under the context of its query.
+type Join struct {
+	Events
+}
+
+// Do provides the member function for processing join requests.
+func (j *Join) Do(req interface{}, err error) (interface{}, error) {
+	j.Log("crossdocs", "Join.Do", "Received Request : %s", utils.Query.Query(req))
+
+	if err != nil {
+		j.Error("crossdocs", "Join.Do", err, "Completed")
+		return nil, err
+	}
+
+	coreq, ok := req.(*coquery.Request)
+	if !ok {
+		j.Error("crossdocs", "Join.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	jr, ok := coreq.R.(*coquery.Join)
+	if !ok {
+		j.Error(coreq.R.RequestID(), "Join.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	if coreq.LastResponse == nil {
+		err := errors.New("Invalid Previous Response: Found Nil")
+		j.Error(jr.RequestID(), "Join.Do", err, "Completed")
+		return nil, &coquery.CoError{Rid: jr.RequestID(), Msg: "No Previous Response", IError: err}
+	}
+
+	if jr.Resolver == nil {
+		err := errors.New("Invalid Resolver: Found Nil")
+		j.Error(jr.RequestID(), "Join.Do", err, "Completed")
+		return nil, &coquery.CoError{Rid: jr.RequestID(), Msg: "No Document Resolver", IError: err}
+	}
+
+	records := coreq.LastResponse.Data
+
+	// Collect the distinct values referenced by the records.
+	var refs []interface{}
+	seen := make(map[interface{}]bool)
+
+	for _, record := range records {
+		val, ok := storage.PullKeys(record, jr.Local)
+		if !ok || val == nil {
+			continue
+		}
+
+		items, ok := val.([]interface{})
+		if !ok || !jr.Expand {
+			items = []interface{}{val}
+		}
+
+		for _, item := range items {
+			if key := groupKey(item); !seen[key] {
+				seen[key] = true
+				refs = append(refs, item)
+			}
+		}
+	}
+
+	matches := make(map[interface{}]data.Parameters)
+
+	if len(refs) > 0 {
+
+		// Resolved requests use their own request id, so their responses are
+		// not mistaken for the response of this request.
+		rid := jr.RequestID() + ":" + jr.RequestName() + ":" + jr.Path
+
+		rctx := (&data.RequestContext{RequestID: rid, Principal: jr.Principal}).WithContext(coreq.Context())
+
+		res, rerr := jr.Resolver.Resolve(jr.RequestID(), rctx, jr.Path, coquery.RecordRequests{&coquery.Where{
+			Doc:       storage.LastKey(jr.Path),
+			RID:       rid,
+			Predicate: &coquery.Predicate{Op: coquery.OpIn, Field: jr.Field, Value: refs},
+		}})
+
+		if rerr != nil {
+			j.Error(jr.RequestID(), "Join.Do", rerr, "Completed")
+			return nil, &coquery.CoError{Rid: jr.RequestID(), Msg: "Join Document Failed", IError: rerr}
+		}
+
+		for _, match := range res.Data {
+			val, _ := storage.PullKeys(match, jr.Field)
+			key := groupKey(val)
+			matches[key] = append(matches[key], match)
+		}
+	}
+
+	var joined data.Parameters
+
+	for _, record := range records {
+		val, found := storage.PullKeys(record, jr.Local)
+
+		if !jr.Expand {
+			embed := make([]interface{}, 0)
+
+			if found && val != nil {
+				for _, match := range matches[groupKey(val)] {
+					embed = append(embed, match)
+				}
+			}
+
+			joined = append(joined, embedKey(record, jr.As, embed))
+			continue
+		}
+
+		if !found || val == nil {
+			joined = append(joined, record)
+			continue
+		}
+
+		// References which match no record are left as they are.
+		expand := func(ref interface{}) interface{} {
+			if found := matches[groupKey(ref)]; len(found) > 0 {
+				return found[0]
+			}
+
+			return ref
+		}
+
+		if items, ok := val.([]interface{}); ok {
+			expanded := make([]interface{}, 0, len(items))
+
+			for _, item := range items {
+				expanded = append(expanded, expand(item))
+			}
+
+			joined = append(joined, embedKey(record, jr.As, expanded))
+			continue
+		}
+
+		joined = append(joined, embedKey(record, jr.As, expand(val)))
+	}
+
+	j.Log(jr.RequestID(), "Join.Do", "Info : Joined %d records from %s", len(refs), jr.Path)
+	j.Log("crossdocs", "Join.Do", "Completed")
+
+	return &coquery.Response{
+		Req:  jr,
+		Data: joined,
+	}, nil
+}
+
+//==============================================================================
+
+// embedKey returns a copy of the record with the period delimited key set to
+// the giving value, leaving the original record unchanged.
+func embedKey(record data.Parameter, key string, val interface{}) data.Parameter {
+	keys := strings.Split(key, ".")
+
+	to := make(data.Parameter, len(record)+1)
+	for k, v := range record {
+		to[k] = v
+	}
+
+	last := map[string]interface{}(to)
+
+	for _, k := range keys[:len(keys)-1] {
+		next := make(map[string]interface{})
+
+		if sub, ok := last[k].(map[string]interface{}); ok {
+			for sk, sv := range sub {
+				next[sk] = sv
+			}
+		}
+
+		last[k] = next
+		last = next
+	}
+
+	last[keys[len(keys)-1]] = val
+	return to
+}
+
+//==============================================================================
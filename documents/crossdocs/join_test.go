@@ -0,0 +1,125 @@
+package crossdocs_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/documents/crossdocs"
+)
+
+//==============================================================================
+
+// events provides a no-op event logger for the processors.
+type events struct{}
+
+// Log logs all standard log reports.
+func (events) Log(context interface{}, name string, message string, data ...interface{}) {}
+
+// Error logs all error reports.
+func (events) Error(context interface{}, name string, err error, message string, data ...interface{}) {
+}
+
+// resolver provides a coquery.Resolver which serves where requests from a
+// fixed set of records, counting the requests it receives.
+type resolver struct {
+	records data.Parameters
+	paths   []string
+}
+
+// Resolve filters the records with the where request's predicate.
+func (r *resolver) Resolve(context interface{}, rctx *data.RequestContext, path string, reqs coquery.RecordRequests) (*coquery.Response, coquery.ResponseError) {
+	r.paths = append(r.paths, path)
+
+	where := reqs[0].(*coquery.Where)
+	return &coquery.Response{Req: where, Data: crossdocs.Filter(r.records, where.Predicate)}, nil
+}
+
+//==============================================================================
+
+// TestJoin validates the embedding of records from other documents.
+func TestJoin(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	users := data.Parameters{
+		{"id": 1, "name": "alex"},
+		{"id": 2, "name": "bob"},
+		{"id": 3, "name": "ruth"},
+	}
+
+	books := data.Parameters{
+		{"id": 10, "uid": 1, "authors": []interface{}{1, 2}},
+		{"id": 11, "uid": 1, "authors": []interface{}{9}},
+		{"id": 12, "uid": 2, "authors": []interface{}{2}},
+	}
+
+	t.Logf("Given the need to embed records from other documents")
+	{
+		t.Logf("\tWhen joining the books of users")
+		{
+			res := &resolver{records: books}
+			join := &coquery.Join{RID: "4", Path: "books", Field: "uid", Local: "id", As: "books", Resolver: res}
+
+			reply, err := (&crossdocs.Join{Events: events{}}).Do(&coquery.Request{R: join, LastResponse: &coquery.Response{Data: users}}, nil)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have joined the records: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have joined the records", tests.Success)
+
+			if len(res.paths) != 1 {
+				t.Fatalf("\t%s\tShould have resolved the books with a single request: %d", tests.Failed, len(res.paths))
+			}
+			t.Logf("\t%s\tShould have resolved the books with a single request", tests.Success)
+
+			records := reply.(*coquery.Response).Data
+
+			var counts []int
+			for _, record := range records {
+				counts = append(counts, len(record["books"].([]interface{})))
+			}
+
+			if !reflect.DeepEqual(counts, []int{2, 1, 0}) {
+				t.Fatalf("\t%s\tShould have embedded 2, 1 and 0 books: %v", tests.Failed, counts)
+			}
+			t.Logf("\t%s\tShould have embedded 2, 1 and 0 books", tests.Success)
+
+			if _, ok := users[0]["books"]; ok {
+				t.Fatalf("\t%s\tShould have left the original records unchanged", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have left the original records unchanged", tests.Success)
+		}
+
+		t.Logf("\tWhen expanding the authors of books")
+		{
+			res := &resolver{records: users}
+			join := &coquery.Join{RID: "4", Path: "docs.users", Field: "id", Local: "authors", As: "authors", Expand: true, Resolver: res}
+
+			reply, err := (&crossdocs.Join{Events: events{}}).Do(&coquery.Request{R: join, LastResponse: &coquery.Response{Data: books}}, nil)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have expanded the records: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have expanded the records", tests.Success)
+
+			if len(res.paths) != 1 || res.paths[0] != "docs.users" {
+				t.Fatalf("\t%s\tShould have resolved the users with a single request: %v", tests.Failed, res.paths)
+			}
+			t.Logf("\t%s\tShould have resolved the users with a single request", tests.Success)
+
+			records := reply.(*coquery.Response).Data
+
+			authors := records[0]["authors"].([]interface{})
+			if len(authors) != 2 || authors[1].(data.Parameter)["name"] != "bob" {
+				t.Fatalf("\t%s\tShould have expanded the authors of the first book: %v", tests.Failed, authors)
+			}
+			t.Logf("\t%s\tShould have expanded the authors of the first book", tests.Success)
+
+			if missing := records[1]["authors"].([]interface{}); missing[0] != 9 {
+				t.Fatalf("\t%s\tShould have left unknown authors as they are: %v", tests.Failed, missing)
+			}
+			t.Logf("\t%s\tShould have left unknown authors as they are", tests.Success)
+		}
+	}
+}
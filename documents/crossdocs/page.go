@@ -0,0 +1,75 @@
+package crossdocs
+
+import (
+	"errors"
+
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/utils"
+)
+
+// Page provides a sumex.Proc implementing struct that retrieves a page of the
+// records of a previous response using the order and cursor of a page(...)
+// request.
+type Page struct {
+	Events
+}
+
+// Do provides the member function for processing page requests.
+func (p *Page) Do(req interface{}, err error) (interface{}, error) {
+	p.Log("crossdocs", "Page.Do", "Received Request : %s", utils.Query.Query(req))
+
+	if err != nil {
+		p.Error("crossdocs", "Page.Do", err, "Completed")
+		return nil, err
+	}
+
+	coreq, ok := req.(*coquery.Request)
+	if !ok {
+		p.Error("crossdocs", "Page.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	pr, ok := coreq.R.(*coquery.Page)
+	if !ok {
+		p.Error(coreq.R.RequestID(), "Page.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	if coreq.LastResponse == nil {
+		err := errors.New("Invalid Previous Response: Found Nil")
+		p.Error(pr.RequestID(), "Page.Do", err, "Completed")
+		return nil, &coquery.CoError{Rid: pr.RequestID(), Msg: "No Previous Response", IError: err}
+	}
+
+	records, more := PageRecords(coreq.LastResponse.Data, pr)
+
+	res, err := pr.Next(records, more)
+	if err != nil {
+		p.Error(pr.RequestID(), "Page.Do", err, "Completed")
+		return nil, &coquery.CoError{Rid: pr.RequestID(), Msg: "Page Cursor Failed", IError: err}
+	}
+
+	p.Log("crossdocs", "Page.Do", "Completed")
+	return res, nil
+}
+
+//==============================================================================
+
+// PageRecords returns the page of records for the giving page request, along
+// with true/false if more records remain after it.
+func PageRecords(records data.Parameters, page *coquery.Page) (data.Parameters, bool) {
+	sorted := SortRecords(records, page.Sort)
+
+	if pred := page.Predicate(); pred != nil {
+		sorted = Filter(sorted, pred)
+	}
+
+	if len(sorted) <= page.Size {
+		return sorted, false
+	}
+
+	return sorted[:page.Size], true
+}
+
+//==============================================================================
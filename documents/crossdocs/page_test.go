@@ -0,0 +1,101 @@
+package crossdocs_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/documents/crossdocs"
+)
+
+//==============================================================================
+
+// TestPageRecords validates paging through records using their cursors.
+func TestPageRecords(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	records := data.Parameters{
+		{"id": 1, "age": 20},
+		{"id": 2, "age": 30},
+		{"id": 3, "age": 20},
+		{"id": 4, "age": 30},
+		{"id": 5, "age": 25},
+	}
+
+	page := coquery.Page{
+		Size: 2,
+		Sort: []coquery.SortField{{Key: "age", Desc: true}, {Key: "id"}},
+	}
+
+	t.Logf("Given the need to page through records")
+	{
+		t.Logf("\tWhen paging by age descending two records at a time")
+		{
+			var ids []interface{}
+			var pages int
+
+			for {
+				recs, more := crossdocs.PageRecords(records, &page)
+				pages++
+
+				for _, rec := range recs {
+					ids = append(ids, rec["id"])
+				}
+
+				res, err := page.Next(recs, more)
+				if err != nil {
+					t.Fatalf("\t%s\tShould have created the page response: %s", tests.Failed, err)
+				}
+
+				if res.NextCursor == "" {
+					break
+				}
+
+				after, err := coquery.DecodeCursor(res.NextCursor, page.Sort)
+				if err != nil {
+					t.Fatalf("\t%s\tShould have decoded the next cursor: %s", tests.Failed, err)
+				}
+
+				page.After = after
+			}
+
+			if pages != 3 {
+				t.Fatalf("\t%s\tShould have retrieved 3 pages: %d", tests.Failed, pages)
+			}
+			t.Logf("\t%s\tShould have retrieved 3 pages", tests.Success)
+
+			var order []int
+			for _, id := range ids {
+				order = append(order, id.(int))
+			}
+
+			if len(order) != 5 || order[0] != 2 || order[1] != 4 || order[2] != 5 || order[3] != 1 || order[4] != 3 {
+				t.Fatalf("\t%s\tShould have retrieved every record once in order: %v", tests.Failed, order)
+			}
+			t.Logf("\t%s\tShould have retrieved every record once in order", tests.Success)
+		}
+
+		t.Logf("\tWhen paging through the records matching a where")
+		{
+			page.After = nil
+			page.Match = &coquery.Predicate{Op: coquery.OpLt, Field: "age", Value: 30}
+
+			recs, more := crossdocs.PageRecords(records, &page)
+			if len(recs) != 2 || !more || recs[0]["id"] != 5 || recs[1]["id"] != 1 {
+				t.Fatalf("\t%s\tShould have retrieved the first matching records: %v", tests.Failed, recs)
+			}
+			t.Logf("\t%s\tShould have retrieved the first matching records", tests.Success)
+
+			res, _ := page.Next(recs, more)
+			page.After, _ = coquery.DecodeCursor(res.NextCursor, page.Sort)
+
+			recs, more = crossdocs.PageRecords(records, &page)
+			if len(recs) != 1 || more || recs[0]["id"] != 3 {
+				t.Fatalf("\t%s\tShould have retrieved the last matching record: %v", tests.Failed, recs)
+			}
+			t.Logf("\t%s\tShould have retrieved the last matching record", tests.Success)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package crossdocs
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/storage"
+	"github.com/influx6/coquery/utils"
+)
+
+// Sort provides a sumex.Proc implementing struct that orders the records of a
+// previous response using the fields of a sort(...) request.
+type Sort struct {
+	Events
+}
+
+// Do provides the member function for processing sort requests.
+func (s *Sort) Do(req interface{}, err error) (interface{}, error) {
+	s.Log("crossdocs", "Sort.Do", "Received Request : %s", utils.Query.Query(req))
+
+	if err != nil {
+		s.Error("crossdocs", "Sort.Do", err, "Completed")
+		return nil, err
+	}
+
+	coreq, ok := req.(*coquery.Request)
+	if !ok {
+		s.Error("crossdocs", "Sort.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	sr, ok := coreq.R.(*coquery.Sort)
+	if !ok {
+		s.Error(coreq.R.RequestID(), "Sort.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	if coreq.LastResponse == nil {
+		err := errors.New("Invalid Previous Response: Found Nil")
+		s.Error(sr.RequestID(), "Sort.Do", err, "Completed")
+		return nil, &coquery.CoError{Rid: sr.RequestID(), Msg: "No Previous Response", IError: err}
+	}
+
+	records := SortRecords(coreq.LastResponse.Data, sr.Fields)
+
+	s.Log("crossdocs", "Sort.Do", "Completed")
+
+	return &coquery.Response{
+		Req:  sr,
+		Data: records,
+	}, nil
+}
+
+//==============================================================================
+
+// SortRecords returns a copy of the records ordered by the giving fields. The
+// sort is stable and uses Compare, hence records missing a field are ordered
+// first as nulls.
+func SortRecords(records data.Parameters, fields []coquery.SortField) data.Parameters {
+	sorted := make(data.Parameters, len(records))
+	copy(sorted, records)
+
+	sort.SliceStable(sorted, func(i, j int) bool {
+		for _, field := range fields {
+			a, _ := storage.PullKeys(sorted[i], field.Key)
+			b, _ := storage.PullKeys(sorted[j], field.Key)
+
+			cmp, _ := Compare(a, b)
+			if cmp == 0 {
+				continue
+			}
+
+			if field.Desc {
+				return cmp > 0
+			}
+
+			return cmp < 0
+		}
+
+		return false
+	})
+
+	return sorted
+}
+
+//==============================================================================
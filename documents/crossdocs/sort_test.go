@@ -0,0 +1,65 @@
+package crossdocs_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/documents/crossdocs"
+)
+
+//==============================================================================
+
+// TestSortRecords validates the ordering of records by multiple fields.
+func TestSortRecords(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	records := data.Parameters{
+		{"name": "c", "age": 20},
+		{"name": "a", "age": int64(30)},
+		{"name": "d"},
+		{"name": "b", "age": 20.0},
+		{"name": "e", "age": 30},
+	}
+
+	t.Logf("Given the need to order records")
+	{
+		t.Logf("\tWhen sorting by age descending")
+		{
+			sorted := crossdocs.SortRecords(records, []coquery.SortField{{Key: "age", Desc: true}})
+
+			var names string
+			for _, rec := range sorted {
+				names += rec["name"].(string)
+			}
+
+			// Equal ages keep their original order and the missing age comes last.
+			if names != "aecbd" {
+				t.Fatalf("\t%s\tShould have ordered the records as %q: %q", tests.Failed, "aecbd", names)
+			}
+			t.Logf("\t%s\tShould have ordered the records as %q", tests.Success, "aecbd")
+
+			if records[0]["name"] != "c" {
+				t.Fatalf("\t%s\tShould have left the original records unchanged", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have left the original records unchanged", tests.Success)
+		}
+
+		t.Logf("\tWhen sorting by age and then name descending")
+		{
+			sorted := crossdocs.SortRecords(records, []coquery.SortField{{Key: "age"}, {Key: "name", Desc: true}})
+
+			var names string
+			for _, rec := range sorted {
+				names += rec["name"].(string)
+			}
+
+			if names != "dcbea" {
+				t.Fatalf("\t%s\tShould have ordered the records as %q: %q", tests.Failed, "dcbea", names)
+			}
+			t.Logf("\t%s\tShould have ordered the records as %q", tests.Success, "dcbea")
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package crossdocs
+
+import (
+	"errors"
+
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/storage"
+	"github.com/influx6/coquery/utils"
+)
+
+// Where provides a sumex.Proc implementing struct that filters the records of
+// a previous response using the predicate of a where(...) request.
+type Where struct {
+	Events
+}
+
+// Do provides the member function for processing where requests.
+func (w *Where) Do(req interface{}, err error) (interface{}, error) {
+	w.Log("crossdocs", "Where.Do", "Received Request : %s", utils.Query.Query(req))
+
+	if err != nil {
+		w.Error("crossdocs", "Where.Do", err, "Completed")
+		return nil, err
+	}
+
+	coreq, ok := req.(*coquery.Request)
+	if !ok {
+		w.Error("crossdocs", "Where.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	wr, ok := coreq.R.(*coquery.Where)
+	if !ok {
+		w.Error(coreq.R.RequestID(), "Where.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	if coreq.LastResponse == nil {
+		err := errors.New("Invalid Previous Response: Found Nil")
+		w.Error(wr.RequestID(), "Where.Do", err, "Completed")
+		return nil, &coquery.CoError{Rid: wr.RequestID(), Msg: "No Previous Response", IError: err}
+	}
+
+	records := Filter(coreq.LastResponse.Data, wr.Predicate)
+
+	w.Log(wr.RequestID(), "Where.Do", "Info : Matched %d of %d records", len(records), len(coreq.LastResponse.Data))
+	w.Log("crossdocs", "Where.Do", "Completed")
+
+	return &coquery.Response{
+		Req:  wr,
+		Data: records,
+	}, nil
+}
+
+//==============================================================================
+
+// Filter returns the records which match the giving predicate.
+func Filter(records data.Parameters, pred *coquery.Predicate) data.Parameters {
+	var matched data.Parameters
+
+	for _, record := range records {
+		if Match(record, pred) {
+			matched = append(matched, record)
+		}
+	}
+
+	return matched
+}
+
+// Match returns true/false if the record matches the giving predicate. The
+// comparisons follow those of mongodb: a missing field equals null, a field
+// holding an array matches if any of its items match and values of different
+// kinds never match the ordering operators.
+func Match(rec map[string]interface{}, pred *coquery.Predicate) bool {
+	switch pred.Op {
+	case coquery.OpAnd:
+		for _, operand := range pred.Operands {
+			if !Match(rec, operand) {
+				return false
+			}
+		}
+
+		return true
+
+	case coquery.OpOr:
+		for _, operand := range pred.Operands {
+			if Match(rec, operand) {
+				return true
+			}
+		}
+
+		return false
+
+	case coquery.OpNot:
+		return len(pred.Operands) == 1 && !Match(rec, pred.Operands[0])
+	}
+
+	val, found := storage.PullKeys(rec, pred.Field)
+
+	switch pred.Op {
+	case coquery.OpEq:
+		return equals(val, pred.Value)
+
+	case coquery.OpNe:
+		return !equals(val, pred.Value)
+
+	case coquery.OpIn:
+		return within(val, pred.Value)
+
+	case coquery.OpNin:
+		return !within(val, pred.Value)
+	}
+
+	if !found {
+		return false
+	}
+
+	return anyItem(val, func(item interface{}) bool {
+		cmp, ok := Compare(item, pred.Value)
+		if !ok {
+			return false
+		}
+
+		switch pred.Op {
+		case coquery.OpGt:
+			return cmp > 0
+		case coquery.OpGte:
+			return cmp >= 0
+		case coquery.OpLt:
+			return cmp < 0
+		case coquery.OpLte:
+			return cmp <= 0
+		}
+
+		return false
+	})
+}
+
+// equals returns true/false if the record value or any of its items equals
+// the giving value.
+func equals(val interface{}, to interface{}) bool {
+	if cmp, ok := Compare(val, to); ok && cmp == 0 {
+		return true
+	}
+
+	return anyItem(val, func(item interface{}) bool {
+		cmp, ok := Compare(item, to)
+		return ok && cmp == 0
+	})
+}
+
+// within returns true/false if the record value equals any of the items of
+// the giving list.
+func within(val interface{}, list interface{}) bool {
+	items, _ := list.([]interface{})
+
+	for _, item := range items {
+		if equals(val, item) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// anyItem returns true/false if the check passes for the value or any of its
+// items if it is an array.
+func anyItem(val interface{}, check func(interface{}) bool) bool {
+	items, ok := val.([]interface{})
+	if !ok {
+		return check(val)
+	}
+
+	for _, item := range items {
+		if check(item) {
+			return true
+		}
+	}
+
+	return false
+}
+
+//==============================================================================
@@ -0,0 +1,79 @@
+package crossdocs_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/documents/crossdocs"
+	"github.com/influx6/coquery/parser"
+)
+
+//==============================================================================
+
+func init() {
+	tests.Init("")
+}
+
+//==============================================================================
+
+// TestMatch validates the in-memory evaluation of where predicates.
+func TestMatch(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	record := data.Parameter{
+		"name":    "alex",
+		"age":     int64(24),
+		"rate":    0.5,
+		"tags":    []interface{}{"admin", "dev"},
+		"_id":     data.ObjectID("5707a1d1e4b0e5a0c8f2b1a3"),
+		"address": map[string]interface{}{"zip": "02134"},
+	}
+
+	cases := []struct {
+		expr  string
+		match bool
+	}{
+		{"age > 21", true},
+		{"age >= 24.0", true},
+		{"age < 24", false},
+		{"age > '21'", false},
+		{"name == 'alex' && rate <= 0.5", true},
+		{"name == 'bob' || address.zip == \"02134\"", true},
+		{"!(name == 'alex')", false},
+		{"tags == 'dev'", true},
+		{"tags in ['ops','admin']", true},
+		{"name not in ['alex']", false},
+		{"missing == null", true},
+		{"missing != 1", true},
+		{"missing > 1", false},
+		{"_id == oid('5707a1d1e4b0e5a0c8f2b1a3')", true},
+	}
+
+	t.Logf("Given the need to match records against where predicates")
+	{
+		for _, tc := range cases {
+			t.Logf("\tWhen giving the expression %q", tc.expr)
+			{
+				q, err := parser.Parse("docs.users.where(" + tc.expr + ")")
+				if err != nil {
+					t.Fatalf("\t%s\tShould have parsed the expression: %s", tests.Failed, err)
+				}
+
+				call := q.Calls[0]
+
+				pred, err := coquery.NewPredicate(call, call.Args[0])
+				if err != nil {
+					t.Fatalf("\t%s\tShould have built the predicate: %s", tests.Failed, err)
+				}
+
+				if crossdocs.Match(record, pred) != tc.match {
+					t.Fatalf("\t%s\tShould have a match result of %t", tests.Failed, tc.match)
+				}
+				t.Logf("\t%s\tShould have a match result of %t", tests.Success, tc.match)
+			}
+		}
+	}
+}
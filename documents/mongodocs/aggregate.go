@@ -0,0 +1,130 @@
+package mongodocs
+
+import (
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/documents/crossdocs"
+	"github.com/influx6/coquery/utils"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//==========================================================================================
+
+// Aggregate provides a worker for handling aggregate requests, computing them
+// in memory over the records of a previous response or else within a mongo
+// aggregation pipeline.
+type Aggregate struct {
+	Events
+	Db DB
+}
+
+// Do performs the necessary tasks passed to Aggregate.
+func (a *Aggregate) Do(dataReq interface{}, err error) (interface{}, error) {
+	a.Log("mongodocs", "Aggregate.Do", "Started : %s", utils.Query.Query(dataReq))
+
+	if err != nil {
+		a.Error("mongodocs", "Aggregate.Do", err, "Completed")
+		return nil, err
+	}
+
+	req, ok := dataReq.(*coquery.Request)
+	if !ok {
+		a.Error("mongodocs", "Aggregate.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	agg, ok := req.R.(*coquery.Aggregate)
+	if !ok {
+		a.Error(req.R.RequestID(), "Aggregate.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	// If we had a previous response, then we are dealing with a concatenated
+	// operation on the last request, so we aggregate its records.
+	if req.LastResponse != nil {
+		res := crossdocs.AggregateRecords(req.LastResponse.Data, agg)
+
+		a.Log(agg.RequestID(), "Aggregate.Do", "Completed")
+
+		return &coquery.Response{
+			Req:  agg,
+			Data: res,
+		}, nil
+	}
+
+	db, session, err := newSession(req, a.Db, agg.RequestID())
+	if err != nil {
+		a.Error(agg.RequestID(), "db.New", err, "Completed : New Session")
+		return nil, &MError{Rid: agg.RID, Msg: "New Session Failed", IError: err}
+	}
+
+	defer session.Close()
+
+	pipeline := mongoPipeline(agg)
+	a.Log(agg.RequestID(), "DBAction", "db.%s.aggregate(%s)", agg.Doc, utils.Query.Query(pipeline))
+
+	var res data.Parameters
+
+	if err := db.C(agg.Doc).Pipe(pipeline).All(&res); err != nil {
+		a.Error(agg.RequestID(), "DBAction", err, "Completed")
+		return nil, &MError{Rid: agg.RID, Msg: "Aggregate Failed", IError: err}
+	}
+
+	// Mongo returns no result when there are no records to aggregate, so we
+	// provide the same empty aggregate as the in-memory aggregation.
+	if len(res) == 0 && agg.Group == "" {
+		res = crossdocs.AggregateRecords(nil, agg)
+	}
+
+	a.Log(agg.RequestID(), "Aggregate.Do", "Info : Response : %s", utils.Query.Query(res))
+	a.Log(agg.RequestID(), "Aggregate.Do", "Completed")
+	a.Log("mongodocs", "Aggregate.Do", "Completed")
+
+	return &coquery.Response{
+		Req:  agg,
+		Data: res,
+	}, nil
+}
+
+//==========================================================================================
+
+// mongoPipeline returns the mongo aggregation pipeline for the giving request,
+// producing records of the same form as crossdocs.AggregateRecords.
+func mongoPipeline(agg *coquery.Aggregate) []bson.M {
+	var pipeline []bson.M
+
+	if agg.Match != nil {
+		pipeline = append(pipeline, bson.M{"$match": mongoQuery(agg.Match)})
+	}
+
+	var acc bson.M
+
+	if agg.Op == coquery.AggCount {
+		acc = bson.M{"$sum": 1}
+	} else {
+		acc = bson.M{"$" + agg.Op: "$" + agg.Field}
+	}
+
+	var id interface{}
+	if agg.Group != "" {
+		id = "$" + agg.Group
+	}
+
+	project := bson.M{"_id": 0, agg.Op: "$value"}
+	if agg.Group != "" {
+		project["group"] = "$_id"
+	}
+
+	pipeline = append(pipeline,
+		bson.M{"$group": bson.M{"_id": id, "value": acc}},
+		bson.M{"$project": project},
+	)
+
+	if agg.Group != "" {
+		pipeline = append(pipeline, bson.M{"$sort": bson.M{"group": 1}})
+	}
+
+	return pipeline
+}
+
+//==========================================================================================
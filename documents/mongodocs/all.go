@@ -7,11 +7,14 @@ import (
 	"github.com/influx6/coquery/utils"
 )
 
-// All provides a find working for handling find requests.
+// All provides a find working for handling find requests. Requests for more
+// than the MaxRecords of its Limits, including those for all records, are
+// rejected with a *coquery.LimitError, reading no more than one past them.
 type All struct {
 	Events
-	Db    DB
-	Store storage.Store
+	Db     DB
+	Store  storage.Store
+	Limits *coquery.Limits
 }
 
 // Do performs the necessary tasks passed to FindProc
@@ -67,7 +70,7 @@ func (a *All) Do(dataReq interface{}, err error) (interface{}, error) {
 		}, nil
 	}
 
-	db, session, err := a.Db.New(find.RequestID())
+	db, session, err := newSession(req, a.Db, find.RequestID())
 	if err != nil {
 		a.Error(find.RequestID(), "db.New", err, "Completed : New Session")
 		return nil, &MError{Rid: find.RID, Msg: "New Session Failed", IError: err}
@@ -87,6 +90,12 @@ func (a *All) Do(dataReq interface{}, err error) (interface{}, error) {
 		find.Amount = total
 	}
 
+	// Retrieve a record past the limit, so the request is rejected for
+	// exceeding it rather than loading every record.
+	if a.Limits != nil && a.Limits.MaxRecords > 0 && find.Amount > a.Limits.MaxRecords {
+		find.Amount = a.Limits.MaxRecords + 1
+	}
+
 	if find.Amount+find.Skip <= a.Store.Length() {
 		records := a.Store.Select(find.Amount, find.Skip)
 
@@ -94,6 +103,11 @@ func (a *All) Do(dataReq interface{}, err error) (interface{}, error) {
 			res = append(res, data.Parameter(recs))
 		}
 
+		if lerr := a.Limits.CheckResponse(find.RID, &coquery.Response{Req: find, Data: res}); lerr != nil {
+			a.Error(find.RequestID(), "All.Do", lerr, "Completed")
+			return nil, lerr
+		}
+
 		a.Log(find.RequestID(), "All.Do", "Info : Store : Record Found")
 
 		a.Log(find.RequestID(), "All.Do", "Completed")
@@ -110,6 +124,11 @@ func (a *All) Do(dataReq interface{}, err error) (interface{}, error) {
 		return nil, &MError{Rid: find.RID, Msg: "All Failed", IError: err}
 	}
 
+	if lerr := a.Limits.CheckResponse(find.RID, &coquery.Response{Req: find, Data: res}); lerr != nil {
+		a.Error(find.RequestID(), "All.Do", lerr, "Completed")
+		return nil, lerr
+	}
+
 	a.Log(find.RequestID(), "All.Do", "Info : Response : %s", utils.Query.Query(res))
 
 	for _, record := range res {
@@ -0,0 +1,150 @@
+package mongodocs
+
+import (
+	"fmt"
+
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/storage"
+	"github.com/influx6/coquery/utils"
+	"github.com/pborman/uuid"
+	"gopkg.in/mgo.v2"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//==========================================================================================
+
+// contains the record key types which can be generated for new records.
+const (
+	KeyUUID     = "uuid"
+	KeyObjectID = "objectid"
+)
+
+// Create provides a record creator for the mongo storage system, inserting new
+// records with keys generated according to its KeyType. When KeyType is empty
+// the records must provide their own keys.
+type Create struct {
+	Events
+	Db      DB
+	Store   storage.Store
+	KeyType string
+}
+
+// Do performs the operations for inserting new records into the db and adding
+// them into the coquery store.
+func (c *Create) Do(dataReq interface{}, err error) (interface{}, error) {
+	c.Log("mongodocs", "Create.Do", "Started : %s", utils.Query.Query(dataReq))
+
+	if err != nil {
+		c.Error("mongodocs", "Create.Do", err, "Completed")
+		return nil, err
+	}
+
+	req, ok := dataReq.(*coquery.Request)
+	if !ok {
+		c.Error("mongodocs", "Create.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	cr, ok := req.R.(*coquery.Create)
+	if !ok {
+		c.Error("mongodocs", "Create.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	key := c.Store.Key()
+
+	var records data.Parameters
+	var docs []interface{}
+
+	for _, record := range cr.Records {
+		rec := data.Parameter(bsonMap(record))
+
+		if err := c.newKey(rec); err != nil {
+			c.Error(cr.RequestID(), "Create.Do", err, "Completed")
+			return nil, &MError{
+				Rid:    cr.RequestID(),
+				Msg:    fmt.Sprintf("Create Failed: Record : %s", utils.Query.Query(record)),
+				IError: err,
+			}
+		}
+
+		// New records start at the first version.
+		rec[storage.VersionKey] = int64(1)
+
+		records = append(records, rec)
+		docs = append(docs, rec)
+	}
+
+	db, session, err := newSession(req, c.Db, cr.RequestID())
+	if err != nil {
+		c.Error(cr.RequestID(), "db.New", err, "Completed : New Session")
+		return nil, &MError{Rid: cr.RequestID(), Msg: "New Session Failed", IError: err}
+	}
+
+	defer session.Close()
+
+	c.Log(cr.RequestID(), "DBAction", "db.%s.insert(%s)", cr.Doc, utils.Query.Query(records))
+
+	if err := db.C(cr.Doc).Insert(docs...); err != nil {
+		msg := "Create DB Insert Failed"
+		if mgo.IsDup(err) {
+			msg = fmt.Sprintf("Create DB Insert Failed: Record With Key[%s] Exists", key)
+		}
+
+		c.Error(cr.RequestID(), "DBAction", err, "Completed")
+		return nil, &MError{Rid: cr.RequestID(), Msg: msg, IError: err}
+	}
+
+	var keys []interface{}
+	for _, record := range records {
+		keys = append(keys, record[key])
+	}
+
+	cr.Journal.Record(dropUndo(c.Events, c.Db, c.Store, cr.RequestID(), cr.Doc, keys))
+
+	for _, record := range records {
+		if err := c.Store.Add((map[string]interface{})(record)); err != nil {
+			c.Error(cr.RequestID(), "Create.Do", err, "Info : Store.Add")
+		}
+	}
+
+	c.Log(cr.RequestID(), "Create.Do", "Completed")
+	c.Log("mongodocs", "Create.Do", "Completed")
+
+	return &coquery.Response{
+		Req:  cr,
+		Data: records,
+	}, nil
+}
+
+// newKey sets the generated key of the new record, records can only provide
+// their own key when no key type is set.
+func (c *Create) newKey(rec data.Parameter) error {
+	key := c.Store.Key()
+
+	if c.KeyType == "" {
+		if !rec.Has(key) {
+			return fmt.Errorf("New Record Lacks Wanted Key: %s", key)
+		}
+
+		return nil
+	}
+
+	if rec.Has(key) {
+		return fmt.Errorf("New Record Key[%s] Is Generated And Can Not Be Set", key)
+	}
+
+	switch c.KeyType {
+	case KeyUUID:
+		rec[key] = uuid.New()
+	case KeyObjectID:
+		rec[key] = bson.NewObjectId()
+	default:
+		return fmt.Errorf("Unknown Record Key Type[%s]", c.KeyType)
+	}
+
+	return nil
+}
+
+//==========================================================================================
@@ -57,6 +57,39 @@ type DB interface {
 	New(context interface{}) (*mgo.Database, *mgo.Session, error)
 }
 
+// newSession returns a new session of the db for the giving request, failing
+// if the request's context is done. The session's socket timeout is limited
+// to the context's deadline, aborting db operations running past it.
+func newSession(req *coquery.Request, dbs DB, rid string) (*mgo.Database, *mgo.Session, error) {
+	ctx := req.Context()
+
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	db, session, err := dbs.New(rid)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		session.SetSocketTimeout(deadline.Sub(time.Now()))
+	}
+
+	return db, session, nil
+}
+
+// bound limits the query to a record past the MaxRecords of the limits, if
+// any, so a query matching more records is rejected by the limits rather than
+// loading all of them. It returns the limit set, which is zero for none.
+func bound(qry *mgo.Query, limits *coquery.Limits) (*mgo.Query, int) {
+	if limits == nil || limits.MaxRecords <= 0 {
+		return qry, 0
+	}
+
+	return qry.Limit(limits.MaxRecords + 1), limits.MaxRecords + 1
+}
+
 //==============================================================================
 
 // DocumentConfig provides a central configuration to initialize the documents
@@ -80,6 +113,18 @@ type DocumentConfig struct {
 
 	// QueryDoc to set an alternative db.document name for the queries to use.
 	QueryDoc string
+
+	// KeyType sets the type of key generated for created records, either
+	// KeyUUID or KeyObjectID. When empty created records must provide their
+	// own key.
+	KeyType string
+
+	// Schema sets the schema created and mutated records are validated
+	// against, it is registered along with the document by DocumentWith.
+	Schema *storage.Schema
+
+	// Limits sets the cost limits of the queries made against the document.
+	Limits *coquery.Limits
 }
 
 // Document provides a Mongo coquery.DocumentOS which provides the internal
@@ -107,6 +152,7 @@ func New(config DocumentConfig) *Document {
 		EventLog: config.Events,
 		Store:    config.Store,
 		Doc:      config.QueryDoc,
+		Limits:   config.Limits,
 	}
 
 	dc := Document{
@@ -133,6 +179,36 @@ func New(config DocumentConfig) *Document {
 		Events: config.Events,
 		Db:     db,
 		Store:  config.Store,
+		Limits: config.Limits,
+	}))
+
+	dc.Stream(sumex.New(config.Workers, config.Events, &Where{
+		Events: config.Events,
+		Db:     db,
+		Store:  config.Store,
+		Limits: config.Limits,
+	}))
+
+	dc.Stream(sumex.New(config.Workers, config.Events, &Sort{
+		Events: config.Events,
+		Db:     db,
+		Store:  config.Store,
+		Limits: config.Limits,
+	}))
+
+	dc.Stream(sumex.New(config.Workers, config.Events, &Page{
+		Events: config.Events,
+		Db:     db,
+		Store:  config.Store,
+	}))
+
+	dc.Stream(sumex.New(config.Workers, config.Events, &Aggregate{
+		Events: config.Events,
+		Db:     db,
+	}))
+
+	dc.Stream(sumex.New(config.Workers, config.Events, &crossdocs.Join{
+		Events: config.Events,
 	}))
 
 	dc.Stream(sumex.New(config.Workers, config.Events, &crossdocs.Collect{
@@ -146,10 +222,24 @@ func New(config DocumentConfig) *Document {
 		Store:  config.Store,
 	}))
 
+	dc.Stream(sumex.New(config.Workers, config.Events, &Create{
+		Events:  config.Events,
+		Db:      db,
+		Store:   config.Store,
+		KeyType: config.KeyType,
+	}))
+
+	dc.Stream(sumex.New(config.Workers, config.Events, &Remove{
+		Events: config.Events,
+		Db:     db,
+		Store:  config.Store,
+	}))
+
 	dc.Stream(sumex.New(config.Workers, config.Events, &All{
 		Events: config.Events,
 		Db:     db,
 		Store:  config.Store,
+		Limits: config.Limits,
 	}))
 
 	return &dc
@@ -165,4 +255,9 @@ func (d *Document) Queries() coquery.QueryProcessor {
 	return d.query
 }
 
+// Schema returns the schema of the document's records, if any.
+func (d *Document) Schema() *storage.Schema {
+	return d.DocumentConfig.Schema
+}
+
 //==============================================================================
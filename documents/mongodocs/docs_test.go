@@ -3,6 +3,7 @@ package mongodocs_test
 import (
 	"errors"
 	"fmt"
+	"net"
 	"testing"
 	"time"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/influx6/coquery"
 	"github.com/influx6/coquery/db/mongo"
 	"github.com/influx6/coquery/documents/mongodocs"
+	"github.com/influx6/coquery/storage"
 	"github.com/influx6/coquery/streams"
 	"github.com/influx6/faux/sumex"
 )
@@ -18,6 +20,31 @@ import (
 
 var context = "testing"
 
+// mongoHost defines the mongodb instance the tests run against, they are
+// skipped when it is not reachable.
+const mongoHost = "127.0.0.1:27017"
+
+// newMongo returns the connection manager of the mongodb instance, skipping
+// the test when it is not reachable.
+func newMongo(t *testing.T, lg mongo.EventLog) *mongo.Mongnod {
+	conn, err := net.DialTimeout("tcp", mongoHost, time.Second)
+	if err != nil {
+		t.Skipf("mongodb is not reachable at %s: %s", mongoHost, err)
+	}
+	conn.Close()
+
+	return &mongo.Mongnod{
+		EventLog: lg,
+		Config: mongo.Config{
+			Host:     mongoHost,
+			AuthDB:   "outcast",
+			DB:       "outcast",
+			User:     "box",
+			Password: "box",
+		},
+	}
+}
+
 //==============================================================================
 
 // logg provides a concrete implementation of a logger.
@@ -39,60 +66,43 @@ func (l *logg) Error(context interface{}, name string, err error, message string
 
 //==============================================================================
 
-// TestFindProc validates the operation provided by the FindProc operator.
+// TestFindProc validates the operation provided by the Find operator.
 func TestFindProc(t *testing.T) {
-	t.Logf("Given the need to retrieve a record using FindProc operator")
+	t.Logf("Given the need to retrieve a record using Find operator")
 	{
 
 		t.Logf("\tWhen giving a mongo provider")
 		{
 
-			var lg coquery.EventLog
-			lg = &logg{}
-
-			mor := mongo.Mongnod{
-				Events: lg,
-				Config: mongo.Config{
-					Host:     "127.0.0.1:27017",
-					AuthDB:   "outcast",
-					DB:       "outcast",
-					User:     "box",
-					Password: "box",
-				},
-			}
+			lg := &logg{}
+			mo := newMongo(t, lg)
 
-			if merr != nil {
-				t.Fatalf("\t%s\tShould have successfully connected to mongodb instance: %q", tests.Failed, merr)
+			if _, _, err := mo.New(context); err != nil {
+				t.Fatalf("\t%s\tShould have successfully connected to mongodb instance: %q", tests.Failed, err)
 			}
 			t.Logf("\t%s\tShould have successfully connected to mongodb instance.", tests.Success)
 
-			defer mo.Shutdown(context)
-
-			request := coquery.Find{
+			request := &coquery.Request{R: &coquery.Find{
 				Doc:   "marine_metric_history",
 				RID:   "43D3UFZ6",
 				Key:   "station_id",
 				Value: "GMZ657",
-			}
-
-			finder := &mongodocs.FindProc{
-				EventLog: lg,
-				Mongo:    mo,
-			}
+			}}
 
-			if finder.Name() != "find" {
-				t.Fatalf("\t%s\tShould have the giving name %q for this operator: %q", tests.Failed, "find", finder.Name())
+			finder := &mongodocs.Find{
+				Events: lg,
+				Db:     mo,
+				Store:  storage.New("station_id"),
 			}
-			t.Logf("\t%s\tShould have the giving name %q for this operator: %q", tests.Success, "find", finder.Name())
 
 			err1 := errors.New("Invalid Operation")
 
-			if _, err := finder.Do(&request, err1); err != err1 {
+			if _, err := finder.Do(request, err1); err != err1 {
 				t.Fatalf("\t%s\tShould have returned the error passed as second argument: %q", tests.Failed, err1)
 			}
 			t.Logf("\t%s\tShould have returned the error passed as second argument,", tests.Success)
 
-			_, err := finder.Do(&request, nil)
+			_, err := finder.Do(request, nil)
 			if err != nil {
 				t.Fatalf("\t%s\tShould have retrived record without error: %q", tests.Failed, err)
 			}
@@ -104,32 +114,23 @@ func TestFindProc(t *testing.T) {
 
 //==============================================================================
 
-// TestFindProcStream validates the operation provided by the FindProc operator
+// TestFindProcStream validates the operation provided by the Find operator
 // when using the streaming interface.
 func TestFindProcStream(t *testing.T) {
-	t.Logf("Given the need to retrieve a record using FindProc operator")
+	t.Logf("Given the need to retrieve a record using Find operator")
 	{
 
 		t.Logf("\tWhen giving a mongo provider")
 		{
 
 			lg := &logg{}
+			mo := newMongo(t, lg)
 
-			mo, merr := mongo.New(lg, mongo.Config{
-				Host:     "127.0.0.1:27017",
-				AuthDB:   "outcast",
-				DB:       "outcast",
-				User:     "box",
-				Password: "box",
-			})
-
-			if merr != nil {
-				t.Fatalf("\t%s\tShould have successfully connected to mongodb instance: %q", tests.Failed, merr)
+			if _, _, err := mo.New(context); err != nil {
+				t.Fatalf("\t%s\tShould have successfully connected to mongodb instance: %q", tests.Failed, err)
 			}
 			t.Logf("\t%s\tShould have successfully connected to mongodb instance.", tests.Success)
 
-			defer mo.Shutdown(context)
-
 			request := &coquery.Find{
 				Doc:   "marine_metric_history",
 				RID:   "43D3UFZ6",
@@ -137,15 +138,15 @@ func TestFindProcStream(t *testing.T) {
 				Value: "GMZ657",
 			}
 
-			finder := &mongodocs.FindProc{
-				EventLog: lg,
-				Mongo:    mo,
-				Query:    mo,
+			finder := &mongodocs.Find{
+				Events: lg,
+				Db:     mo,
+				Store:  storage.New("station_id"),
 			}
 
 			findStream := sumex.New(3, lg, finder)
 
-			findStream.Inject(request)
+			findStream.Inject(&coquery.Request{R: request})
 
 			res, err := streams.ReadResponse(lg, context, 1*time.Minute, request.RID, findStream)
 			if err != nil {
@@ -153,7 +154,7 @@ func TestFindProcStream(t *testing.T) {
 			}
 			t.Logf("\t%s\tShould have returned the error passed as second argument,", tests.Success)
 
-			if res.RequestID() != request.RequestID() {
+			if res.Req.RequestID() != request.RequestID() {
 				t.Fatalf("\t%s\tShould have recieved a reply for request ID %s", tests.Failed, request.RID)
 			}
 			t.Logf("\t%s\tShould have recieved a reply for request ID %s", tests.Success, request.RID)
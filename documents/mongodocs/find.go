@@ -10,11 +10,14 @@ import (
 
 //==========================================================================================
 
-// Find provides a find working for handling find requests.
+// Find provides a find working for handling find requests. Finds matching
+// more than the MaxRecords of its Limits within the db are rejected with a
+// *coquery.LimitError, reading no more than one past them.
 type Find struct {
 	Events
-	Db    DB
-	Store storage.Store
+	Db     DB
+	Store  storage.Store
+	Limits *coquery.Limits
 }
 
 // Do performs the necessary tasks passed to FindProc
@@ -38,11 +41,7 @@ func (f *Find) Do(dataReq interface{}, err error) (interface{}, error) {
 		return nil, coquery.ErrInvalidRequestType
 	}
 
-	var val interface{}
-
-	if utils.IsDigits(find.Value) {
-		val, _ = utils.ParseInt(find.Value)
-	}
+	val := bsonValue(find.Value)
 
 	var res data.Parameters
 	found := true
@@ -67,7 +66,7 @@ func (f *Find) Do(dataReq interface{}, err error) (interface{}, error) {
 		}, nil
 	}
 
-	db, session, err := f.Db.New(find.RequestID())
+	db, session, err := newSession(req, f.Db, find.RequestID())
 	if err != nil {
 		f.Error(find.RequestID(), "db.New", err, "Completed : New Session")
 		return nil, &MError{Rid: find.RID, Msg: "New Session Failed", IError: err}
@@ -76,13 +75,20 @@ func (f *Find) Do(dataReq interface{}, err error) (interface{}, error) {
 	defer session.Close()
 
 	q := bson.M{find.Key: val}
-	f.Log(find.RequestID(), find.RequestID(), "DBAction : db.%s.find(%s)", find.Doc, utils.Query.Query(q))
 
-	if err := db.C(find.Doc).Find(q).All(&res); err != nil {
+	qry, limit := bound(db.C(find.Doc).Find(q), f.Limits)
+	f.Log(find.RequestID(), find.RequestID(), "DBAction : db.%s.find(%s).limit(%d)", find.Doc, utils.Query.Query(q), limit)
+
+	if err := qry.All(&res); err != nil {
 		f.Error(find.RequestID(), "DBAction", err, "Completed")
 		return nil, &MError{Rid: find.RID, Msg: "FindProc Failed", IError: err}
 	}
 
+	if lerr := f.Limits.CheckResponse(find.RID, &coquery.Response{Req: find, Data: res}); lerr != nil {
+		f.Error(find.RequestID(), "Find.Do", lerr, "Completed")
+		return nil, lerr
+	}
+
 	f.Log(find.RequestID(), "Find.Do", "Info : Response : %s", utils.Query.Query(res))
 
 	for _, record := range res {
@@ -0,0 +1,97 @@
+package mongodocs
+
+import (
+	"fmt"
+
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/storage"
+	"github.com/influx6/coquery/utils"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//==========================================================================================
+
+// restoreUndo returns the undo function which puts the giving records back
+// into the db and store as they were before being changed or removed. As a
+// compensating write, it replaces any change made to the records since. The
+// records are untainted, so the undone changes are never reported.
+func restoreUndo(events Events, dbs DB, store storage.Store, rid string, doc string, records []map[string]interface{}) coquery.Undo {
+	return func() error {
+		events.Log(rid, "Undo", "Started : Restore %d Records", len(records))
+
+		db, session, err := dbs.New(rid)
+		if err != nil {
+			events.Error(rid, "db.New", err, "Completed : New Session")
+			return &MError{Rid: rid, Msg: "New Session Failed", IError: err}
+		}
+
+		defer session.Close()
+
+		for _, rec := range records {
+			qry := bson.M{store.Key(): bsonValue(rec[store.Key()])}
+
+			events.Log(rid, "DBAction", "db.%s.upsert(%s,%s)", doc, utils.Query.Query(qry), utils.Query.Query(rec))
+
+			if _, err := db.C(doc).Upsert(qry, bsonMap(rec)); err != nil {
+				events.Error(rid, "DBAction", err, "Completed")
+				return &MError{
+					Rid:    rid,
+					Msg:    fmt.Sprintf("Undo Failed: Record : %s", utils.Query.Query(rec)),
+					IError: err,
+				}
+			}
+
+			if err := store.Replace(rec); err != nil {
+				events.Error(rid, "Undo", err, "Info : Store.Replace")
+			}
+
+			store.Untaint(fmt.Sprintf("%+v", rec[store.Key()]))
+		}
+
+		events.Log(rid, "Undo", "Completed")
+		return nil
+	}
+}
+
+// dropUndo returns the undo function which removes the records with the
+// giving keys from the db and store, reversing their creation without it
+// being reported as a removal.
+func dropUndo(events Events, dbs DB, store storage.Store, rid string, doc string, keys []interface{}) coquery.Undo {
+	return func() error {
+		events.Log(rid, "Undo", "Started : Drop %d Records", len(keys))
+
+		db, session, err := dbs.New(rid)
+		if err != nil {
+			events.Error(rid, "db.New", err, "Completed : New Session")
+			return &MError{Rid: rid, Msg: "New Session Failed", IError: err}
+		}
+
+		defer session.Close()
+
+		var ids []interface{}
+		for _, key := range keys {
+			ids = append(ids, bsonValue(key))
+		}
+
+		qry := bson.M{store.Key(): bson.M{"$in": ids}}
+
+		events.Log(rid, "DBAction", "db.%s.remove(%s)", doc, utils.Query.Query(qry))
+
+		if _, err := db.C(doc).RemoveAll(qry); err != nil {
+			events.Error(rid, "DBAction", err, "Completed")
+			return &MError{Rid: rid, Msg: "Undo Failed", IError: err}
+		}
+
+		for _, key := range keys {
+			skey := fmt.Sprintf("%+v", key)
+
+			store.Delete(skey)
+			store.Untaint(skey)
+		}
+
+		events.Log(rid, "Undo", "Completed")
+		return nil
+	}
+}
+
+//==========================================================================================
@@ -1,12 +1,14 @@
 package mongodocs
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/influx6/coquery"
 	"github.com/influx6/coquery/data"
 	"github.com/influx6/coquery/storage"
 	"github.com/influx6/coquery/utils"
+	"gopkg.in/mgo.v2"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -40,7 +42,7 @@ func (m *Mutate) Do(dataReq interface{}, err error) (interface{}, error) {
 	// 	return nil, err
 	// }
 
-	db, session, err := m.Db.New(mux.RequestID())
+	db, session, err := newSession(req, m.Db, mux.RequestID())
 	if err != nil {
 		m.Error(mux.RequestID(), "db.New", err, "Completed : New Session")
 		return nil, &MError{Rid: mux.RequestID(), Msg: "New Session Failed", IError: err}
@@ -48,47 +50,69 @@ func (m *Mutate) Do(dataReq interface{}, err error) (interface{}, error) {
 
 	defer session.Close()
 
-	param := (map[string]interface{})(mux.Parameter)
+	if mux.Update != nil {
+		return m.update(db, req, mux)
+	}
+
+	// Versions are maintained by the server, never taken from the caller.
+	param := bsonMap(mux.Parameter)
+	delete(param, storage.VersionKey)
+
 	new := true
 
 	// If there were previous records then update those and save them.
-	if records := req.LastResponse.Data; len(records) > 0 {
+	if req.LastResponse != nil && len(req.LastResponse.Data) > 0 {
+		records := req.LastResponse.Data
+
 		new = false
 
-		// Mutate all provided records and attempt to store back into cache store.
+		// Check the record versions before touching any record, so a stale
+		// mutation leaves them all as they were.
+		if err := m.checkVersions(mux, records); err != nil {
+			return nil, err
+		}
+
+		// The records updated so far are put back if a later one fails, so a
+		// merge conflicting on any record changes none of them.
+		var undo coquery.Journal
+
 		for index, rec := range records {
 			mrec := (map[string]interface{})(rec)
+			version := storage.Version(mrec)
+			prev := storage.CopyMap(mrec)
+
 			storage.MergeMaps(mrec, param)
+			mrec[storage.VersionKey] = version + 1
+
+			val := mrec[m.Store.Key()]
+
+			// The record is only replaced if it is still at the version it was
+			// read at, else another mutation changed it since and the merge
+			// would lose that change.
+			qry := bson.M{m.Store.Key(): val, storage.VersionKey: versionQuery(version)}
+
+			m.Log(mux.RequestID(), "DBAction", "db.%s.update(%s,%s)", mux.Doc, utils.Query.Query(qry), utils.Query.Query(mrec))
+
+			if err := db.C(mux.Doc).Update(qry, mrec); err != nil {
+				return nil, m.abort(mux, &undo, m.failed(db, mux, val, &version, mrec, err))
+			}
+
+			undo.Record(restoreUndo(m.Events, m.Db, m.Store, mux.RequestID(), mux.Doc, []map[string]interface{}{prev}))
 
 			// If the error failed, then stop and return as failure.
 			if err := m.Store.Add(mrec); err != nil {
 				m.Error(mux.RequestID(), "Mutate.Do", err, "Completed")
-				return nil, &MError{
+				return nil, m.abort(mux, &undo, &MError{
 					Rid:    mux.RequestID(),
 					Msg:    fmt.Sprintf("Mutate Failed: Record : %s", utils.Query.Query(rec)),
 					IError: err,
-				}
+				})
 			}
 
 			records[index] = mrec
 		}
 
-		// If we get here, then everything updated fine, lets push this into the db.
-		for _, newRec := range records {
-			val := newRec[m.Store.Key()]
-			qry := bson.M{m.Store.Key(): val}
-
-			m.Log(mux.RequestID(), "DBAction", "db.%s.upsert(%s,%s)", mux.Doc, utils.Query.Query(qry), utils.Query.Query(newRec))
-
-			if _, err := db.C(mux.Doc).Upsert(qry, newRec); err != nil {
-				m.Error(mux.RequestID(), "DBAction", err, "Completed")
-				return nil, &MError{
-					Rid:    mux.RequestID(),
-					Msg:    fmt.Sprintf("Mutate DB Update: Record : %s", utils.Query.Query(newRec)),
-					IError: err,
-				}
-			}
-		}
+		mux.Journal.Record(undo.Rollback)
 
 		m.Log(mux.RequestID(), "Mutate.Do", "Completed")
 		return &coquery.Response{
@@ -97,6 +121,8 @@ func (m *Mutate) Do(dataReq interface{}, err error) (interface{}, error) {
 		}, nil
 	}
 
+	reply := data.Parameters{mux.Parameter}
+
 	if new {
 		val, ok := param[m.Store.Key()]
 		if !ok {
@@ -109,9 +135,43 @@ func (m *Mutate) Do(dataReq interface{}, err error) (interface{}, error) {
 
 		qry := bson.M{m.Store.Key(): val}
 
-		m.Log(mux.RequestID(), "DBAction", "db.%s.upsert(%s,%s)", mux.Doc, utils.Query.Query(qry), utils.Query.Query(param))
+		// Within an atomic batch, keep the record being replaced so it can be
+		// put back, or note that there was none to drop the new one.
+		var undo coquery.Undo
+
+		if mux.Journal != nil {
+			var prior bson.M
+
+			switch err := db.C(mux.Doc).Find(qry).One(&prior); err {
+			case nil:
+				undo = restoreUndo(m.Events, m.Db, m.Store, mux.RequestID(), mux.Doc, []map[string]interface{}{storage.BSONtoMap(prior)})
+			case mgo.ErrNotFound:
+				undo = dropUndo(m.Events, m.Db, m.Store, mux.RequestID(), mux.Doc, []interface{}{val})
+			default:
+				m.Error(mux.RequestID(), "DBAction", err, "Completed")
+				return nil, &MError{Rid: mux.RequestID(), Msg: "Mutate DB Find Failed", IError: err}
+			}
+		}
+
+		// The fields are set on the stored record, or a new one, while the db
+		// moves it to its next version, starting new records at version 1.
+		set := make(bson.M)
+		for field, value := range param {
+			if field != m.Store.Key() {
+				set[field] = value
+			}
+		}
 
-		if _, err := db.C(mux.Doc).Upsert(qry, param); err != nil {
+		change := bson.M{storage.OpInc: bson.M{storage.VersionKey: 1}}
+		if len(set) > 0 {
+			change[storage.OpSet] = set
+		}
+
+		m.Log(mux.RequestID(), "DBAction", "db.%s.upsert(%s,%s)", mux.Doc, utils.Query.Query(qry), utils.Query.Query(change))
+
+		var stored bson.M
+
+		if _, err := db.C(mux.Doc).Find(qry).Apply(mgo.Change{Update: change, Upsert: true, ReturnNew: true}, &stored); err != nil {
 			m.Error(mux.RequestID(), "DBAction", err, "Completed")
 			return nil, &MError{
 				Rid:    mux.RequestID(),
@@ -120,6 +180,11 @@ func (m *Mutate) Do(dataReq interface{}, err error) (interface{}, error) {
 			}
 		}
 
+		if undo != nil {
+			mux.Journal.Record(undo)
+		}
+
+		reply = data.Parameters{storage.BSONtoMap(stored)}
 	}
 
 	m.Log(mux.RequestID(), "Mutate.Do", "Completed")
@@ -127,8 +192,195 @@ func (m *Mutate) Do(dataReq interface{}, err error) (interface{}, error) {
 
 	return &coquery.Response{
 		Req:  mux,
-		Data: data.Parameters{mux.Parameter},
+		Data: reply,
 	}, nil
 }
 
+// update applies the update operators of the mutate request to the selected
+// records, sending the native mongo form of the operators to the db and then
+// applying them to the cached copies of the records.
+func (m *Mutate) update(db *mgo.Database, req *coquery.Request, mux *coquery.Mutate) (interface{}, error) {
+	if req.LastResponse == nil || len(req.LastResponse.Data) == 0 {
+		err := errors.New("Update operators require selected records")
+		m.Error(mux.RequestID(), "Mutate.Do", err, "Completed")
+		return nil, &MError{Rid: mux.RequestID(), Msg: "Mutate Failed: No Records", IError: err}
+	}
+
+	if err := m.checkVersions(mux, req.LastResponse.Data); err != nil {
+		return nil, err
+	}
+
+	// Every update moves the records to their next version.
+	upd := *mux.Update
+	upd.Inc = map[string]interface{}{storage.VersionKey: 1}
+
+	for field, by := range mux.Update.Inc {
+		upd.Inc[field] = by
+	}
+
+	change := mongoUpdate(&upd)
+
+	// Validate the update against every record before sending it off.
+	for _, rec := range req.LastResponse.Data {
+		if err := upd.Apply(storage.CopyMap(rec)); err != nil {
+			m.Error(mux.RequestID(), "Mutate.Do", err, "Completed")
+			return nil, &MError{
+				Rid:    mux.RequestID(),
+				Msg:    fmt.Sprintf("Mutate Failed: Record : %s", utils.Query.Query(rec)),
+				IError: err,
+			}
+		}
+	}
+
+	// The records updated so far are put back if a later one fails.
+	var undo coquery.Journal
+	var records data.Parameters
+
+	for _, rec := range req.LastResponse.Data {
+		val := rec[m.Store.Key()]
+
+		qry := bson.M{m.Store.Key(): val}
+
+		var expected *int64
+		if mux.IfVersion != nil {
+			expected = mux.IfVersion
+			qry[storage.VersionKey] = versionQuery(*mux.IfVersion)
+		}
+
+		m.Log(mux.RequestID(), "DBAction", "db.%s.update(%s,%s)", mux.Doc, utils.Query.Query(qry), utils.Query.Query(change))
+
+		prev := storage.CopyMap(rec)
+
+		if err := db.C(mux.Doc).Update(qry, change); err != nil {
+			return nil, m.abort(mux, &undo, m.failed(db, mux, val, expected, rec, err))
+		}
+
+		undo.Record(restoreUndo(m.Events, m.Db, m.Store, mux.RequestID(), mux.Doc, []map[string]interface{}{prev}))
+
+		newRec, err := m.Store.Update((map[string]interface{})(rec), &upd)
+		if err != nil {
+			m.Error(mux.RequestID(), "Mutate.Do", err, "Completed")
+			return nil, m.abort(mux, &undo, &MError{
+				Rid:    mux.RequestID(),
+				Msg:    fmt.Sprintf("Mutate Failed: Record : %s", utils.Query.Query(rec)),
+				IError: err,
+			})
+		}
+
+		records = append(records, newRec)
+	}
+
+	mux.Journal.Record(undo.Rollback)
+
+	m.Log(mux.RequestID(), "Mutate.Do", "Completed")
+	m.Log("mongodocs", "Mutate.Do", "Completed")
+
+	return &coquery.Response{
+		Req:  mux,
+		Data: records,
+	}, nil
+}
+
+// checkVersions returns a *coquery.ConflictError for the first record which
+// is not at the version expected by the mutate request.
+func (m *Mutate) checkVersions(mux *coquery.Mutate, records data.Parameters) error {
+	if mux.IfVersion == nil {
+		return nil
+	}
+
+	for _, rec := range records {
+		if version := storage.Version(rec); version != *mux.IfVersion {
+			err := &coquery.ConflictError{
+				Rid:     mux.RequestID(),
+				Msg:     fmt.Sprintf("Version Conflict: Expected Version %d", *mux.IfVersion),
+				Key:     rec[m.Store.Key()],
+				Version: version,
+			}
+
+			m.Error(mux.RequestID(), "Mutate.Do", err, "Completed")
+			return err
+		}
+	}
+
+	return nil
+}
+
+// abort puts back the records updated by the mutate request before it failed
+// with the giving error, which it returns.
+func (m *Mutate) abort(mux *coquery.Mutate, undo *coquery.Journal, err error) error {
+	if rerr := undo.Rollback(); rerr != nil {
+		m.Error(mux.RequestID(), "Mutate.Do", rerr, "Info : Rollback Failed")
+	}
+
+	return err
+}
+
+// failed returns the error for a failed db update of the record with the
+// giving key. A record missing from an update matching the expected version
+// was changed by another mutation, so a *coquery.ConflictError holding its
+// current version is returned.
+func (m *Mutate) failed(db *mgo.Database, mux *coquery.Mutate, key interface{}, expected *int64, rec map[string]interface{}, err error) error {
+	m.Error(mux.RequestID(), "DBAction", err, "Completed")
+
+	if err == mgo.ErrNotFound && expected != nil {
+		var current bson.M
+		db.C(mux.Doc).Find(bson.M{m.Store.Key(): key}).One(&current)
+
+		return &coquery.ConflictError{
+			Rid:     mux.RequestID(),
+			Msg:     fmt.Sprintf("Version Conflict: Expected Version %d", *expected),
+			Key:     key,
+			Version: storage.Version(current),
+		}
+	}
+
+	return &MError{
+		Rid:    mux.RequestID(),
+		Msg:    fmt.Sprintf("Mutate DB Update: Record : %s", utils.Query.Query(rec)),
+		IError: err,
+	}
+}
+
+// versionQuery returns the query value matching records at the giving
+// version, records lacking a version are at version 0.
+func versionQuery(version int64) interface{} {
+	if version == 0 {
+		return bson.M{"$in": []interface{}{nil, 0}}
+	}
+
+	return version
+}
+
+// mongoUpdate returns the mongo update document for the giving operators.
+func mongoUpdate(upd *storage.Update) bson.M {
+	change := make(bson.M)
+
+	if len(upd.Set) > 0 {
+		change[storage.OpSet] = bsonMap(upd.Set)
+	}
+
+	if len(upd.Inc) > 0 {
+		change[storage.OpInc] = bsonMap(upd.Inc)
+	}
+
+	if len(upd.Push) > 0 {
+		change[storage.OpPush] = bsonMap(upd.Push)
+	}
+
+	if len(upd.Pull) > 0 {
+		change[storage.OpPull] = bsonMap(upd.Pull)
+	}
+
+	if len(upd.Unset) > 0 {
+		unset := make(bson.M)
+		for _, field := range upd.Unset {
+			unset[field] = ""
+		}
+
+		change[storage.OpUnset] = unset
+	}
+
+	return change
+}
+
 //==========================================================================================
@@ -0,0 +1,154 @@
+package mongodocs_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/documents/mongodocs"
+	"github.com/influx6/coquery/storage"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//==============================================================================
+
+// TestConcurrentMerges validates that only one of two merges of a record read
+// at the same version is stored, the other failing with a conflict.
+func TestConcurrentMerges(t *testing.T) {
+	mo := newMongo(t, &logg{})
+
+	t.Logf("Given the need to merge a record from two requests at once")
+	{
+		db, session, err := mo.New(context)
+		if err != nil {
+			t.Fatalf("\t%s\tShould have connected to mongodb: %s", tests.Failed, err)
+		}
+		defer session.Close()
+
+		col := db.C("coquery_merges")
+		col.DropCollection()
+		defer col.DropCollection()
+
+		record := map[string]interface{}{"id": "m1", "name": "alex", storage.VersionKey: 1}
+		if err := col.Insert(record); err != nil {
+			t.Fatalf("\t%s\tShould have inserted the record: %s", tests.Failed, err)
+		}
+
+		mutator := &mongodocs.Mutate{Events: &logg{}, Db: mo, Store: storage.New("id")}
+
+		merge := func(name string) error {
+			_, err := mutator.Do(&coquery.Request{
+				R: &coquery.Mutate{
+					Doc:       "coquery_merges",
+					RID:       "43D3UFZ6",
+					Parameter: data.Parameter{"name": name, storage.VersionKey: 40},
+				},
+				LastResponse: &coquery.Response{
+					Data: data.Parameters{storage.CopyMap(record)},
+				},
+			}, nil)
+
+			return err
+		}
+
+		t.Logf("\tWhen merging the record read at the same version twice")
+		{
+			var wg sync.WaitGroup
+			errs := make(chan error, 2)
+
+			for _, name := range []string{"ruth", "tobi"} {
+				wg.Add(1)
+
+				go func(name string) {
+					defer wg.Done()
+					errs <- merge(name)
+				}(name)
+			}
+
+			wg.Wait()
+			close(errs)
+
+			var conflicts int
+			for err := range errs {
+				if _, ok := err.(*coquery.ConflictError); ok {
+					conflicts++
+				}
+			}
+
+			if conflicts != 1 {
+				t.Fatalf("\t%s\tShould have failed one merge with a conflict: %d", tests.Failed, conflicts)
+			}
+			t.Logf("\t%s\tShould have failed one merge with a conflict", tests.Success)
+
+			var stored bson.M
+			if err := col.Find(bson.M{"id": "m1"}).One(&stored); err != nil || storage.Version(stored) != 2 {
+				t.Fatalf("\t%s\tShould have stored the record at version 2: %v : %+v", tests.Failed, err, stored)
+			}
+			t.Logf("\t%s\tShould have stored the record at version 2", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
+
+// TestPartialMerges validates that a merge failing with a conflict on one of
+// its records leaves the records merged before it as they were.
+func TestPartialMerges(t *testing.T) {
+	mo := newMongo(t, &logg{})
+
+	t.Logf("Given the need to merge several records at once")
+	{
+		db, session, err := mo.New(context)
+		if err != nil {
+			t.Fatalf("\t%s\tShould have connected to mongodb: %s", tests.Failed, err)
+		}
+		defer session.Close()
+
+		col := db.C("coquery_partial_merges")
+		col.DropCollection()
+		defer col.DropCollection()
+
+		first := map[string]interface{}{"id": "p1", "name": "alex", storage.VersionKey: 1}
+		second := map[string]interface{}{"id": "p2", "name": "ruth", storage.VersionKey: 1}
+
+		if err := col.Insert(first, second); err != nil {
+			t.Fatalf("\t%s\tShould have inserted the records: %s", tests.Failed, err)
+		}
+
+		// The second record changes after being read, so merging it conflicts.
+		if err := col.Update(bson.M{"id": "p2"}, bson.M{"$set": bson.M{storage.VersionKey: 2}}); err != nil {
+			t.Fatalf("\t%s\tShould have changed the second record: %s", tests.Failed, err)
+		}
+
+		mutator := &mongodocs.Mutate{Events: &logg{}, Db: mo, Store: storage.New("id")}
+
+		t.Logf("\tWhen merging records where the last conflicts")
+		{
+			_, err := mutator.Do(&coquery.Request{
+				R: &coquery.Mutate{
+					Doc:       "coquery_partial_merges",
+					RID:       "43D3UFZ7",
+					Parameter: data.Parameter{"name": "tobi"},
+				},
+				LastResponse: &coquery.Response{
+					Data: data.Parameters{storage.CopyMap(first), storage.CopyMap(second)},
+				},
+			}, nil)
+
+			if _, ok := err.(*coquery.ConflictError); !ok {
+				t.Fatalf("\t%s\tShould have failed with a conflict: %#v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have failed with a conflict", tests.Success)
+
+			var stored bson.M
+			if err := col.Find(bson.M{"id": "p1"}).One(&stored); err != nil || stored["name"] != "alex" || storage.Version(stored) != 1 {
+				t.Fatalf("\t%s\tShould have left the first record as it was: %v : %+v", tests.Failed, err, stored)
+			}
+			t.Logf("\t%s\tShould have left the first record as it was", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
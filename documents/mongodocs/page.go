@@ -0,0 +1,99 @@
+package mongodocs
+
+import (
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/documents/crossdocs"
+	"github.com/influx6/coquery/storage"
+	"github.com/influx6/coquery/utils"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//==========================================================================================
+
+// Page provides a worker for handling page requests, paging through the
+// records of a previous response in memory or else seeking the db records
+// after the page cursor in sort order.
+type Page struct {
+	Events
+	Db    DB
+	Store storage.Store
+}
+
+// Do performs the necessary tasks passed to Page.
+func (p *Page) Do(dataReq interface{}, err error) (interface{}, error) {
+	p.Log("mongodocs", "Page.Do", "Started : %s", utils.Query.Query(dataReq))
+
+	if err != nil {
+		p.Error("mongodocs", "Page.Do", err, "Completed")
+		return nil, err
+	}
+
+	req, ok := dataReq.(*coquery.Request)
+	if !ok {
+		p.Error("mongodocs", "Page.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	page, ok := req.R.(*coquery.Page)
+	if !ok {
+		p.Error(req.R.RequestID(), "Page.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	var res data.Parameters
+	var more bool
+
+	// If we had a previous response, then we are dealing with a concatenated
+	// operation on the last request, so we page through its records.
+	if req.LastResponse != nil {
+		res, more = crossdocs.PageRecords(req.LastResponse.Data, page)
+	} else {
+		db, session, err := newSession(req, p.Db, page.RequestID())
+		if err != nil {
+			p.Error(page.RequestID(), "db.New", err, "Completed : New Session")
+			return nil, &MError{Rid: page.RID, Msg: "New Session Failed", IError: err}
+		}
+
+		defer session.Close()
+
+		q := bson.M{}
+		if pred := page.Predicate(); pred != nil {
+			q = mongoQuery(pred)
+		}
+
+		keys := mongoSort(page.Sort)
+		p.Log(page.RequestID(), "DBAction", "db.%s.find(%s).sort(%s).limit(%d)", page.Doc, utils.Query.Query(q), keys, page.Size+1)
+
+		// Retrieve an extra record to know if there are more records after
+		// this page.
+		if err := db.C(page.Doc).Find(q).Sort(keys...).Limit(page.Size + 1).All(&res); err != nil {
+			p.Error(page.RequestID(), "DBAction", err, "Completed")
+			return nil, &MError{Rid: page.RID, Msg: "Page Failed", IError: err}
+		}
+
+		if len(res) > page.Size {
+			res = res[:page.Size]
+			more = true
+		}
+
+		for _, record := range res {
+			if err := p.Store.Add((map[string]interface{})(record)); err != nil {
+				p.Error(page.RequestID(), "Page.Do", err, "Info : Store.Add")
+			}
+		}
+	}
+
+	response, err := page.Next(res, more)
+	if err != nil {
+		p.Error(page.RequestID(), "Page.Do", err, "Completed")
+		return nil, &MError{Rid: page.RID, Msg: "Page Cursor Failed", IError: err}
+	}
+
+	p.Log(page.RequestID(), "Page.Do", "Completed")
+	p.Log("mongodocs", "Page.Do", "Completed")
+
+	return response, nil
+}
+
+//==========================================================================================
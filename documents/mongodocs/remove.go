@@ -0,0 +1,116 @@
+package mongodocs
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/storage"
+	"github.com/influx6/coquery/utils"
+	"gopkg.in/mgo.v2/bson"
+)
+
+// Remove provides a record remover for the mongo storage system, deleting the
+// records of a previous response from the db and the coquery store.
+type Remove struct {
+	Events
+	Db    DB
+	Store storage.Store
+}
+
+// Do performs the operations for removing the selected records from the db and
+// marking them as deleted within the coquery store, so their removal is
+// reported within the response deltas.
+func (r *Remove) Do(dataReq interface{}, err error) (interface{}, error) {
+	r.Log("mongodocs", "Remove.Do", "Started : %s", utils.Query.Query(dataReq))
+
+	if err != nil {
+		r.Error("mongodocs", "Remove.Do", err, "Completed")
+		return nil, err
+	}
+
+	req, ok := dataReq.(*coquery.Request)
+	if !ok {
+		r.Error("mongodocs", "Remove.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	rm, ok := req.R.(*coquery.Remove)
+	if !ok {
+		r.Error("mongodocs", "Remove.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	if req.LastResponse == nil {
+		err := errors.New("Remove only works on already selected records")
+		r.Error(rm.RequestID(), "Remove.Do", err, "Completed")
+		return nil, &MError{Rid: rm.RequestID(), Msg: "No Previous Response", IError: err}
+	}
+
+	key := r.Store.Key()
+
+	var ids []interface{}
+	var removed data.Parameters
+
+	for _, record := range req.LastResponse.Data {
+		id, ok := record[key]
+		if !ok {
+			r.Error(rm.RequestID(), "Remove.Do", storage.ErrNoKeyInRecord, "Info : Record : %s", utils.Query.Query(record))
+			continue
+		}
+
+		ids = append(ids, bsonValue(id))
+		removed = append(removed, record)
+	}
+
+	if len(ids) == 0 {
+		r.Log(rm.RequestID(), "Remove.Do", "Completed : No Records")
+		return &coquery.Response{
+			Req:  rm,
+			Data: removed,
+		}, nil
+	}
+
+	db, session, err := newSession(req, r.Db, rm.RequestID())
+	if err != nil {
+		r.Error(rm.RequestID(), "db.New", err, "Completed : New Session")
+		return nil, &MError{Rid: rm.RequestID(), Msg: "New Session Failed", IError: err}
+	}
+
+	defer session.Close()
+
+	q := bson.M{key: bson.M{"$in": ids}}
+	r.Log(rm.RequestID(), "DBAction", "db.%s.remove(%s)", rm.Doc, utils.Query.Query(q))
+
+	if _, err := db.C(rm.Doc).RemoveAll(q); err != nil {
+		r.Error(rm.RequestID(), "DBAction", err, "Completed")
+		return nil, &MError{Rid: rm.RequestID(), Msg: "Remove Failed", IError: err}
+	}
+
+	var prev []map[string]interface{}
+	for _, record := range removed {
+		prev = append(prev, storage.CopyMap(record))
+	}
+
+	rm.Journal.Record(restoreUndo(r.Events, r.Db, r.Store, rm.RequestID(), rm.Doc, prev))
+
+	for _, record := range removed {
+
+		// Records not held by the store are still marked as deleted, so their
+		// removal is reported to clients who have them.
+		if err := r.Store.Delete(fmt.Sprintf("%+v", record[key])); err != nil {
+			if err := r.Store.Remove(record); err != nil {
+				r.Error(rm.RequestID(), "Remove.Do", err, "Info : Store.Remove")
+			}
+		}
+	}
+
+	r.Log(rm.RequestID(), "Remove.Do", "Completed")
+	r.Log("mongodocs", "Remove.Do", "Completed")
+
+	return &coquery.Response{
+		Req:  rm,
+		Data: removed,
+	}, nil
+}
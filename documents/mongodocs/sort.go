@@ -0,0 +1,119 @@
+package mongodocs
+
+import (
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/documents/crossdocs"
+	"github.com/influx6/coquery/storage"
+	"github.com/influx6/coquery/utils"
+)
+
+//==========================================================================================
+
+// Sort provides a worker for handling sort requests, ordering the records of
+// a previous response in memory or else retrieving the db records in order.
+// Sorts of the db records holding more than the MaxRecords of its Limits are
+// rejected with a *coquery.LimitError, reading no more than one past them.
+type Sort struct {
+	Events
+	Db     DB
+	Store  storage.Store
+	Limits *coquery.Limits
+}
+
+// Do performs the necessary tasks passed to Sort.
+func (s *Sort) Do(dataReq interface{}, err error) (interface{}, error) {
+	s.Log("mongodocs", "Sort.Do", "Started : %s", utils.Query.Query(dataReq))
+
+	if err != nil {
+		s.Error("mongodocs", "Sort.Do", err, "Completed")
+		return nil, err
+	}
+
+	req, ok := dataReq.(*coquery.Request)
+	if !ok {
+		s.Error("mongodocs", "Sort.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	sr, ok := req.R.(*coquery.Sort)
+	if !ok {
+		s.Error(req.R.RequestID(), "Sort.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	// If we had a previous response, then we are dealing with a concatenated
+	// operation on the last request, so we order its records.
+	if req.LastResponse != nil {
+		res := crossdocs.SortRecords(req.LastResponse.Data, sr.Fields)
+
+		s.Log(sr.RequestID(), "Sort.Do", "Completed")
+
+		return &coquery.Response{
+			Req:  sr,
+			Data: res,
+		}, nil
+	}
+
+	db, session, err := newSession(req, s.Db, sr.RequestID())
+	if err != nil {
+		s.Error(sr.RequestID(), "db.New", err, "Completed : New Session")
+		return nil, &MError{Rid: sr.RID, Msg: "New Session Failed", IError: err}
+	}
+
+	defer session.Close()
+
+	keys := mongoSort(sr.Fields)
+
+	// Retrieve a record past the limit, so the sort is rejected for
+	// exceeding it rather than silently cut short.
+	qry, limit := bound(db.C(sr.Doc).Find(nil).Sort(keys...), s.Limits)
+	s.Log(sr.RequestID(), "DBAction", "db.%s.find({}).sort(%s).limit(%d)", sr.Doc, keys, limit)
+
+	var res data.Parameters
+
+	if err := qry.All(&res); err != nil {
+		s.Error(sr.RequestID(), "DBAction", err, "Completed")
+		return nil, &MError{Rid: sr.RID, Msg: "Sort Failed", IError: err}
+	}
+
+	if lerr := s.Limits.CheckResponse(sr.RID, &coquery.Response{Req: sr, Data: res}); lerr != nil {
+		s.Error(sr.RequestID(), "Sort.Do", lerr, "Completed")
+		return nil, lerr
+	}
+
+	for _, record := range res {
+		if err := s.Store.Add((map[string]interface{})(record)); err != nil {
+			s.Error(sr.RequestID(), "Sort.Do", err, "Info : Store.Add")
+		}
+	}
+
+	s.Log(sr.RequestID(), "Sort.Do", "Completed")
+	s.Log("mongodocs", "Sort.Do", "Completed")
+
+	return &coquery.Response{
+		Req:  sr,
+		Data: res,
+	}, nil
+}
+
+//==========================================================================================
+
+// mongoSort returns the mgo sort keys for the giving fields, where descending
+// fields are prefixed with '-'.
+func mongoSort(fields []coquery.SortField) []string {
+	var keys []string
+
+	for _, field := range fields {
+		if field.Desc {
+			keys = append(keys, "-"+field.Key)
+			continue
+		}
+
+		keys = append(keys, field.Key)
+	}
+
+	return keys
+}
+
+//==========================================================================================
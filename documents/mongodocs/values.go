@@ -0,0 +1,53 @@
+package mongodocs
+
+import (
+	"github.com/influx6/coquery/data"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//==============================================================================
+
+// bsonValue converts the typed query values within the giving value into
+// their bson counterparts eg a data.ObjectID into a bson.ObjectId, walking
+// through maps and slices as needed.
+func bsonValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case data.ObjectID:
+		if !bson.IsObjectIdHex(string(value)) {
+			return string(value)
+		}
+
+		return bson.ObjectIdHex(string(value))
+
+	case data.Parameter:
+		return bsonMap(value)
+
+	case map[string]interface{}:
+		return bsonMap(value)
+
+	case []interface{}:
+		items := make([]interface{}, 0, len(value))
+
+		for _, item := range value {
+			items = append(items, bsonValue(item))
+		}
+
+		return items
+	}
+
+	return v
+}
+
+// bsonMap returns a copy of the giving map with its values converted using
+// bsonValue.
+func bsonMap(m map[string]interface{}) map[string]interface{} {
+	to := make(map[string]interface{}, len(m))
+
+	for key, value := range m {
+		to[key] = bsonValue(value)
+	}
+
+	return to
+}
+
+//==============================================================================
@@ -0,0 +1,146 @@
+package mongodocs
+
+import (
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/documents/crossdocs"
+	"github.com/influx6/coquery/storage"
+	"github.com/influx6/coquery/utils"
+	"gopkg.in/mgo.v2/bson"
+)
+
+//==========================================================================================
+
+// Where provides a worker for handling where requests, filtering the records
+// of a previous response in memory or else querying the db with the mongo
+// form of the predicate. Queries matching more than the MaxRecords of its
+// Limits are rejected with a *coquery.LimitError, reading no more than one
+// past them.
+type Where struct {
+	Events
+	Db     DB
+	Store  storage.Store
+	Limits *coquery.Limits
+}
+
+// Do performs the necessary tasks passed to Where.
+func (w *Where) Do(dataReq interface{}, err error) (interface{}, error) {
+	w.Log("mongodocs", "Where.Do", "Started : %s", utils.Query.Query(dataReq))
+
+	if err != nil {
+		w.Error("mongodocs", "Where.Do", err, "Completed")
+		return nil, err
+	}
+
+	req, ok := dataReq.(*coquery.Request)
+	if !ok {
+		w.Error("mongodocs", "Where.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	where, ok := req.R.(*coquery.Where)
+	if !ok {
+		w.Error(req.R.RequestID(), "Where.Do", coquery.ErrInvalidRequestType, "Completed")
+		return nil, coquery.ErrInvalidRequestType
+	}
+
+	// If we had a previous response, then we are dealing with a concatenated
+	// operation on the last request, so we filter its records.
+	if req.LastResponse != nil {
+		res := crossdocs.Filter(req.LastResponse.Data, where.Predicate)
+
+		w.Log(where.RequestID(), "Where.Do", "Info : Filtered : Record Found")
+		w.Log(where.RequestID(), "Where.Do", "Completed")
+
+		return &coquery.Response{
+			Req:  where,
+			Data: res,
+		}, nil
+	}
+
+	db, session, err := newSession(req, w.Db, where.RequestID())
+	if err != nil {
+		w.Error(where.RequestID(), "db.New", err, "Completed : New Session")
+		return nil, &MError{Rid: where.RID, Msg: "New Session Failed", IError: err}
+	}
+
+	defer session.Close()
+
+	q := mongoQuery(where.Predicate)
+
+	qry, limit := bound(db.C(where.Doc).Find(q), w.Limits)
+	w.Log(where.RequestID(), "DBAction", "db.%s.find(%s).limit(%d)", where.Doc, utils.Query.Query(q), limit)
+
+	var res data.Parameters
+
+	if err := qry.All(&res); err != nil {
+		w.Error(where.RequestID(), "DBAction", err, "Completed")
+		return nil, &MError{Rid: where.RID, Msg: "Where Failed", IError: err}
+	}
+
+	if lerr := w.Limits.CheckResponse(where.RID, &coquery.Response{Req: where, Data: res}); lerr != nil {
+		w.Error(where.RequestID(), "Where.Do", lerr, "Completed")
+		return nil, lerr
+	}
+
+	w.Log(where.RequestID(), "Where.Do", "Info : Response : %s", utils.Query.Query(res))
+
+	for _, record := range res {
+		if err := w.Store.Add((map[string]interface{})(record)); err != nil {
+			w.Error(where.RequestID(), "Where.Do", err, "Info : Store.Add")
+		}
+	}
+
+	w.Log(where.RequestID(), "Where.Do", "Completed")
+	w.Log("mongodocs", "Where.Do", "Completed")
+
+	return &coquery.Response{
+		Req:  where,
+		Data: res,
+	}, nil
+}
+
+//==========================================================================================
+
+// mongoOps maps the predicate comparison operators to their mongo operators.
+var mongoOps = map[string]string{
+	coquery.OpNe:  "$ne",
+	coquery.OpGt:  "$gt",
+	coquery.OpGte: "$gte",
+	coquery.OpLt:  "$lt",
+	coquery.OpLte: "$lte",
+	coquery.OpIn:  "$in",
+	coquery.OpNin: "$nin",
+}
+
+// mongoQuery returns the mongo query document for the giving predicate.
+func mongoQuery(pred *coquery.Predicate) bson.M {
+	switch pred.Op {
+	case coquery.OpAnd, coquery.OpOr:
+		var subs []bson.M
+
+		for _, operand := range pred.Operands {
+			subs = append(subs, mongoQuery(operand))
+		}
+
+		return bson.M{"$" + pred.Op: subs}
+
+	case coquery.OpNot:
+		// Mongo's $not only applies to a single field, so a negated expression
+		// is expressed as matching none of the operands.
+		var subs []bson.M
+
+		for _, operand := range pred.Operands {
+			subs = append(subs, mongoQuery(operand))
+		}
+
+		return bson.M{"$nor": subs}
+
+	case coquery.OpEq:
+		return bson.M{pred.Field: bsonValue(pred.Value)}
+	}
+
+	return bson.M{pred.Field: bson.M{mongoOps[pred.Op]: bsonValue(pred.Value)}}
+}
+
+//==========================================================================================
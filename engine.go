@@ -2,8 +2,11 @@ package coquery
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"sync/atomic"
 
 	"github.com/influx6/coquery/data"
@@ -26,7 +29,7 @@ type EventLog interface {
 // QueryProcessor provides an interface that processes a query into a requests
 // lists returning an error if the query had or was an invalid requests.
 type QueryProcessor interface {
-	Generate(context interface{}, rid string, doc string, queries []string) (RecordRequests, ResponseError)
+	Generate(context interface{}, rid string, doc string, calls []*parser.Call) (RecordRequests, ResponseError)
 }
 
 // ResponseWriter defines a interface for custom response writers for a
@@ -41,6 +44,30 @@ type Document interface {
 	Handle(context interface{}, rq RecordRequests, rw ResponseWriter)
 }
 
+// ContextDocument defines a interface for Documents which serve requests
+// under the context.Context of their query, abandoning the requests once the
+// context is done eg cancelled or past its deadline.
+type ContextDocument interface {
+	Document
+	HandleContext(ctx context.Context, context interface{}, rq RecordRequests, rw ResponseWriter)
+}
+
+// Resolver defines a interface for serving requests against another document,
+// allowing requests to retrieve records held by other documents. The path is
+// either the name of a document within the same route eg users or a root and
+// document name eg docs.users. The request context provides the request id,
+// the principal the requests are authorized for and the context.Context they
+// are served under.
+type Resolver interface {
+	Resolve(context interface{}, rctx *data.RequestContext, path string, reqs RecordRequests) (*Response, ResponseError)
+}
+
+// ResolverUser defines a interface for requests which need a Resolver to
+// retrieve records from other documents.
+type ResolverUser interface {
+	UseResolver(Resolver)
+}
+
 // Doc provides a interface that allows a single-level of responsibility
 // for the object that provides both its Document system and
 // its QueryProcessor.
@@ -77,14 +104,62 @@ func (r *CoError) Error() string {
 	return r.Rid + " : " + r.Msg
 }
 
+// MarshalJSON encodes the error with its internal error as a string, which
+// would otherwise encode as a empty object.
+func (r *CoError) MarshalJSON() ([]byte, error) {
+	var ierr string
+	if r.IError != nil {
+		ierr = r.IError.Error()
+	}
+
+	return json.Marshal(struct {
+		Rid    string `json:"rid"`
+		Msg    string `json:"message"`
+		IError string `json:"error,omitempty"`
+	}{r.Rid, r.Msg, ierr})
+}
+
+//==============================================================================
+
+// ConflictError is returned when a mutate request made with an expected
+// version (eg mutate({...}, ifVersion=7)) finds the record at another version.
+// Version holds the current version of the record, allowing clients to reload
+// and retry.
+type ConflictError struct {
+	Rid     string      `json:"rid" bson:"rid"`
+	Msg     string      `json:"message" bson:"message"`
+	Key     interface{} `json:"key" bson:"key"`
+	Version int64       `json:"version" bson:"version"`
+}
+
+// Message returns the internal message for this error
+func (r *ConflictError) Message() string {
+	return r.Msg
+}
+
+// RequestID returns the response error requestID
+func (r *ConflictError) RequestID() string {
+	return r.Rid
+}
+
+// Error returns the error message for this response error.
+func (r *ConflictError) Error() string {
+	return fmt.Sprintf("%s : %s : Record[%v] At Version %d", r.Rid, r.Msg, r.Key, r.Version)
+}
+
 //==============================================================================
 
 // DocumentRouter defines a interface that defines a means for registering
 // document providers for request processing.
 type DocumentRouter interface {
+	Resolver
 	DocumentWith(context interface{}, path string, doc Doc) DocumentRouter
 	Document(context interface{}, path string, qs QueryProcessor, d Document) DocumentRouter
-	Serve(context interface{}, rid string, path string, queries []string, rw ResponseWriter)
+	Schema(context interface{}, path string, schema *storage.Schema) DocumentRouter
+	UseAuthorizer(context interface{}, auth Authorizer) DocumentRouter
+	UseLimits(context interface{}, limits Limits) DocumentRouter
+	Serve(context interface{}, rctx *data.RequestContext, path string, calls []*parser.Call, rw ResponseWriter)
+	Describe(context interface{}) []DocumentInfo
 }
 
 // docSet defines a structure for storing a query processor and a Document
@@ -100,6 +175,10 @@ type DocRoute struct {
 	EventLog
 	docAdd    int64
 	documents map[string]*docSet
+	schemas   map[string]*storage.Schema
+	auth      Authorizer
+	limits    *Limits
+	parent    Resolver
 }
 
 // NewDocRoute returns a new instance of a DocRoute.
@@ -107,6 +186,7 @@ func NewDocRoute(elog EventLog) *DocRoute {
 	dr := DocRoute{
 		EventLog:  elog,
 		documents: make(map[string]*docSet),
+		schemas:   make(map[string]*storage.Schema),
 	}
 
 	return &dr
@@ -131,6 +211,10 @@ func (d *DocRoute) DocumentWith(context interface{}, subPath string, doc Doc) Do
 			d.documents[subPath] = &docSet{query: doc.Queries(), doc: doc.Document()}
 		}
 		atomic.AddInt64(&d.docAdd, -1)
+
+		if sd, ok := doc.(SchemaDoc); ok && sd.Schema() != nil {
+			d.Schema(context, subPath, sd.Schema())
+		}
 	}
 
 	d.Log(context, "Document", "Completed")
@@ -162,20 +246,73 @@ func (d *DocRoute) Document(context interface{}, subPath string, qs QueryProcess
 	return d
 }
 
+// Schema sets the schema which the records created or mutated through the
+// document of the giving subroute are validated against, replacing any
+// schema set before.
+func (d *DocRoute) Schema(context interface{}, subPath string, schema *storage.Schema) DocumentRouter {
+	d.Log(context, "Schema", "Started : Document Schema : %s", subPath)
+
+	atomic.AddInt64(&d.docAdd, 1)
+	{
+		d.schemas[subPath] = schema
+	}
+	atomic.AddInt64(&d.docAdd, -1)
+
+	d.Log(context, "Schema", "Completed")
+	return d
+}
+
+// UseAuthorizer sets the Authorizer consulted with the requests of every query
+// served by the route, replacing any set before.
+func (d *DocRoute) UseAuthorizer(context interface{}, auth Authorizer) DocumentRouter {
+	d.Log(context, "UseAuthorizer", "Started")
+
+	atomic.AddInt64(&d.docAdd, 1)
+	{
+		d.auth = auth
+	}
+	atomic.AddInt64(&d.docAdd, -1)
+
+	d.Log(context, "UseAuthorizer", "Completed")
+	return d
+}
+
+// UseLimits sets the cost limits of every query served by the route, in
+// addition to those of its documents, replacing any set before. MaxBatch is
+// left to the engine.
+func (d *DocRoute) UseLimits(context interface{}, limits Limits) DocumentRouter {
+	d.Log(context, "UseLimits", "Started")
+
+	atomic.AddInt64(&d.docAdd, 1)
+	{
+		d.limits = &limits
+	}
+	atomic.AddInt64(&d.docAdd, -1)
+
+	d.Log(context, "UseLimits", "Completed")
+	return d
+}
+
 // ErrDocumentRoutePanic is returned when a document internal processing panics.
 var ErrDocumentRoutePanic = errors.New("Document Paniced")
 
 // Serve takes the requests needed and serves up the requests lists to the
-// response writer.
-func (d *DocRoute) Serve(context interface{}, requestID string, subPath string, queries []string, rw ResponseWriter) {
-	d.Log(context, "Serve", "Started : Path[%s] : Query: %s", subPath, queries)
+// response writer. The requests are served only if the route's Authorizer, if
+// any, authorizes them for the principal of the request context and they are
+// within the route's Limits.
+func (d *DocRoute) Serve(context interface{}, rctx *data.RequestContext, subPath string, calls []*parser.Call, rw ResponseWriter) {
+	d.Log(context, "Serve", "Started : Path[%s] : Query: %s", subPath, calls)
+
+	requestID := rctx.RequestID
 
 	var ok bool
 	var set *docSet
+	var limits *Limits
 
 	atomic.AddInt64(&d.docAdd, 1)
 	{
 		set, ok = d.documents[subPath]
+		limits = d.limits
 	}
 	atomic.AddInt64(&d.docAdd, -1)
 
@@ -192,7 +329,13 @@ func (d *DocRoute) Serve(context interface{}, requestID string, subPath string,
 		return
 	}
 
-	reqs, err := set.query.Generate(context, requestID, subPath, queries)
+	if err := limits.CheckQuery(requestID, calls); err != nil {
+		d.Error(context, "Serve", err, "Completed")
+		rw.Write(context, nil, err)
+		return
+	}
+
+	reqs, err := set.query.Generate(context, requestID, subPath, calls)
 	if err != nil {
 		d.Error(context, "Serve", err, "Completed")
 		rw.Write(context, nil, err)
@@ -211,16 +354,205 @@ func (d *DocRoute) Serve(context interface{}, requestID string, subPath string,
 		return
 	}
 
-	// Let the handler work in a go-routine and report a panic if any.
+	rw, err = d.prepare(context, rctx, subPath, reqs, rw)
+	if err != nil {
+		d.Error(context, "Serve", err, "Completed")
+		rw.Write(context, nil, err)
+		return
+	}
+
+	d.handle(context, rctx, set, reqs, rw)
+	d.Log(context, "Serve", "Completed")
+}
+
+// prepare checks the requests for the giving document against the route's
+// Limits, Authorizer and the document's schema, providing them with what they
+// need to be served. It returns the writer their replies are to be written
+// through, which applies the limits and hides the fields the principal may
+// not see.
+func (d *DocRoute) prepare(context interface{}, rctx *data.RequestContext, subPath string, reqs RecordRequests, rw ResponseWriter) (ResponseWriter, ResponseError) {
+	requestID := rctx.RequestID
+
+	var auth Authorizer
+	var schema *storage.Schema
+	var limits *Limits
+
+	atomic.AddInt64(&d.docAdd, 1)
+	{
+		schema = d.schemas[subPath]
+		auth = d.auth
+		limits = d.limits
+	}
+	atomic.AddInt64(&d.docAdd, -1)
+
+	if err := limits.CheckRequests(requestID, reqs); err != nil {
+		return rw, err
+	}
+
+	var grant *Grant
+
+	if auth != nil {
+		var err ResponseError
+		if grant, err = authorize(context, requestID, auth, rctx.Principal, subPath, reqs); err != nil {
+			return rw, err
+		}
+	}
+
+	// Records being created or mutated must satisfy the document's schema
+	// before they reach the document.
+	if schema != nil {
+		if err := validateRequests(requestID, schema, reqs); err != nil {
+			return rw, err
+		}
+	}
+
+	// Provide requests which traverse into other documents with the means to
+	// do so.
+	for _, req := range reqs {
+		if ru, ok := req.(ResolverUser); ok {
+			ru.UseResolver(d)
+		}
+	}
+
+	// Provide requests with the principal making them, if any.
+	if rctx.Principal != nil {
+		for _, req := range reqs {
+			if pu, ok := req.(PrincipalUser); ok {
+				pu.UsePrincipal(rctx.Principal)
+			}
+		}
+	}
+
+	// Requests served within an atomic batch record their changes into the
+	// batch's journal.
+	if jw, ok := rw.(JournalWriter); ok {
+		for _, req := range reqs {
+			if ju, ok := req.(JournalUser); ok {
+				ju.UseJournal(jw.Journal())
+			}
+		}
+	}
+
+	// Replies exceeding the route's limits are replaced by the limit hit.
+	if limits != nil {
+		rw = &limitingWriter{ResponseWriter: rw, rid: requestID, limits: limits}
+	}
+
+	// Fields hidden from the principal are stripped from the replies, which
+	// carry the grant so the patches replied along are limited to it.
+	if grant != nil {
+		rw = &hidingWriter{ResponseWriter: rw, grant: grant}
+	}
+
+	return rw, nil
+}
+
+// handle serves the requests with the document under the context of the
+// request, reporting a panic if any.
+func (d *DocRoute) handle(context interface{}, rctx *data.RequestContext, set *docSet, reqs RecordRequests, rw ResponseWriter) {
 	panics.Defer(func() {
-		d.Log(context, "Serve.GoRoutine", "Started : Req %s", requestID)
-		set.doc.Handle(context, reqs, rw)
-		d.Log(context, "Serve.GoRoutine", "Completed")
+		d.Log(context, "handle", "Started : Req %s", rctx.RequestID)
+
+		if cd, ok := set.doc.(ContextDocument); ok {
+			cd.HandleContext(rctx.Context(), context, reqs, rw)
+		} else {
+			set.doc.Handle(context, reqs, rw)
+		}
+
+		d.Log(context, "handle", "Completed")
 	}, func(report *bytes.Buffer) {
-		d.Error(context, "Serve", ErrDocumentRoutePanic, "Panic : \n%s", report.String())
+		d.Error(context, "handle", ErrDocumentRoutePanic, "Panic : \n%s", report.String())
 	})
+}
 
-	d.Log(context, "Serve", "Completed")
+// Resolve serves the giving requests with the document of the giving path,
+// returning its response. Paths with a root eg docs.users are resolved by
+// the engine the DocRoute belongs to. The requests are checked as those of a
+// query served by the route, for the principal of the request context and
+// under its context.
+func (d *DocRoute) Resolve(context interface{}, rctx *data.RequestContext, path string, reqs RecordRequests) (*Response, ResponseError) {
+	d.Log(context, "Resolve", "Started : Path[%s] : Requests[%d]", path, len(reqs))
+
+	requestID := rctx.RequestID
+
+	if strings.Contains(path, ".") {
+		if d.parent == nil {
+			err := &CoError{
+				Rid:    requestID,
+				Msg:    fmt.Sprintf("Invalid Path[%s] Request", path),
+				IError: errors.New("404"),
+			}
+
+			d.Error(context, "Resolve", err, "Completed")
+			return nil, err
+		}
+
+		d.Log(context, "Resolve", "Completed")
+		return d.parent.Resolve(context, rctx, path, reqs)
+	}
+
+	var ok bool
+	var set *docSet
+
+	atomic.AddInt64(&d.docAdd, 1)
+	{
+		set, ok = d.documents[path]
+	}
+	atomic.AddInt64(&d.docAdd, -1)
+
+	if !ok {
+		err := &CoError{
+			Rid:    requestID,
+			Msg:    fmt.Sprintf("Invalid Path[%s] Request", path),
+			IError: errors.New("404"),
+		}
+
+		d.Error(context, "Resolve", err, "Completed")
+		return nil, err
+	}
+
+	var capture captureWriter
+
+	rw, err := d.prepare(context, rctx, path, reqs, &capture)
+	if err != nil {
+		d.Error(context, "Resolve", err, "Completed")
+		return nil, err
+	}
+
+	d.handle(context, rctx, set, reqs, rw)
+
+	if capture.err != nil {
+		d.Error(context, "Resolve", capture.err, "Completed")
+		return nil, capture.err
+	}
+
+	if capture.res == nil {
+		err := &CoError{
+			Rid:    requestID,
+			Msg:    fmt.Sprintf("No Response From Path[%s]", path),
+			IError: ErrDocumentRoutePanic,
+		}
+
+		d.Error(context, "Resolve", err, "Completed")
+		return nil, err
+	}
+
+	d.Log(context, "Resolve", "Completed")
+	return capture.res, nil
+}
+
+// captureWriter provides a ResponseWriter which keeps the response written
+// to it.
+type captureWriter struct {
+	res *Response
+	err ResponseError
+}
+
+// Write stores the giving response and error.
+func (c *captureWriter) Write(context interface{}, res *Response, err ResponseError) error {
+	c.res = res
+	c.err = err
+	return nil
 }
 
 //==============================================================================
@@ -228,7 +560,9 @@ func (d *DocRoute) Serve(context interface{}, requestID string, subPath string,
 // Engine defines a interface for a coquery service providers.
 type Engine interface {
 	Route(context interface{}, root string) DocumentRouter
+	UseLimits(context interface{}, limits Limits) Engine
 	Serve(context interface{}, ctx *data.RequestContext, rw ResponseWriter)
+	Describe(context interface{}) *EngineInfo
 }
 
 // New returns a new Engine implementing structure for interfacing with
@@ -253,9 +587,28 @@ type CoEngine struct {
 	diff     Diffs
 	routeAdd int64
 	store    storage.Store
+	limits   *Limits
 	routers  map[string]DocumentRouter
 }
 
+// UseLimits sets the cost limits of every request served by the engine,
+// replacing any set before. MaxBatch limits the queries of a request and
+// MaxDepth each of its queries, while MaxRecords and MaxBytes are checked
+// against the replies of its queries. Routes and documents may set stricter
+// limits of their own.
+func (co *CoEngine) UseLimits(context interface{}, limits Limits) Engine {
+	co.Log(context, "UseLimits", "Started")
+
+	atomic.AddInt64(&co.routeAdd, 1)
+	{
+		co.limits = &limits
+	}
+	atomic.AddInt64(&co.routeAdd, -1)
+
+	co.Log(context, "UseLimits", "Completed")
+	return co
+}
+
 // Serve processes the query using the coquery parser and runs the internal
 // pieces accordingly sending the parts into the appropriate route else
 // responding with an appropriate error.
@@ -273,6 +626,20 @@ func (co *CoEngine) Serve(context interface{}, rctx *data.RequestContext, rw Res
 		return
 	}
 
+	var limits *Limits
+
+	atomic.AddInt64(&co.routeAdd, 1)
+	{
+		limits = co.limits
+	}
+	atomic.AddInt64(&co.routeAdd, -1)
+
+	if err := limits.CheckBatch(rctx.RequestID, rctx.Queries); err != nil {
+		co.Error(context, "Serve", err, "Completed")
+		rw.Write(context, nil, err)
+		return
+	}
+
 	// The final ResponseWriter for this request.
 	var rws ResponseWriter
 
@@ -286,12 +653,15 @@ func (co *CoEngine) Serve(context interface{}, rctx *data.RequestContext, rw Res
 
 		// Create the JSON response writer for this request.
 		inRws = &JSONResponseWriter{
-			ctx:   rctx,
-			res:   rw,
-			store: co.store,
-			diff:  co.diff,
+			ctx:    rctx,
+			res:    rw,
+			store:  co.store,
+			diff:   co.diff,
+			limits: limits,
 		}
 
+	} else if limits != nil {
+		inRws = &limitingWriter{ResponseWriter: rw, rid: rctx.RequestID, limits: limits}
 	} else {
 		inRws = rw
 	}
@@ -308,8 +678,36 @@ func (co *CoEngine) Serve(context interface{}, rctx *data.RequestContext, rw Res
 		rws = inRws
 	}
 
+	// Atomic batches hold back the responses of their queries until all have
+	// succeeded, else the changes they made are rolled back and only the
+	// failure is reported.
+	if rctx.Atomic {
+		var batch AtomicResponseWriter
+
+		for _, qry := range rctx.Queries {
+			co.serve(context, qry, rctx, limits, &batch)
+
+			if batch.Failed() {
+				break
+			}
+		}
+
+		if err := batch.Commit(context, rctx.RequestID, len(rctx.Queries)); err != nil {
+			co.Error(context, "Serve", err, "Completed : Atomic Batch Rolled Back")
+			inRws.Write(context, nil, err)
+			return
+		}
+
+		for _, res := range batch.Responses() {
+			rws.Write(context, res, nil)
+		}
+
+		co.Log(context, "Serve", "Completed")
+		return
+	}
+
 	for _, qry := range rctx.Queries {
-		co.serve(context, qry, rctx, rws)
+		co.serve(context, qry, rctx, limits, rws)
 	}
 
 	co.Log(context, "Serve", "Completed")
@@ -317,16 +715,32 @@ func (co *CoEngine) Serve(context interface{}, rctx *data.RequestContext, rw Res
 
 // serve processes the individual query strings that are to be processed by
 // the coquery.API, using the appropriate API calls needed.
-func (co *CoEngine) serve(context interface{}, query string, rctx *data.RequestContext, rw ResponseWriter) {
+func (co *CoEngine) serve(context interface{}, query string, rctx *data.RequestContext, limits *Limits, rw ResponseWriter) {
 	co.Log(context, "serve", "Started : RequestID[%s] : Query[%s]", rctx.RequestID, query)
 
-	queryList := parser.ParseQuery(context, query)
+	// Queries of a cancelled or timed out request are not served.
+	if cerr := rctx.Context().Err(); cerr != nil {
+		err := &CoError{
+			Rid:    rctx.RequestID,
+			Msg:    fmt.Sprintf("Request Cancelled: %s", query),
+			IError: cerr,
+		}
+
+		co.Error(context, "serve", err, "Completed")
+		rw.Write(context, nil, err)
+		return
+	}
+
+	q, perr := parser.Parse(query)
+	if perr == nil {
+		perr = validQuery(q)
+	}
 
-	if dl := len(queryList); dl < 3 {
+	if perr != nil {
 		err := &CoError{
 			Rid:    rctx.RequestID,
 			Msg:    fmt.Sprintf("Invalid Query: %s", query),
-			IError: fmt.Errorf("Invalid Query Length %d", dl),
+			IError: perr,
 		}
 
 		co.Error(context, "serve", err, "Completed")
@@ -334,10 +748,16 @@ func (co *CoEngine) serve(context interface{}, query string, rctx *data.RequestC
 		return
 	}
 
+	if err := limits.CheckQuery(rctx.RequestID, q.Calls); err != nil {
+		co.Error(context, "serve", err, "Completed")
+		rw.Write(context, nil, err)
+		return
+	}
+
 	var ok bool
 	var set DocumentRouter
 
-	root := queryList[0]
+	root := q.Path[0].Name
 
 	atomic.AddInt64(&co.routeAdd, 1)
 	{
@@ -357,13 +777,84 @@ func (co *CoEngine) serve(context interface{}, query string, rctx *data.RequestC
 		return
 	}
 
-	sub := queryList[1]
-	qs := queryList[2:]
+	sub := q.Path[1].Name
 
-	set.Serve(context, rctx.RequestID, sub, qs, rw)
+	set.Serve(context, rctx, sub, q.Calls, rw)
 	co.Log(context, "serve", "Completed")
 }
 
+// validQuery returns a *parser.SyntaxError if the parsed query does not have
+// the root and document path followed by atleast one method call.
+func validQuery(q *parser.Query) error {
+	if len(q.Path) != 2 {
+		pos := parser.Position{Line: 1, Column: 1}
+
+		switch {
+		case len(q.Path) > 2:
+			pos = q.Path[2].Pos()
+		case len(q.Calls) > 0:
+			pos = q.Calls[0].Pos()
+		}
+
+		return &parser.SyntaxError{
+			Pos: pos,
+			Msg: fmt.Sprintf("expected root and document path (eg docs.users), found %d path segment(s)", len(q.Path)),
+		}
+	}
+
+	if len(q.Calls) == 0 {
+		return &parser.SyntaxError{
+			Pos: q.Path[1].End(),
+			Msg: "expected a method call after the document path",
+		}
+	}
+
+	return nil
+}
+
+// Resolve serves the giving requests with the document of the giving root
+// and document path eg docs.users, returning its response.
+func (co *CoEngine) Resolve(context interface{}, rctx *data.RequestContext, path string, reqs RecordRequests) (*Response, ResponseError) {
+	co.Log(context, "Resolve", "Started : RequestID[%s] : Path[%s]", rctx.RequestID, path)
+
+	rid := rctx.RequestID
+
+	parts := strings.Split(path, ".")
+	if len(parts) != 2 {
+		err := &CoError{
+			Rid:    rid,
+			Msg:    fmt.Sprintf("Invalid Document Path[%s]", path),
+			IError: errors.New("404"),
+		}
+
+		co.Error(context, "Resolve", err, "Completed")
+		return nil, err
+	}
+
+	var ok bool
+	var set DocumentRouter
+
+	atomic.AddInt64(&co.routeAdd, 1)
+	{
+		set, ok = co.routers[parts[0]]
+	}
+	atomic.AddInt64(&co.routeAdd, -1)
+
+	if !ok {
+		err := &CoError{
+			Rid:    rid,
+			Msg:    fmt.Sprintf("Invalid Query Path[%s]", parts[0]),
+			IError: errors.New("504"),
+		}
+
+		co.Error(context, "Resolve", err, "Completed")
+		return nil, err
+	}
+
+	co.Log(context, "Resolve", "Completed")
+	return set.Resolve(context, rctx, parts[1], reqs)
+}
+
 // Route sets up a document router for handling subdocuments for this
 // specific route.
 func (co *CoEngine) Route(context interface{}, root string) DocumentRouter {
@@ -383,7 +874,9 @@ func (co *CoEngine) Route(context interface{}, root string) DocumentRouter {
 		return doc
 	}
 
-	doc = NewDocRoute(co.EventLog)
+	dr := NewDocRoute(co.EventLog)
+	dr.parent = co
+	doc = dr
 
 	atomic.AddInt64(&co.routeAdd, 1)
 	{
@@ -1,13 +1,18 @@
 package coquery_test
 
 import (
+	gocontext "context"
 	"errors"
 	"os"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/ardanlabs/kit/log"
 	"github.com/ardanlabs/kit/tests"
 	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/parser"
 	"github.com/influx6/coquery/storage"
 )
 
@@ -61,7 +66,57 @@ func (im *inMemory) Handle(context interface{}, reqs coquery.RecordRequests, res
 
 	res.Write(context, &coquery.Response{
 		Req:  reqs[0],
-		Data: []coquery.Parameter{{"id": 1, "greeting": "Hello World!"}},
+		Data: data.Parameters{{"id": 1, "greeting": "Hello World!"}},
+	}, nil)
+}
+
+//==============================================================================
+
+// remover provides a Document which removes the record with id 5 from its
+// store.
+type remover struct {
+	store storage.Store
+}
+
+// Handle removes the record and replies with it.
+func (r *remover) Handle(context interface{}, reqs coquery.RecordRequests, res coquery.ResponseWriter) {
+	record := map[string]interface{}{"id": 5}
+	r.store.Remove(record)
+
+	res.Write(context, &coquery.Response{
+		Req:  reqs[0],
+		Data: data.Parameters{record},
+	}, nil)
+}
+
+//==============================================================================
+
+// creator provides a Document which adds the records of create requests to
+// its store, recording their removal within the request's journal.
+type creator struct {
+	store storage.Store
+}
+
+// Handle adds the records and replies with them.
+func (c *creator) Handle(context interface{}, reqs coquery.RecordRequests, res coquery.ResponseWriter) {
+	create, ok := reqs[0].(*coquery.Create)
+	if !ok {
+		res.Write(context, nil, &coquery.CoError{Rid: reqs[0].RequestID(), Msg: "Expected create request"})
+		return
+	}
+
+	for _, record := range create.Records {
+		rec := map[string]interface{}(record)
+		c.store.Add(rec)
+
+		create.Journal.Record(func() error {
+			return c.store.Remove(rec)
+		})
+	}
+
+	res.Write(context, &coquery.Response{
+		Req:  create,
+		Data: create.Records,
 	}, nil)
 }
 
@@ -92,10 +147,11 @@ func TestCoEngine(t *testing.T) {
 	t.Logf("Given the need to pass requests to a coquery.Engine")
 	{
 
-		eos := coquery.New(events, coquery.NewDiffs(events), storage.New("id"))
+		store := storage.New("id")
+		eos := coquery.New(events, coquery.NewDiffs(events), store)
 
 		eos.Route(context, "doc").
-			Document(context, "greetings", &coquery.BasicQueries{EventLog: events}, &inMemory{})
+			Document(context, "greetings", &coquery.BasicQueries{EventLog: events, Store: store}, &inMemory{})
 
 		q1 := "doc.greetings.find(id,1)"
 		t.Logf("\tWhen giving a query with one request: %q", q1)
@@ -108,9 +164,9 @@ func TestCoEngine(t *testing.T) {
 
 			qid := "432UFY"
 
-			eos.Serve(context, &coquery.RequestContext{
+			eos.Serve(context, &data.RequestContext{
 				RequestID: qid,
-				Query:     []string{q1},
+				Queries:   []string{q1},
 			}, writer)
 
 			var res *coquery.Response
@@ -133,7 +189,7 @@ func TestCoEngine(t *testing.T) {
 			t.Logf("\t%s\tShould have received response with request Id[%s]", tests.Success, qid)
 
 			first := res.Data[0]
-			result := (first.Get("results").(coquery.Parameters))[0]
+			result := (first.Get("results").(data.Parameters))[0]
 
 			if result.Get("greeting") != "Hello World!" {
 				t.Logf("\t\t%+s\n", result)
@@ -154,9 +210,9 @@ func TestCoEngine(t *testing.T) {
 
 			qid := "632UFY"
 
-			eos.Serve(context, &coquery.RequestContext{
+			eos.Serve(context, &data.RequestContext{
 				RequestID: qid,
-				Query:     []string{q2},
+				Queries:   []string{q2},
 			}, writer)
 
 			// var res *coquery.Response
@@ -172,5 +228,669 @@ func TestCoEngine(t *testing.T) {
 			}
 			t.Logf("\t%s\tShould have failed when there was more than one request: %q", tests.Success, err.Error())
 		}
+
+		q3 := "doc.greetings.find(id,'alex)"
+		t.Logf("\tWhen giving a malformed query: %q", q3)
+		{
+
+			writer := &spyWriter{
+				Out: make(chan *coquery.Response),
+				Err: make(chan coquery.ResponseError),
+			}
+
+			eos.Serve(context, &data.RequestContext{
+				RequestID: "732UFY",
+				Queries:   []string{q3},
+			}, writer)
+
+			var err coquery.ResponseError
+
+			select {
+			case <-writer.Out:
+			case err = <-writer.Err:
+			}
+
+			if err == nil {
+				t.Fatalf("\t%s\tShould have failed with a syntax error.", tests.Failed)
+			}
+
+			coerr, ok := err.(*coquery.CoError)
+			if !ok {
+				t.Fatalf("\t%s\tShould have failed with a *coquery.CoError: %T", tests.Failed, err)
+			}
+
+			if _, ok := coerr.IError.(*parser.SyntaxError); !ok {
+				t.Fatalf("\t%s\tShould have failed with a *parser.SyntaxError: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have failed with a syntax error: %q", tests.Success, err.Error())
+		}
+	}
+}
+
+//==============================================================================
+
+// TestResolve validates the routing of requests to other documents through a
+// coquery.Resolver.
+func TestResolve(t *testing.T) {
+	t.Logf("Given the need to resolve requests against other documents")
+	{
+
+		store := storage.New("id")
+		eos := coquery.New(events, coquery.NewDiffs(events), store)
+
+		route := eos.Route(context, "doc").
+			Document(context, "greetings", &coquery.BasicQueries{EventLog: events, Store: store}, &inMemory{})
+
+		eos.Route(context, "secure").
+			Document(context, "greetings", &coquery.BasicQueries{EventLog: events, Store: store}, &inMemory{}).
+			Document(context, "waits", &coquery.BasicQueries{EventLog: events, Store: store}, &waiter{}).
+			UseAuthorizer(context, &coquery.RuleAuthorizer{
+				Rules: []coquery.Rule{
+					{Role: "admin", Doc: "*"},
+					{Role: "*", Doc: "greetings", Hidden: []string{"greeting"}},
+				},
+			})
+
+		reqs := coquery.RecordRequests{&coquery.FindN{RID: "832UFY", Doc: "greetings", Amount: -1}}
+		rctx := &data.RequestContext{RequestID: "832UFY"}
+
+		for _, path := range []string{"greetings", "doc.greetings"} {
+			t.Logf("\tWhen resolving a request with the path %q", path)
+			{
+				res, err := route.Resolve(context, rctx, path, reqs)
+				if err != nil {
+					t.Fatalf("\t%s\tShould have resolved the request: %s", tests.Failed, err)
+				}
+
+				if res.Data[0].Get("greeting") != "Hello World!" {
+					t.Fatalf("\t%s\tShould have received the greetings records: %+s", tests.Failed, res.Data)
+				}
+				t.Logf("\t%s\tShould have received the greetings records", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen resolving a request with an unknown path")
+		{
+			if _, err := route.Resolve(context, rctx, "docs.greetings", reqs); err == nil {
+				t.Fatalf("\t%s\tShould have failed to resolve the request", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have failed to resolve the request", tests.Success)
+		}
+
+		t.Logf("\tWhen resolving a request against a route with an authorizer")
+		{
+			res, err := route.Resolve(context, rctx, "secure.greetings", reqs)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have resolved the request: %s", tests.Failed, err)
+			}
+
+			if res.Data[0].Has("greeting") {
+				t.Fatalf("\t%s\tShould have hidden the greeting field: %+v", tests.Failed, res.Data[0])
+			}
+			t.Logf("\t%s\tShould have hidden the greeting field", tests.Success)
+
+			_, err = route.Resolve(context, rctx, "secure.waits", reqs)
+			if cerr, ok := err.(*coquery.CoError); !ok || cerr.IError != coquery.ErrAccessDenied {
+				t.Fatalf("\t%s\tShould have denied the anonymous request: %#v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have denied the anonymous request", tests.Success)
+
+			admin := &data.Principal{ID: "ruth", Roles: []string{"admin"}}
+
+			ctx, cancel := gocontext.WithCancel(gocontext.Background())
+			cancel()
+
+			actx := (&data.RequestContext{RequestID: "832UFY", Principal: admin}).WithContext(ctx)
+
+			res, err = route.Resolve(context, actx, "secure.greetings", reqs)
+			if err != nil || res.Data[0].Get("greeting") != "Hello World!" {
+				t.Fatalf("\t%s\tShould have replied the greeting field to the admin: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have replied the greeting field to the admin", tests.Success)
+
+			_, err = route.Resolve(context, actx, "secure.waits", reqs)
+			if cerr, ok := err.(*coquery.CoError); !ok || cerr.IError != gocontext.Canceled {
+				t.Fatalf("\t%s\tShould have served the request under the cancelled context: %#v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have served the request under the cancelled context", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
+
+// TestRemovalDeltas validates that removed records are reported within the
+// deltas of a response.
+func TestRemovalDeltas(t *testing.T) {
+	t.Logf("Given the need to report removed records to clients")
+	{
+
+		store := storage.New("id")
+		eos := coquery.New(events, coquery.NewDiffs(events), store)
+
+		eos.Route(context, "doc").
+			Document(context, "removals", &coquery.BasicQueries{EventLog: events, Store: store}, &remover{store: store})
+
+		q := "doc.removals.find(id,5).remove()"
+		t.Logf("\tWhen giving a query which removes a record: %q", q)
+		{
+
+			writer := &spyWriter{
+				Out: make(chan *coquery.Response),
+				Err: make(chan coquery.ResponseError),
+			}
+
+			eos.Serve(context, &data.RequestContext{
+				RequestID: "932UFY",
+				Queries:   []string{q},
+				Diffs:     true,
+			}, writer)
+
+			var res *coquery.Response
+			var err coquery.ResponseError
+
+			select {
+			case res = <-writer.Out:
+			case err = <-writer.Err:
+			}
+
+			if err != nil {
+				t.Fatalf("\t%s\tShould have successfull received a response: %s", tests.Failed, err.Error())
+			}
+			t.Logf("\t%s\tShould have successfull received a response.", tests.Success)
+
+			deltas, _ := res.Data[0].Get("deltas").([]string)
+			if len(deltas) != 1 || deltas[0] != "5" {
+				t.Fatalf("\t%s\tShould have reported the removed record within the deltas: %v", tests.Failed, deltas)
+			}
+			t.Logf("\t%s\tShould have reported the removed record within the deltas", tests.Success)
+
+			if len(store.DeletedRecords()) != 0 {
+				t.Fatalf("\t%s\tShould have cleared the deleted records of the store", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have cleared the deleted records of the store", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
+
+// TestPatchDeltas validates that the patches of changed records are reported
+// along with the deltas of a response asking for them.
+func TestPatchDeltas(t *testing.T) {
+	t.Logf("Given the need to report the patches of changed records to clients")
+	{
+		original := map[string]interface{}{"id": 7, "name": "alex", "address": map[string]interface{}{"city": "lagos"}}
+
+		store := storage.New("id")
+		store.Add(storage.CopyMap(original))
+
+		diffs := coquery.NewDiffs(events)
+		tag := diffs.Put([]string{"1"})
+
+		eos := coquery.New(events, diffs, store)
+		eos.Route(context, "doc").
+			Document(context, "people", &coquery.BasicQueries{EventLog: events, Store: store}, &creator{store: store})
+
+		eos.Route(context, "secure").
+			UseAuthorizer(context, &coquery.RuleAuthorizer{Rules: []coquery.Rule{
+				{Role: "*", Doc: "*", Hidden: []string{"address.city"}},
+			}}).
+			Document(context, "people", &coquery.BasicQueries{EventLog: events, Store: store}, &creator{store: store})
+
+		serve := func(q string, tag string) (*coquery.Response, coquery.ResponseError) {
+			writer := &spyWriter{
+				Out: make(chan *coquery.Response),
+				Err: make(chan coquery.ResponseError),
+			}
+
+			eos.Serve(context, &data.RequestContext{
+				RequestID: "732UFY",
+				Queries:   []string{q},
+				Diffs:     true,
+				DiffTag:   tag,
+				Patches:   true,
+			}, writer)
+
+			select {
+			case res := <-writer.Out:
+				return res, nil
+			case err := <-writer.Err:
+				return nil, err
+			}
+		}
+
+		q := "doc.people.create({id:7, age:20, address:{city:'abuja'}})"
+		t.Logf("\tWhen giving a query which changes a record: %q", q)
+		{
+			writer := &spyWriter{
+				Out: make(chan *coquery.Response),
+				Err: make(chan coquery.ResponseError),
+			}
+
+			eos.Serve(context, &data.RequestContext{
+				RequestID: "732UFY",
+				Queries:   []string{q},
+				Diffs:     true,
+				DiffTag:   tag,
+				Patches:   true,
+			}, writer)
+
+			var res *coquery.Response
+			var err coquery.ResponseError
+
+			select {
+			case res = <-writer.Out:
+			case err = <-writer.Err:
+			}
+
+			if err != nil {
+				t.Fatalf("\t%s\tShould have successfull received a response: %s", tests.Failed, err.Error())
+			}
+			t.Logf("\t%s\tShould have successfull received a response.", tests.Success)
+
+			patches, _ := res.Data[0].Get("patches").(map[string]data.Patch)
+			patch, ok := patches["7"]
+			if !ok || len(patch) != 2 {
+				t.Fatalf("\t%s\tShould have reported the patch of the changed record: %+v", tests.Failed, patches)
+			}
+			t.Logf("\t%s\tShould have reported the patch of the changed record: %+v", tests.Success, patch)
+
+			stored, _ := store.Get("7")
+			if err := patch.Apply(original); err != nil || !reflect.DeepEqual(original, stored) {
+				t.Fatalf("\t%s\tShould have patched the record into the stored record: %v : %+v", tests.Failed, err, original)
+			}
+			t.Logf("\t%s\tShould have patched the record into the stored record", tests.Success)
+		}
+
+		q = "secure.people.create({id:9, name:'ruth', address:{city:'kano', state:'kano'}})"
+		t.Logf("\tWhen giving a query served under a grant: %q", q)
+		{
+			store.Add(map[string]interface{}{"id": 8, "name": "kim"})
+			store.Add(map[string]interface{}{"id": 9, "name": "ruth", "address": map[string]interface{}{"city": "lagos", "state": "lagos"}})
+			store.ClearTainted()
+
+			tag := diffs.Put([]string{"1"})
+
+			if _, err := serve("doc.people.create({id:8, name:'kimberly'})", tag); err != nil {
+				t.Fatalf("\t%s\tShould have changed a unread record: %s", tests.Failed, err)
+			}
+
+			res, err := serve(q, tag)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have successfull received a response: %s", tests.Failed, err.Error())
+			}
+			t.Logf("\t%s\tShould have successfull received a response.", tests.Success)
+
+			patches, _ := res.Data[0].Get("patches").(map[string]data.Patch)
+			if _, ok := patches["8"]; ok || len(patches) != 1 {
+				t.Fatalf("\t%s\tShould have only reported the patch of the record replied: %+v", tests.Failed, patches)
+			}
+			t.Logf("\t%s\tShould have only reported the patch of the record replied", tests.Success)
+
+			patch := patches["9"]
+			if len(patch) != 1 || patch[0].Path != "/address/state" {
+				t.Fatalf("\t%s\tShould have stripped the hidden field from the patch: %+v", tests.Failed, patch)
+			}
+			t.Logf("\t%s\tShould have stripped the hidden field from the patch", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
+
+// TestAtomicBatches validates that the changes of an atomic batch are rolled
+// back when any of its queries fail.
+func TestAtomicBatches(t *testing.T) {
+	t.Logf("Given the need to apply a batch of queries atomically")
+	{
+
+		store := storage.New("id")
+		eos := coquery.New(events, coquery.NewDiffs(events), store)
+
+		eos.Route(context, "doc").
+			Document(context, "records", &coquery.BasicQueries{EventLog: events, Store: store}, &creator{store: store}).
+			Document(context, "greetings", &coquery.BasicQueries{EventLog: events, Store: store}, &inMemory{})
+
+		late := &laggard{store: store, release: make(chan struct{}), done: make(chan struct{})}
+
+		eos.Route(context, "doc").
+			Document(context, "late", &coquery.BasicQueries{EventLog: events, Store: store}, late)
+
+		serve := func(queries ...string) (*coquery.Response, coquery.ResponseError) {
+			writer := &spyWriter{
+				Out: make(chan *coquery.Response),
+				Err: make(chan coquery.ResponseError),
+			}
+
+			eos.Serve(context, &data.RequestContext{
+				RequestID: "932UFY",
+				Queries:   queries,
+				Atomic:    true,
+				NoJSON:    true,
+			}, writer)
+
+			select {
+			case res := <-writer.Out:
+				return res, nil
+			case err := <-writer.Err:
+				return nil, err
+			}
+		}
+
+		t.Logf("	When giving a batch whose queries succeed")
+		{
+			res, err := serve("doc.records.create({id:1})", "doc.records.create({id:2})")
+			if err != nil {
+				t.Fatalf("	%s	Should have successfull received a response: %s", tests.Failed, err.Error())
+			}
+			t.Logf("	%s	Should have successfull received a response.", tests.Success)
+
+			if len(res.Data) != 2 {
+				t.Fatalf("	%s	Should have received the responses of both queries: %v", tests.Failed, res.Data)
+			}
+			t.Logf("	%s	Should have received the responses of both queries", tests.Success)
+
+			if !store.Has("1") || !store.Has("2") {
+				t.Fatalf("	%s	Should have kept the created records", tests.Failed)
+			}
+			t.Logf("	%s	Should have kept the created records", tests.Success)
+		}
+
+		t.Logf("	When giving a batch with a failing query")
+		{
+			_, err := serve("doc.records.create({id:3})", "doc.records.create({id:4})", "doc.greetings.findN(1).findN(1)")
+			if err == nil {
+				t.Fatalf("	%s	Should have failed the batch", tests.Failed)
+			}
+			t.Logf("	%s	Should have failed the batch: %s", tests.Success, err)
+
+			if store.Has("3") || store.Has("4") {
+				t.Fatalf("	%s	Should have rolled back the created records", tests.Failed)
+			}
+			t.Logf("	%s	Should have rolled back the created records", tests.Success)
+
+			if !store.Has("1") || !store.Has("2") {
+				t.Fatalf("	%s	Should have kept the records of the earlier batch", tests.Failed)
+			}
+			t.Logf("	%s	Should have kept the records of the earlier batch", tests.Success)
+		}
+
+		t.Logf("	When giving a batch whose query changes records after timing out")
+		{
+			writer := &spyWriter{
+				Out: make(chan *coquery.Response),
+				Err: make(chan coquery.ResponseError),
+			}
+
+			ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			rctx := &data.RequestContext{
+				RequestID: "932UFZ",
+				Queries:   []string{"doc.late.create({id:5})", "doc.records.create({id:6})"},
+				Atomic:    true,
+				NoJSON:    true,
+			}
+
+			go eos.Serve(context, rctx.WithContext(ctx), writer)
+
+			select {
+			case <-writer.Out:
+				t.Fatalf("	%s	Should have failed the batch", tests.Failed)
+			case err := <-writer.Err:
+				t.Logf("	%s	Should have failed the batch: %s", tests.Success, err)
+			}
+
+			close(late.release)
+			<-late.done
+
+			if store.Has("5") {
+				t.Fatalf("	%s	Should have undone the record created after the rollback", tests.Failed)
+			}
+			t.Logf("	%s	Should have undone the record created after the rollback", tests.Success)
+		}
+	}
+}
+
+// laggard provides a ContextDocument which abandons its requests once their
+// context is done, creating their records only once released as processors
+// finishing after their batch do.
+type laggard struct {
+	store   storage.Store
+	release chan struct{}
+	done    chan struct{}
+}
+
+// Handle serves the requests without a context.
+func (l *laggard) Handle(context interface{}, reqs coquery.RecordRequests, res coquery.ResponseWriter) {
+	l.HandleContext(gocontext.Background(), context, reqs, res)
+}
+
+// HandleContext replies the error of the context once done, leaving the
+// records to be created when released.
+func (l *laggard) HandleContext(ctx gocontext.Context, context interface{}, reqs coquery.RecordRequests, res coquery.ResponseWriter) {
+	create := reqs[0].(*coquery.Create)
+
+	go func() {
+		defer close(l.done)
+		<-l.release
+
+		for _, record := range create.Records {
+			rec := map[string]interface{}(record)
+			l.store.Add(rec)
+
+			create.Journal.Record(func() error {
+				return l.store.Remove(rec)
+			})
+		}
+	}()
+
+	<-ctx.Done()
+	res.Write(context, nil, &coquery.CoError{Rid: create.RequestID(), Msg: "Cancelled", IError: ctx.Err()})
+}
+
+// TestEngineDescribe validates the description of the routes served by a
+// coquery.Engine.
+func TestEngineDescribe(t *testing.T) {
+	t.Logf("Given the need to discover the routes served by a coquery.Engine")
+	{
+		store := storage.New("id")
+		eos := coquery.New(events, coquery.NewDiffs(events), store)
+
+		eos.Route(context, "doc").
+			Document(context, "greetings", &coquery.BasicQueries{EventLog: events, Store: store}, &inMemory{}).
+			Document(context, "records", &coquery.BasicQueries{EventLog: events, Store: storage.New("uid")}, &inMemory{})
+
+		eos.Route(context, "api")
+
+		info := eos.Describe(context)
+
+		if len(info.Routes) != 2 || info.Routes[0].Root != "api" || info.Routes[1].Root != "doc" {
+			t.Fatalf("\t%s\tShould have described the api and doc routes: %+v", tests.Failed, info.Routes)
+		}
+		t.Logf("\t%s\tShould have described the api and doc routes", tests.Success)
+
+		docs := info.Routes[1].Documents
+		if len(docs) != 2 || docs[0].Name != "greetings" || docs[1].Name != "records" {
+			t.Fatalf("\t%s\tShould have described the greetings and records documents: %+v", tests.Failed, docs)
+		}
+		t.Logf("\t%s\tShould have described the greetings and records documents", tests.Success)
+
+		if docs[0].Key != "id" || docs[1].Key != "uid" {
+			t.Fatalf("\t%s\tShould have described the record keys of the documents: %q %q", tests.Failed, docs[0].Key, docs[1].Key)
+		}
+		t.Logf("\t%s\tShould have described the record keys of the documents", tests.Success)
+
+		var page *coquery.MethodInfo
+		for index, method := range docs[0].Methods {
+			if method.Name == "page" {
+				page = &docs[0].Methods[index]
+			}
+		}
+
+		if page == nil {
+			t.Fatalf("\t%s\tShould have described the page method", tests.Failed)
+		}
+		t.Logf("\t%s\tShould have described the page method", tests.Success)
+
+		if page.Usage != "page(size, [cursor])" || len(page.Examples) == 0 {
+			t.Fatalf("\t%s\tShould have described the usage and examples of page: %+v", tests.Failed, page)
+		}
+		t.Logf("\t%s\tShould have described the usage and examples of page", tests.Success)
+	}
+}
+
+// TestDocumentSchema validates the validation of created records against the
+// schema of their document.
+func TestDocumentSchema(t *testing.T) {
+	t.Logf("Given the need to validate created records against a schema")
+	{
+		store := storage.New("id")
+		eos := coquery.New(events, coquery.NewDiffs(events), store)
+
+		eos.Route(context, "doc").
+			Document(context, "records", &coquery.BasicQueries{EventLog: events, Store: store}, &creator{store: store}).
+			Schema(context, "records", &storage.Schema{
+				Fields: []storage.Field{
+					{Name: "id", Type: storage.TypeInteger, Required: true},
+					{Name: "name", Type: storage.TypeString, Required: true},
+					{Name: "role", Type: storage.TypeString, Default: "user"},
+				},
+			})
+
+		serve := func(query string) (*coquery.Response, coquery.ResponseError) {
+			writer := &spyWriter{
+				Out: make(chan *coquery.Response),
+				Err: make(chan coquery.ResponseError),
+			}
+
+			eos.Serve(context, &data.RequestContext{
+				RequestID: "732UFY",
+				Queries:   []string{query},
+				NoJSON:    true,
+			}, writer)
+
+			select {
+			case res := <-writer.Out:
+				return res, nil
+			case err := <-writer.Err:
+				return nil, err
+			}
+		}
+
+		query := "doc.records.create({id:1, name:'alex'})"
+		t.Logf("\tWhen giving the valid query %q", query)
+		{
+			res, err := serve(query)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have created the record: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have created the record", tests.Success)
+
+			if res.Data[0].Get("role") != "user" {
+				t.Fatalf("\t%s\tShould have set the default role: %#v", tests.Failed, res.Data[0])
+			}
+			t.Logf("\t%s\tShould have set the default role", tests.Success)
+		}
+
+		query = "doc.records.create({id:2, name:3})"
+		t.Logf("\tWhen giving the invalid query %q", query)
+		{
+			_, err := serve(query)
+
+			invalid, ok := err.(*coquery.ValidationError)
+			if !ok {
+				t.Fatalf("\t%s\tShould have failed with a validation error: %#v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have failed with a validation error: %s", tests.Success, err)
+
+			if len(invalid.Fields) != 1 || invalid.Fields[0].Field != "name" {
+				t.Fatalf("\t%s\tShould have failed the name field: %s", tests.Failed, invalid.Fields)
+			}
+			t.Logf("\t%s\tShould have failed the name field", tests.Success)
+
+			if store.Has("2") {
+				t.Fatalf("\t%s\tShould not have created the record", tests.Failed)
+			}
+			t.Logf("\t%s\tShould not have created the record", tests.Success)
+		}
+	}
+}
+
+// waiter provides a ContextDocument which replies once the context of its
+// requests is done.
+type waiter struct{}
+
+// Handle replies the requests served without a context.
+func (w *waiter) Handle(context interface{}, reqs coquery.RecordRequests, res coquery.ResponseWriter) {
+	res.Write(context, &coquery.Response{Req: reqs[0]}, nil)
+}
+
+// HandleContext waits for the context to be done, replying its error.
+func (w *waiter) HandleContext(ctx gocontext.Context, context interface{}, reqs coquery.RecordRequests, res coquery.ResponseWriter) {
+	<-ctx.Done()
+	res.Write(context, nil, &coquery.CoError{Rid: reqs[0].RequestID(), Msg: "Cancelled", IError: ctx.Err()})
+}
+
+// TestRequestContext validates the cancellation of requests by their context.
+func TestRequestContext(t *testing.T) {
+	t.Logf("Given the need to abandon requests once their context is done")
+	{
+		store := storage.New("id")
+		eos := coquery.New(events, coquery.NewDiffs(events), store)
+
+		eos.Route(context, "doc").
+			Document(context, "waits", &coquery.BasicQueries{EventLog: events, Store: store}, &waiter{})
+
+		serve := func(ctx gocontext.Context) coquery.ResponseError {
+			writer := &spyWriter{
+				Out: make(chan *coquery.Response),
+				Err: make(chan coquery.ResponseError),
+			}
+
+			rctx := &data.RequestContext{
+				RequestID: "632UFY",
+				Queries:   []string{"doc.waits.findN(1)"},
+				NoJSON:    true,
+			}
+
+			go eos.Serve(context, rctx.WithContext(ctx), writer)
+
+			select {
+			case <-writer.Out:
+				return nil
+			case err := <-writer.Err:
+				return err
+			case <-time.After(time.Second):
+				t.Fatalf("\t%s\tShould have received a reply before the context was done", tests.Failed)
+			}
+
+			return nil
+		}
+
+		t.Logf("\tWhen giving a cancelled context")
+		{
+			ctx, cancel := gocontext.WithCancel(gocontext.Background())
+			cancel()
+
+			err := serve(ctx)
+			if cerr, ok := err.(*coquery.CoError); !ok || cerr.IError != gocontext.Canceled {
+				t.Fatalf("\t%s\tShould have failed with a cancelled error: %#v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have failed with a cancelled error: %s", tests.Success, err)
+		}
+
+		t.Logf("\tWhen giving a context with a deadline")
+		{
+			ctx, cancel := gocontext.WithTimeout(gocontext.Background(), 20*time.Millisecond)
+			defer cancel()
+
+			err := serve(ctx)
+			if cerr, ok := err.(*coquery.CoError); !ok || cerr.IError != gocontext.DeadlineExceeded {
+				t.Fatalf("\t%s\tShould have failed with a deadline error: %#v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have failed with a deadline error: %s", tests.Success, err)
+		}
 	}
 }
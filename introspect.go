@@ -0,0 +1,165 @@
+package coquery
+
+import (
+	"sort"
+	"sync/atomic"
+
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+// MethodInfo describes a query method supported by a document.
+type MethodInfo struct {
+	Name     string   `json:"name"`
+	Usage    string   `json:"usage"`
+	Args     []Arg    `json:"args,omitempty"`
+	Named    []Arg    `json:"named,omitempty"`
+	Examples []string `json:"examples,omitempty"`
+	Write    bool     `json:"write,omitempty"`
+}
+
+// DocumentInfo describes a document registered within a route, its record
+// key, the schema of its records and the query methods it supports.
+type DocumentInfo struct {
+	Name    string          `json:"name"`
+	Key     string          `json:"key,omitempty"`
+	Schema  *storage.Schema `json:"schema,omitempty"`
+	Methods []MethodInfo    `json:"methods"`
+}
+
+// RouteInfo describes a root route eg docs and its documents.
+type RouteInfo struct {
+	Root      string         `json:"root"`
+	Documents []DocumentInfo `json:"documents"`
+}
+
+// EngineInfo describes the routes served by a Engine, allowing clients to
+// discover the documents and query methods available to them.
+type EngineInfo struct {
+	Routes []RouteInfo `json:"routes"`
+}
+
+// QueryDescriber defines a interface for QueryProcessors which describe the
+// record key and query methods of the documents they generate requests for.
+type QueryDescriber interface {
+	Describe(context interface{}) DocumentInfo
+}
+
+// NewMethodInfo returns the MethodInfo of the giving method.
+func NewMethodInfo(m Method) MethodInfo {
+	return MethodInfo{
+		Name:     m.Name,
+		Usage:    m.Usage(),
+		Args:     m.Args,
+		Named:    m.Named,
+		Examples: m.Examples,
+		Write:    m.Write,
+	}
+}
+
+//==============================================================================
+
+// Describe returns the record key of the store and the query methods of the
+// Methods registry, else of DefaultMethods.
+func (b *BasicQueries) Describe(context interface{}) DocumentInfo {
+	b.Log(context, "BasicQueries.Describe", "Started : Doc[%s]", b.Doc)
+
+	methods := b.Methods
+	if methods == nil {
+		methods = DefaultMethods
+	}
+
+	var ds DocumentInfo
+
+	if b.Store != nil {
+		ds.Key = b.Key()
+	}
+
+	for _, m := range methods.Methods() {
+		ds.Methods = append(ds.Methods, NewMethodInfo(m))
+	}
+
+	b.Log(context, "BasicQueries.Describe", "Completed")
+	return ds
+}
+
+// Describe returns the descriptions of the documents registered with the route
+// ordered by name. Documents whose QueryProcessor is not a QueryDescriber are
+// listed by name alone.
+func (d *DocRoute) Describe(context interface{}) []DocumentInfo {
+	d.Log(context, "Describe", "Started")
+
+	sets := make(map[string]*docSet)
+	schemas := make(map[string]*storage.Schema)
+
+	atomic.AddInt64(&d.docAdd, 1)
+	{
+		for name, set := range d.documents {
+			sets[name] = set
+			schemas[name] = d.schemas[name]
+		}
+	}
+	atomic.AddInt64(&d.docAdd, -1)
+
+	var names []string
+	for name := range sets {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	var docs []DocumentInfo
+
+	for _, name := range names {
+		var ds DocumentInfo
+
+		if qd, ok := sets[name].query.(QueryDescriber); ok {
+			ds = qd.Describe(context)
+		}
+
+		ds.Name = name
+		ds.Schema = schemas[name]
+		docs = append(docs, ds)
+	}
+
+	d.Log(context, "Describe", "Completed")
+	return docs
+}
+
+// Describe returns the description of the routes registered with the engine
+// ordered by their root.
+func (co *CoEngine) Describe(context interface{}) *EngineInfo {
+	co.Log(context, "Describe", "Started")
+
+	routers := make(map[string]DocumentRouter)
+
+	atomic.AddInt64(&co.routeAdd, 1)
+	{
+		for root, router := range co.routers {
+			routers[root] = router
+		}
+	}
+	atomic.AddInt64(&co.routeAdd, -1)
+
+	var roots []string
+	for root := range routers {
+		roots = append(roots, root)
+	}
+
+	sort.Strings(roots)
+
+	var info EngineInfo
+
+	for _, root := range roots {
+		info.Routes = append(info.Routes, RouteInfo{
+			Root:      root,
+			Documents: routers[root].Describe(context),
+		})
+	}
+
+	co.Log(context, "Describe", "Completed")
+	return &info
+}
+
+//==============================================================================
@@ -0,0 +1,74 @@
+package coquery
+
+import "sync"
+
+//==============================================================================
+
+// Undo defines a function which reverses a change made while serving a
+// request.
+type Undo func() error
+
+// Journal records the undo functions of the changes made by the queries of an
+// atomic batch (see data.RequestContext.Atomic), allowing the changes to be
+// compensated in reverse order when any query of the batch fails.
+type Journal struct {
+	ml     sync.Mutex
+	undos  []Undo
+	closed bool
+}
+
+// Record adds the undo function of a change. Calls on a nil Journal are
+// ignored, so requests served outside of atomic batches can record freely.
+// Changes recorded once the journal is rolled back, eg by processors which
+// finished after their batch was abandoned, are undone right away.
+func (j *Journal) Record(undo Undo) {
+	if j == nil {
+		return
+	}
+
+	j.ml.Lock()
+	if !j.closed {
+		j.undos = append(j.undos, undo)
+		j.ml.Unlock()
+		return
+	}
+	j.ml.Unlock()
+
+	undo()
+}
+
+// Rollback runs the recorded undo functions in reverse order, returning the
+// first error met while still attempting the rest. The journal is closed,
+// so changes recorded after are undone as they are recorded.
+func (j *Journal) Rollback() error {
+	j.ml.Lock()
+	undos := j.undos
+	j.undos = nil
+	j.closed = true
+	j.ml.Unlock()
+
+	var first error
+
+	for index := len(undos) - 1; index >= 0; index-- {
+		if err := undos[index](); err != nil && first == nil {
+			first = err
+		}
+	}
+
+	return first
+}
+
+// JournalUser defines a interface for requests which change records and
+// record how to undo those changes within the journal of an atomic batch.
+type JournalUser interface {
+	UseJournal(*Journal)
+}
+
+// JournalWriter defines a ResponseWriter for the queries of an atomic batch,
+// providing the Journal their requests record their changes into.
+type JournalWriter interface {
+	ResponseWriter
+	Journal() *Journal
+}
+
+//==============================================================================
@@ -0,0 +1,178 @@
+package coquery
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/influx6/coquery/parser"
+)
+
+//==============================================================================
+
+// contains the names of the limits of Limits, reported by LimitError.
+const (
+	LimitRecords = "MaxRecords"
+	LimitDepth   = "MaxDepth"
+	LimitBatch   = "MaxBatch"
+	LimitBytes   = "MaxBytes"
+)
+
+// Limits defines the cost limits of queries, where a zero value leaves its
+// limit unset. MaxRecords limits the records a query may request eg
+// findN(100) or page(100) and reply, rejecting findN(-1). MaxDepth limits the
+// methods chained by a query, MaxBatch the queries of a request and MaxBytes
+// the JSON encoded size of the records replied for a query.
+type Limits struct {
+	MaxRecords int `json:"max_records,omitempty"`
+	MaxDepth   int `json:"max_depth,omitempty"`
+	MaxBatch   int `json:"max_batch,omitempty"`
+	MaxBytes   int `json:"max_bytes,omitempty"`
+}
+
+// LimitError is returned when a query or its response exceeds a limit, Limit
+// names the limit (eg MaxRecords) and Max its value.
+type LimitError struct {
+	Rid   string `json:"rid" bson:"rid"`
+	Msg   string `json:"message" bson:"message"`
+	Limit string `json:"limit" bson:"limit"`
+	Max   int    `json:"max" bson:"max"`
+}
+
+// Message returns the internal message for this error
+func (r *LimitError) Message() string {
+	return r.Msg
+}
+
+// RequestID returns the response error requestID
+func (r *LimitError) RequestID() string {
+	return r.Rid
+}
+
+// Error returns the error message for this response error.
+func (r *LimitError) Error() string {
+	return fmt.Sprintf("%s : Limit[%s] Of %d Exceeded : %s", r.Rid, r.Limit, r.Max, r.Msg)
+}
+
+//==============================================================================
+
+// CheckQuery returns a *LimitError if the query's method calls exceed
+// MaxDepth.
+func (l *Limits) CheckQuery(rid string, calls []*parser.Call) ResponseError {
+	if l == nil || l.MaxDepth <= 0 || len(calls) <= l.MaxDepth {
+		return nil
+	}
+
+	return &LimitError{
+		Rid:   rid,
+		Msg:   fmt.Sprintf("query chains %d methods", len(calls)),
+		Limit: LimitDepth,
+		Max:   l.MaxDepth,
+	}
+}
+
+// CheckRequests returns a *LimitError if the requests ask for more records
+// than MaxRecords, including those asking for all records eg findN(-1).
+func (l *Limits) CheckRequests(rid string, reqs RecordRequests) ResponseError {
+	if l == nil || l.MaxRecords <= 0 {
+		return nil
+	}
+
+	for _, req := range reqs {
+		var msg string
+
+		switch rq := req.(type) {
+		case *FindN:
+			if rq.Amount < 0 {
+				msg = "findN(-1) requests all records"
+			} else if rq.Amount > l.MaxRecords {
+				msg = fmt.Sprintf("findN(%d) requests too many records", rq.Amount)
+			}
+		case *Page:
+			if rq.Size > l.MaxRecords {
+				msg = fmt.Sprintf("page(%d) requests too many records", rq.Size)
+			}
+		}
+
+		if msg != "" {
+			return &LimitError{
+				Rid:   rid,
+				Msg:   msg,
+				Limit: LimitRecords,
+				Max:   l.MaxRecords,
+			}
+		}
+	}
+
+	return nil
+}
+
+// CheckBatch returns a *LimitError if the request has more queries than
+// MaxBatch.
+func (l *Limits) CheckBatch(rid string, queries []string) ResponseError {
+	if l == nil || l.MaxBatch <= 0 || len(queries) <= l.MaxBatch {
+		return nil
+	}
+
+	return &LimitError{
+		Rid:   rid,
+		Msg:   fmt.Sprintf("request has %d queries", len(queries)),
+		Limit: LimitBatch,
+		Max:   l.MaxBatch,
+	}
+}
+
+// CheckResponse returns a *LimitError if the response has more records than
+// MaxRecords or they encode to more than MaxBytes of JSON.
+func (l *Limits) CheckResponse(rid string, res *Response) ResponseError {
+	if l == nil || res == nil {
+		return nil
+	}
+
+	if l.MaxRecords > 0 && len(res.Data) > l.MaxRecords {
+		return &LimitError{
+			Rid:   rid,
+			Msg:   fmt.Sprintf("response has %d records, narrow the query eg with page(%d)", len(res.Data), l.MaxRecords),
+			Limit: LimitRecords,
+			Max:   l.MaxRecords,
+		}
+	}
+
+	if l.MaxBytes <= 0 {
+		return nil
+	}
+
+	encoded, err := json.Marshal(res.Data)
+	if err != nil || len(encoded) <= l.MaxBytes {
+		return nil
+	}
+
+	return &LimitError{
+		Rid:   rid,
+		Msg:   fmt.Sprintf("response has %d bytes", len(encoded)),
+		Limit: LimitBytes,
+		Max:   l.MaxBytes,
+	}
+}
+
+//==============================================================================
+
+// limitingWriter provides a ResponseWriter which replaces the responses
+// exceeding its limits with the *LimitError they hit.
+type limitingWriter struct {
+	ResponseWriter
+	rid    string
+	limits *Limits
+}
+
+// Write writes the response if it is within the limits, else the error.
+func (l *limitingWriter) Write(context interface{}, res *Response, err ResponseError) error {
+	if err == nil {
+		if lerr := l.limits.CheckResponse(l.rid, res); lerr != nil {
+			return l.ResponseWriter.Write(context, nil, lerr)
+		}
+	}
+
+	return l.ResponseWriter.Write(context, res, err)
+}
+
+//==============================================================================
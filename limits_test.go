@@ -0,0 +1,108 @@
+package coquery_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+// TestLimits validates the enforcement of the cost limits of the engine, its
+// routes and documents.
+func TestLimits(t *testing.T) {
+	t.Logf("Given the need to limit the cost of queries")
+	{
+		store := storage.New("id")
+		eos := coquery.New(events, coquery.NewDiffs(events), store).
+			UseLimits(context, coquery.Limits{MaxBatch: 2})
+
+		eos.Route(context, "doc").
+			Document(context, "greetings", &coquery.BasicQueries{
+				EventLog: events,
+				Store:    store,
+				Limits:   &coquery.Limits{MaxRecords: 10},
+			}, &inMemory{}).
+			UseLimits(context, coquery.Limits{MaxDepth: 2})
+
+		eos.Route(context, "small").
+			Document(context, "greetings", &coquery.BasicQueries{EventLog: events, Store: store}, &inMemory{}).
+			UseLimits(context, coquery.Limits{MaxBytes: 10})
+
+		serve := func(queries ...string) (*coquery.Response, coquery.ResponseError) {
+			writer := &spyWriter{
+				Out: make(chan *coquery.Response),
+				Err: make(chan coquery.ResponseError),
+			}
+
+			eos.Serve(context, &data.RequestContext{
+				RequestID: "532UFY",
+				Queries:   queries,
+				NoJSON:    true,
+			}, writer)
+
+			select {
+			case res := <-writer.Out:
+				return res, nil
+			case err := <-writer.Err:
+				return nil, err
+			}
+		}
+
+		expectLimit := func(err coquery.ResponseError, limit string) {
+			lerr, ok := err.(*coquery.LimitError)
+			if !ok {
+				t.Fatalf("\t%s\tShould have failed with a *LimitError: %#v", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have failed with a *LimitError.", tests.Success)
+
+			if lerr.Limit != limit {
+				t.Fatalf("\t%s\tShould have hit the %s limit: %s", tests.Failed, limit, lerr.Limit)
+			}
+			t.Logf("\t%s\tShould have hit the %s limit: %s", tests.Success, limit, lerr)
+		}
+
+		t.Logf("\tWhen requesting all records of a document limiting its records")
+		{
+			_, err := serve("doc.greetings.findN(-1)")
+			expectLimit(err, coquery.LimitRecords)
+		}
+
+		t.Logf("\tWhen requesting records within the document's limit")
+		{
+			res, err := serve("doc.greetings.findN(5)")
+			if err != nil {
+				t.Fatalf("\t%s\tShould have served the query: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have served the query.", tests.Success)
+
+			if len(res.Data) != 1 {
+				t.Fatalf("\t%s\tShould have replied a record: %d", tests.Failed, len(res.Data))
+			}
+			t.Logf("\t%s\tShould have replied a record.", tests.Success)
+		}
+
+		t.Logf("\tWhen chaining more methods than the route allows")
+		{
+			_, err := serve("doc.greetings.find(id,1).sort(id).findN(2)")
+			expectLimit(err, coquery.LimitDepth)
+		}
+
+		t.Logf("\tWhen batching more queries than the engine allows")
+		{
+			_, err := serve("doc.greetings.find(id,1)", "doc.greetings.find(id,2)", "doc.greetings.find(id,3)")
+			expectLimit(err, coquery.LimitBatch)
+		}
+
+		t.Logf("\tWhen replying more bytes than the route allows")
+		{
+			_, err := serve("small.greetings.find(id,1)")
+			expectLimit(err, coquery.LimitBytes)
+		}
+	}
+}
+
+//==============================================================================
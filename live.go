@@ -0,0 +1,343 @@
+package coquery
+
+import (
+	gocontext "context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/parser"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+// LiveTimeout sets the time a live query is given to reply each time it is
+// run.
+var LiveTimeout = 30 * time.Second
+
+// ErrLiveQuery is held by the *CoError returned for requests which can not be
+// served live eg batches and queries changing records.
+var ErrLiveQuery = errors.New("Invalid Live Query")
+
+// LiveWriter defines the function the results of a live query are pushed to,
+// first when subscribed and then each time its results change.
+type LiveWriter func(res *Response, err ResponseError)
+
+// LiveQueries provides live queries, which are run once when subscribed and
+// again whenever any record they replied is changed, as recorded by the
+// engine's diffs, pushing their results to the subscriber when they differ.
+// Records entering the results of a query without a record it replied
+// changing are picked up on its next run.
+type LiveQueries struct {
+	EventLog
+	engine  Engine
+	store   storage.Store
+	ml      sync.Mutex
+	queries map[string]*liveQuery
+	unwatch func()
+}
+
+// NewLiveQueries returns a new instance of LiveQueries serving queries with
+// the engine, which records the changes of the store in the diffs.
+func NewLiveQueries(el EventLog, engine Engine, diff DiffWatcher, store storage.Store) *LiveQueries {
+	lq := LiveQueries{
+		EventLog: el,
+		engine:   engine,
+		store:    store,
+		queries:  make(map[string]*liveQuery),
+	}
+
+	lq.unwatch = diff.Watch(lq.changed)
+	return &lq
+}
+
+// liveQuery defines a subscribed query, the record keys it last replied and
+// the signal to run it again.
+type liveQuery struct {
+	rctx    *data.RequestContext
+	push    LiveWriter
+	stop    gocontext.CancelFunc
+	run     chan struct{}
+	tl      sync.Mutex
+	touched map[string]bool
+}
+
+// Subscribe subscribes to the single query of the request, its RequestID
+// identifying the subscription, pushing its results to the writer until
+// unsubscribed or the request's context is done. A subscription of the same
+// id is replaced.
+func (l *LiveQueries) Subscribe(context interface{}, rctx *data.RequestContext, push LiveWriter) ResponseError {
+	l.Log(context, "Subscribe", "Started : Request ID[%s] : Queries %s", rctx.RequestID, rctx.Queries)
+
+	if len(rctx.Queries) != 1 {
+		err := &CoError{
+			Rid:    rctx.RequestID,
+			Msg:    fmt.Sprintf("Live Queries Require A Single Query, Found %d", len(rctx.Queries)),
+			IError: ErrLiveQuery,
+		}
+
+		l.Error(context, "Subscribe", err, "Completed")
+		return err
+	}
+
+	if name, ok := l.writes(context, rctx.Queries[0]); ok {
+		err := &CoError{
+			Rid:    rctx.RequestID,
+			Msg:    fmt.Sprintf("Live Queries Can Not Change Records, Found %s", name),
+			IError: ErrLiveQuery,
+		}
+
+		l.Error(context, "Subscribe", err, "Completed")
+		return err
+	}
+
+	ctx, stop := gocontext.WithCancel(rctx.Context())
+
+	cp := *rctx.WithContext(ctx)
+	cp.NoJSON = true
+	cp.Atomic = false
+
+	lq := &liveQuery{
+		rctx:    &cp,
+		push:    push,
+		stop:    stop,
+		run:     make(chan struct{}, 1),
+		touched: make(map[string]bool),
+	}
+
+	l.ml.Lock()
+	{
+		if old, ok := l.queries[rctx.RequestID]; ok {
+			old.stop()
+		}
+
+		l.queries[rctx.RequestID] = lq
+	}
+	l.ml.Unlock()
+
+	lq.run <- struct{}{}
+	go l.serve(context, lq)
+
+	l.Log(context, "Subscribe", "Completed")
+	return nil
+}
+
+// Unsubscribe ends the live query of the giving request id.
+func (l *LiveQueries) Unsubscribe(context interface{}, requestID string) {
+	l.Log(context, "Unsubscribe", "Started : Request ID[%s]", requestID)
+
+	l.ml.Lock()
+	{
+		if lq, ok := l.queries[requestID]; ok {
+			lq.stop()
+			delete(l.queries, requestID)
+		}
+	}
+	l.ml.Unlock()
+
+	l.Log(context, "Unsubscribe", "Completed")
+}
+
+// Close ends all live queries and stops watching the diffs.
+func (l *LiveQueries) Close() {
+	l.unwatch()
+
+	l.ml.Lock()
+	defer l.ml.Unlock()
+
+	for id, lq := range l.queries {
+		lq.stop()
+		delete(l.queries, id)
+	}
+}
+
+// changed signals the live queries which replied any of the changed records
+// to run again.
+func (l *LiveQueries) changed(id string, records []string) {
+	l.ml.Lock()
+	defer l.ml.Unlock()
+
+	for _, lq := range l.queries {
+		if !lq.replied(records) {
+			continue
+		}
+
+		select {
+		case lq.run <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// serve runs the live query each time it is signalled, pushing its results
+// when they differ from those pushed last, until its context is done.
+func (l *LiveQueries) serve(context interface{}, lq *liveQuery) {
+	ctx := lq.rctx.Context()
+
+	var last *Response
+
+	defer func() {
+		l.ml.Lock()
+		if l.queries[lq.rctx.RequestID] == lq {
+			delete(l.queries, lq.rctx.RequestID)
+		}
+		l.ml.Unlock()
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-lq.run:
+		}
+
+		res, err := l.query(context, lq.rctx)
+		if ctx.Err() != nil {
+			return
+		}
+
+		if err != nil {
+			lq.push(nil, err)
+			continue
+		}
+
+		lq.touch(l.store.Key(), res.Data)
+
+		if last != nil && reflect.DeepEqual(last.Data, res.Data) {
+			continue
+		}
+
+		last = res
+		lq.push(res, nil)
+	}
+}
+
+// query runs the live query with the engine, returning its response.
+func (l *LiveQueries) query(context interface{}, rctx *data.RequestContext) (*Response, ResponseError) {
+	rw := liveResponse{done: make(chan struct{})}
+
+	l.engine.Serve(context, rctx, &rw)
+
+	select {
+	case <-rw.done:
+		return rw.res, rw.err
+	case <-rctx.Context().Done():
+		return nil, &CoError{Rid: rctx.RequestID, Msg: "Live Query Cancelled", IError: rctx.Context().Err()}
+	case <-time.After(LiveTimeout):
+		return nil, &CoError{Rid: rctx.RequestID, Msg: "Live Query Timed Out", IError: ErrLiveQuery}
+	}
+}
+
+//==============================================================================
+
+// replied returns true/false if the live query replied any of the records.
+func (lq *liveQuery) replied(records []string) bool {
+	lq.tl.Lock()
+	defer lq.tl.Unlock()
+
+	for _, key := range records {
+		if lq.touched[key] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// touch sets the records the live query replied, keyed by the record key.
+func (lq *liveQuery) touch(key string, records data.Parameters) {
+	touched := make(map[string]bool)
+
+	for _, rec := range records {
+		if value, ok := rec[key]; ok {
+			touched[fmt.Sprintf("%+v", value)] = true
+		}
+	}
+
+	lq.tl.Lock()
+	lq.touched = touched
+	lq.tl.Unlock()
+}
+
+//==============================================================================
+
+// liveResponse provides a ResponseWriter capturing the first response of a
+// live query run.
+type liveResponse struct {
+	once sync.Once
+	done chan struct{}
+	res  *Response
+	err  ResponseError
+}
+
+// Write captures the response, ignoring those written after it.
+func (l *liveResponse) Write(context interface{}, res *Response, err ResponseError) error {
+	l.once.Do(func() {
+		l.res = res
+		l.err = err
+		close(l.done)
+	})
+
+	return nil
+}
+
+// writes returns the name of the first method of the query which changes
+// records, if any. Queries failing to parse are left to the engine to reject.
+func (l *LiveQueries) writes(context interface{}, query string) (string, bool) {
+	q, err := parser.Parse(query)
+	if err != nil || len(q.Path) < 2 {
+		return "", false
+	}
+
+	writes := l.writeMethods(context, q.Path[0].Name, q.Path[1].Name)
+
+	for _, call := range q.Calls {
+		if writes[strings.ToLower(call.Name)] {
+			return call.Name, true
+		}
+	}
+
+	return "", false
+}
+
+// writeMethods returns the lower cased names of the methods flagged as Write
+// by the engine's description of the document, else by DefaultMethods for
+// documents not describing their methods.
+func (l *LiveQueries) writeMethods(context interface{}, root string, doc string) map[string]bool {
+	writes := make(map[string]bool)
+
+	for _, route := range l.engine.Describe(context).Routes {
+		if route.Root != root {
+			continue
+		}
+
+		for _, ds := range route.Documents {
+			if ds.Name != doc || len(ds.Methods) == 0 {
+				continue
+			}
+
+			for _, m := range ds.Methods {
+				if m.Write {
+					writes[strings.ToLower(m.Name)] = true
+				}
+			}
+
+			return writes
+		}
+	}
+
+	for _, m := range DefaultMethods.Methods() {
+		if m.Write {
+			writes[strings.ToLower(m.Name)] = true
+		}
+	}
+
+	return writes
+}
+
+//==============================================================================
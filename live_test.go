@@ -0,0 +1,161 @@
+package coquery_test
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+// counter provides a Document which replies the number of times it was
+// requested.
+type counter struct {
+	hits int64
+}
+
+// Handle replies the record with the count of requests.
+func (c *counter) Handle(context interface{}, reqs coquery.RecordRequests, res coquery.ResponseWriter) {
+	res.Write(context, &coquery.Response{
+		Req:  reqs[0],
+		Data: data.Parameters{{"id": 1, "count": atomic.AddInt64(&c.hits, 1)}},
+	}, nil)
+}
+
+//==============================================================================
+
+// TestLiveQueries validates the pushing of the results of live queries when
+// the records they replied change.
+func TestLiveQueries(t *testing.T) {
+	t.Logf("Given the need to push the results of live queries")
+	{
+		store := storage.New("id")
+		diffs := coquery.NewDiffs(events)
+
+		methods := coquery.NewMethodRegistry()
+		for _, m := range append(coquery.BuiltinMethods(), coquery.Method{
+			Name:  "reset",
+			Write: true,
+			Build: func(call *coquery.MethodCall) (coquery.RecordRequests, error) {
+				return call.Reqs, nil
+			},
+		}) {
+			methods.Register(m)
+		}
+
+		eos := coquery.New(events, diffs, store)
+		eos.Route(context, "doc").
+			Document(context, "counts", &coquery.BasicQueries{EventLog: events, Store: store}, &counter{}).
+			Document(context, "resets", &coquery.BasicQueries{EventLog: events, Store: store, Methods: methods}, &counter{})
+
+		live := coquery.NewLiveQueries(events, eos, diffs, store)
+		defer live.Close()
+
+		pushed := make(chan *coquery.Response, 4)
+
+		push := func(res *coquery.Response, err coquery.ResponseError) {
+			if err != nil {
+				t.Errorf("\t%s\tShould have pushed the results: %s", tests.Failed, err)
+				return
+			}
+
+			pushed <- res
+		}
+
+		expect := func(count int64) {
+			select {
+			case res := <-pushed:
+				if len(res.Data) != 1 || res.Data[0]["count"] != count {
+					t.Fatalf("\t%s\tShould have pushed the count %d: %+v", tests.Failed, count, res.Data)
+				}
+				t.Logf("\t%s\tShould have pushed the count %d", tests.Success, count)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("\t%s\tShould have pushed the count %d", tests.Failed, count)
+			}
+		}
+
+		expectNone := func() {
+			select {
+			case res := <-pushed:
+				t.Fatalf("\t%s\tShould not have pushed the results: %+v", tests.Failed, res.Data)
+			case <-time.After(200 * time.Millisecond):
+				t.Logf("\t%s\tShould not have pushed the results", tests.Success)
+			}
+		}
+
+		t.Logf("\tWhen subscribing to a batch of queries")
+		{
+			err := live.Subscribe(context, &data.RequestContext{
+				RequestID: "batch",
+				Queries:   []string{"doc.counts.find(id,1)", "doc.counts.find(id,2)"},
+			}, push)
+
+			if err == nil {
+				t.Fatalf("\t%s\tShould have refused the batch", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have refused the batch", tests.Success)
+		}
+
+		t.Logf("\tWhen subscribing to queries changing records")
+		{
+			for _, query := range []string{
+				"doc.counts.find(id,1).mutate({count:0})",
+				"doc.counts.create({id:3})",
+				"doc.counts.find(id,1).remove()",
+				"doc.resets.find(id,1).reset()",
+			} {
+				err := live.Subscribe(context, &data.RequestContext{
+					RequestID: "write",
+					Queries:   []string{query},
+				}, push)
+
+				if cerr, ok := err.(*coquery.CoError); !ok || cerr.IError != coquery.ErrLiveQuery {
+					t.Fatalf("\t%s\tShould have refused %q with ErrLiveQuery: %v", tests.Failed, query, err)
+				}
+			}
+			t.Logf("\t%s\tShould have refused the queries with ErrLiveQuery", tests.Success)
+		}
+
+		t.Logf("\tWhen subscribing to a query")
+		{
+			err := live.Subscribe(context, &data.RequestContext{
+				RequestID: "532UFY",
+				Queries:   []string{"doc.counts.find(id,1)"},
+			}, push)
+
+			if err != nil {
+				t.Fatalf("\t%s\tShould have subscribed to the query: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have subscribed to the query", tests.Success)
+
+			expect(1)
+		}
+
+		t.Logf("\tWhen records the query did not reply change")
+		{
+			diffs.Put([]string{"2"})
+			expectNone()
+		}
+
+		t.Logf("\tWhen records the query replied change")
+		{
+			diffs.Put([]string{"1"})
+			expect(2)
+		}
+
+		t.Logf("\tWhen unsubscribed from the query")
+		{
+			live.Unsubscribe(context, "532UFY")
+
+			diffs.Put([]string{"1"})
+			expectNone()
+		}
+	}
+}
+
+//==============================================================================
@@ -0,0 +1,369 @@
+package coquery
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/influx6/coquery/parser"
+)
+
+//==============================================================================
+
+// ArgKind defines the kind of value a query method argument accepts, which
+// decides the go value provided to the method's builder.
+type ArgKind int
+
+// contains the kinds of query method arguments.
+const (
+	ArgNode   ArgKind = iota // any argument, provided as its parser.Node
+	ArgInt                   // an integer, provided as a int
+	ArgNumber                // a integer or floating point number, provided as a float64
+	ArgString                // a quoted string, provided as a string
+	ArgName                  // a key name eg age or 'age', provided as a string
+	ArgValue                 // any literal value, provided as its go value
+	ArgObject                // a object or JSON string, provided as a data.Parameter
+	ArgFilter                // a filter expression eg age > 21, provided as a *Predicate
+	ArgSort                  // a key name or -name, provided as a SortField
+)
+
+// argKinds provides the printable names of the argument kinds.
+var argKinds = map[ArgKind]string{
+	ArgNode:   "argument",
+	ArgInt:    "integer",
+	ArgNumber: "number",
+	ArgString: "string",
+	ArgName:   "key name",
+	ArgValue:  "value",
+	ArgObject: "object",
+	ArgFilter: "filter expression",
+	ArgSort:   "sort key",
+}
+
+// String returns the printable name of the argument kind.
+func (k ArgKind) String() string {
+	return argKinds[k]
+}
+
+// MarshalText returns the printable name of the argument kind, allowing
+// argument kinds to be encoded by name.
+func (k ArgKind) MarshalText() ([]byte, error) {
+	return []byte(k.String()), nil
+}
+
+// Arg defines a argument of a query method. Optional arguments may only be
+// followed by other optional arguments, while a Variadic argument must be the
+// last and accepts any number of values, atleast one unless Optional.
+type Arg struct {
+	Name     string  `json:"name"`
+	Kind     ArgKind `json:"kind"`
+	Optional bool    `json:"optional,omitempty"`
+	Variadic bool    `json:"variadic,omitempty"`
+}
+
+// MethodCall provides a MethodBuilder with the query method call being built
+// and its arguments converted according to the method's Args, along with the
+// requests built from the calls before it.
+type MethodCall struct {
+	*parser.Call
+	RID   string
+	Doc   string
+	Key   string
+	Args  []interface{}
+	Nodes []parser.Node
+	Named map[string]interface{}
+	Reqs  RecordRequests
+}
+
+// Errorf returns a *parser.SyntaxError positioned at the argument of the
+// giving index or at the call itself for an index out of range.
+func (m *MethodCall) Errorf(index int, msg string) error {
+	if index < 0 || index >= len(m.Nodes) {
+		return m.Call.Errorf(nil, msg)
+	}
+
+	return m.Call.Errorf(m.Nodes[index], msg)
+}
+
+// Last returns the request built for the call before this one, if any.
+func (m *MethodCall) Last() RecordRequest {
+	if len(m.Reqs) == 0 {
+		return nil
+	}
+
+	return m.Reqs[len(m.Reqs)-1]
+}
+
+// MethodBuilder builds the requests of a query method call, returning the
+// requests of the query so far with its own added. Builders may fold the
+// requests of earlier calls into theirs eg page(...) absorbs a preceding
+// sort(...).
+type MethodBuilder func(call *MethodCall) (RecordRequests, error)
+
+// Method defines a query method understood by BasicQueries eg find(id,4).
+// Write marks methods which change records eg create(...), whose queries can
+// not be served live.
+type Method struct {
+	Name     string
+	Args     []Arg
+	Named    []Arg
+	Examples []string
+	Write    bool
+	Build    MethodBuilder
+}
+
+// Usage returns the signature of the method eg page(size[, cursor]).
+func (m Method) Usage() string {
+	var args []string
+
+	for _, arg := range m.Args {
+		name := arg.Name
+		if arg.Variadic {
+			name += "..."
+		}
+
+		if arg.Optional && !arg.Variadic {
+			name = "[" + name + "]"
+		}
+
+		args = append(args, name)
+	}
+
+	for _, arg := range m.Named {
+		args = append(args, "["+arg.Name+"=]")
+	}
+
+	return fmt.Sprintf("%s(%s)", m.Name, strings.Join(args, ", "))
+}
+
+// bind validates the arguments of the call against the method's Args and
+// Named arguments, returning the MethodCall to build.
+func (m Method) bind(call *parser.Call) (*MethodCall, error) {
+	mc := MethodCall{
+		Call:  call,
+		Nodes: call.Args,
+		Named: make(map[string]interface{}),
+	}
+
+	var min int
+	max := len(m.Args)
+
+	for _, arg := range m.Args {
+		if !arg.Optional {
+			min++
+		}
+
+		if arg.Variadic {
+			max = -1
+		}
+	}
+
+	if len(call.Args) < min || (max > -1 && len(call.Args) > max) {
+		index := -1
+		if max > -1 && len(call.Args) > max {
+			index = max
+		}
+
+		return nil, mc.Errorf(index, m.describe(fmt.Sprintf("expected %s", m.Usage())))
+	}
+
+	for index, node := range call.Args {
+		arg := m.Args[len(m.Args)-1]
+		if index < len(m.Args) {
+			arg = m.Args[index]
+		}
+
+		val, err := convertArg(call, arg, node)
+		if err != nil {
+			return nil, err
+		}
+
+		mc.Args = append(mc.Args, val)
+	}
+
+	for _, named := range call.Named {
+		var spec *Arg
+
+		for index := range m.Named {
+			if m.Named[index].Name == named.Name {
+				spec = &m.Named[index]
+				break
+			}
+		}
+
+		if spec == nil {
+			return nil, call.Errorf(named, m.describe(fmt.Sprintf("unknown argument %q, expected %s", named.Name, m.Usage())))
+		}
+
+		val, err := convertArg(call, *spec, named.Value)
+		if err != nil {
+			return nil, err
+		}
+
+		mc.Named[named.Name] = val
+	}
+
+	return &mc, nil
+}
+
+// describe returns the message followed by the examples of the method.
+func (m Method) describe(msg string) string {
+	if len(m.Examples) == 0 {
+		return msg
+	}
+
+	return fmt.Sprintf("%s eg %s", msg, strings.Join(m.Examples, " or "))
+}
+
+// convertArg returns the go value of the argument node for its kind, else a
+// *parser.SyntaxError naming the expected kind and wrapping the error of the
+// conversion, whose position it takes when it is a syntax error itself.
+func convertArg(call *parser.Call, arg Arg, node parser.Node) (interface{}, error) {
+	var val interface{}
+	var err error
+
+	switch arg.Kind {
+	case ArgNode:
+		return node, nil
+	case ArgInt:
+		val, err = intArg(call, node)
+	case ArgNumber:
+		num, ok := node.(*parser.NumberLit)
+		if !ok {
+			err = errors.New("not a number")
+			break
+		}
+
+		val = num.Float
+		if num.IsInt {
+			val = float64(num.Int)
+		}
+	case ArgString:
+		str, ok := node.(*parser.StringLit)
+		if !ok {
+			err = errors.New("not a string")
+			break
+		}
+
+		val = str.Value
+	case ArgName:
+		val, err = nameArg(call, node)
+	case ArgValue:
+		val, err = valueArg(call, node)
+	case ArgObject:
+		val, err = objectArg(call, node)
+	case ArgFilter:
+		return NewPredicate(call, node)
+	case ArgSort:
+		val, err = sortArg(call, node)
+	}
+
+	if err != nil {
+		reason := err.Error()
+
+		serr := call.Errorf(node, fmt.Sprintf("expected %s for %s", arg.Kind, arg.Name))
+		serr.Err = err
+
+		if cause, ok := err.(*parser.SyntaxError); ok {
+			serr.Pos = cause.Pos
+			reason = strings.TrimPrefix(cause.Msg, call.Name+": ")
+		}
+
+		serr.Msg += ": " + reason
+		return nil, serr
+	}
+
+	return val, nil
+}
+
+//==============================================================================
+
+// MethodRegistry holds the query methods understood by BasicQueries, allowing
+// applications to add their own.
+type MethodRegistry struct {
+	ml      sync.RWMutex
+	methods map[string]Method
+}
+
+// NewMethodRegistry returns a new MethodRegistry without any methods.
+func NewMethodRegistry() *MethodRegistry {
+	return &MethodRegistry{methods: make(map[string]Method)}
+}
+
+// Register adds the giving method to the registry. An error is returned if
+// a method of the same name exists, method names being case insensitive, or
+// if the method's arguments are malformed.
+func (r *MethodRegistry) Register(m Method) error {
+	if m.Name == "" || m.Build == nil {
+		return errors.New("Method requires a name and builder")
+	}
+
+	for index, arg := range m.Args {
+		last := index == len(m.Args)-1
+
+		if arg.Variadic && !last {
+			return fmt.Errorf("Method[%s] : Variadic argument %q must be the last", m.Name, arg.Name)
+		}
+
+		if !last && arg.Optional && !m.Args[index+1].Optional {
+			return fmt.Errorf("Method[%s] : Optional argument %q is followed by a required argument", m.Name, arg.Name)
+		}
+	}
+
+	name := strings.ToLower(m.Name)
+
+	r.ml.Lock()
+	defer r.ml.Unlock()
+
+	if _, ok := r.methods[name]; ok {
+		return fmt.Errorf("Method[%s] already registered", m.Name)
+	}
+
+	r.methods[name] = m
+	return nil
+}
+
+// Method returns the method of the giving case insensitive name.
+func (r *MethodRegistry) Method(name string) (Method, bool) {
+	r.ml.RLock()
+	defer r.ml.RUnlock()
+
+	m, ok := r.methods[strings.ToLower(name)]
+	return m, ok
+}
+
+// Methods returns the registered methods ordered by name.
+func (r *MethodRegistry) Methods() []Method {
+	r.ml.RLock()
+	defer r.ml.RUnlock()
+
+	var methods []Method
+	for _, m := range r.methods {
+		methods = append(methods, m)
+	}
+
+	sort.Sort(byName(methods))
+	return methods
+}
+
+// byName sorts methods by their names.
+type byName []Method
+
+func (b byName) Len() int           { return len(b) }
+func (b byName) Swap(i, j int)      { b[i], b[j] = b[j], b[i] }
+func (b byName) Less(i, j int) bool { return b[i].Name < b[j].Name }
+
+//==============================================================================
+
+// DefaultMethods provides the registry used by BasicQueries without their own,
+// it holds the builtin query methods.
+var DefaultMethods = NewMethodRegistry()
+
+// RegisterMethod adds the giving method to the DefaultMethods registry.
+func RegisterMethod(m Method) error {
+	return DefaultMethods.Register(m)
+}
+
+//==============================================================================
@@ -0,0 +1,159 @@
+package coquery_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/parser"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+// near defines a request for the records closest to a location.
+type near struct {
+	RID      string
+	Lat, Lng float64
+}
+
+// RequestID returns the request id for this request object.
+func (n *near) RequestID() string {
+	return n.RID
+}
+
+// RequestName returns the name for the giving request type.
+func (n *near) RequestName() string {
+	return "near"
+}
+
+//==============================================================================
+
+// TestMethodRegistry validates the registration of custom query methods.
+func TestMethodRegistry(t *testing.T) {
+	t.Logf("Given the need to add custom query methods")
+	{
+		methods := coquery.NewMethodRegistry()
+
+		for _, m := range coquery.BuiltinMethods() {
+			methods.Register(m)
+		}
+
+		err := methods.Register(coquery.Method{
+			Name:     "near",
+			Args:     []coquery.Arg{{Name: "lat", Kind: coquery.ArgNumber}, {Name: "lng", Kind: coquery.ArgNumber}},
+			Examples: []string{"near(6.45,3.39)"},
+			Build: func(call *coquery.MethodCall) (coquery.RecordRequests, error) {
+				return append(call.Reqs, &near{
+					RID: call.RID,
+					Lat: call.Args[0].(float64),
+					Lng: call.Args[1].(float64),
+				}), nil
+			},
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tShould have registered the near method: %s", tests.Failed, err)
+		}
+		t.Logf("\t%s\tShould have registered the near method", tests.Success)
+
+		if err := methods.Register(coquery.Method{Name: "Find", Build: func(call *coquery.MethodCall) (coquery.RecordRequests, error) { return call.Reqs, nil }}); err == nil {
+			t.Fatalf("\t%s\tShould have failed to register a method twice", tests.Failed)
+		}
+		t.Logf("\t%s\tShould have failed to register a method twice", tests.Success)
+
+		bq := coquery.BasicQueries{EventLog: events, Store: storage.New("id"), Methods: methods}
+
+		generate := func(query string) (coquery.RecordRequests, coquery.ResponseError) {
+			q, err := parser.Parse(query)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have parsed the query %q: %s", tests.Failed, query, err)
+			}
+
+			return bq.Generate(context, "43D3UFZ6", "users", q.Calls)
+		}
+
+		query := "docs.users.near(6.45, 3).findN(4)"
+		t.Logf("\tWhen giving the query %q", query)
+		{
+			reqs, err := generate(query)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have generated requests: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have generated requests", tests.Success)
+
+			loc, ok := reqs[0].(*near)
+			if !ok || loc.Lat != 6.45 || loc.Lng != 3 {
+				t.Fatalf("\t%s\tShould have generated a near request: %#v", tests.Failed, reqs[0])
+			}
+			t.Logf("\t%s\tShould have generated a near request", tests.Success)
+		}
+
+		for _, query := range []string{"docs.users.near(6.45)", "docs.users.near('a', 3)", "docs.users.near(6.45, 3, 1)"} {
+			t.Logf("\tWhen giving the invalid query %q", query)
+			{
+				_, err := generate(query)
+				if err == nil {
+					t.Fatalf("\t%s\tShould have failed to generate requests", tests.Failed)
+				}
+				t.Logf("\t%s\tShould have failed to generate requests: %s", tests.Success, err)
+			}
+		}
+
+		query = "docs.users.near(6.45, 3)"
+		t.Logf("\tWhen giving the query %q to the default methods", query)
+		{
+			bq.Methods = nil
+
+			if _, err := generate(query); err == nil {
+				t.Fatalf("\t%s\tShould have failed to find the near method", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have failed to find the near method", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
+
+// TestArgumentErrors validates that the errors of invalid arguments keep the
+// position and cause of the problem found.
+func TestArgumentErrors(t *testing.T) {
+	t.Logf("Given the need to report invalid arguments")
+	{
+		syntaxError := func(query string) *parser.SyntaxError {
+			_, err := generate(t, query)
+
+			cerr, ok := err.(*coquery.CoError)
+			if !ok {
+				t.Fatalf("\t%s\tShould have failed with a *coquery.CoError: %T", tests.Failed, err)
+			}
+
+			serr, ok := cerr.IError.(*parser.SyntaxError)
+			if !ok || serr.Err == nil {
+				t.Fatalf("\t%s\tShould have wrapped the cause in a *parser.SyntaxError: %#v", tests.Failed, cerr.IError)
+			}
+
+			return serr
+		}
+
+		query := `docs.users.find(id,1).mutate('{"name":')`
+		t.Logf("\tWhen giving the query %q", query)
+		{
+			if serr := syntaxError(query); !strings.Contains(serr.Error(), "unexpected end of JSON input") {
+				t.Fatalf("\t%s\tShould have reported the JSON error: %s", tests.Failed, serr)
+			}
+			t.Logf("\t%s\tShould have reported the JSON error", tests.Success)
+		}
+
+		query = "docs.users.sort(-true)"
+		t.Logf("\tWhen giving the query %q", query)
+		{
+			if serr := syntaxError(query); serr.Pos.Column != 18 {
+				t.Fatalf("\t%s\tShould have reported the position of the key: %s", tests.Failed, serr)
+			}
+			t.Logf("\t%s\tShould have reported the position of the key", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
@@ -0,0 +1,261 @@
+package parser
+
+import (
+	"strconv"
+
+	"github.com/influx6/coquery/data"
+)
+
+//==============================================================================
+
+// Node defines the base interface for all parsed elements of a query.
+type Node interface {
+	Pos() Position
+	End() Position
+}
+
+// Value defines a Node which represents a literal argument to a method call
+// and can be converted into its go value.
+type Value interface {
+	Node
+	Interface() interface{}
+}
+
+// span provides the start and end positions of a node.
+type span struct {
+	start Position
+	end   Position
+}
+
+// Pos returns the position of the first character of the node.
+func (s span) Pos() Position {
+	return s.start
+}
+
+// End returns the position immediately after the node.
+func (s span) End() Position {
+	return s.end
+}
+
+//==============================================================================
+
+// Query defines the parsed form of a query string, made up of the document
+// path (eg docs.users) and the method calls made on it.
+type Query struct {
+	Source string
+	Path   []*Ident
+	Calls  []*Call
+}
+
+// Call defines a method call within a query eg find(id,4). Its arguments are
+// either literal Values or expressions (BinaryExpr, UnaryExpr), followed by
+// the optional named arguments eg mutate({views:2}, ifVersion=7).
+type Call struct {
+	span
+	Name   string
+	Args   []Node
+	Named  []*NamedArg
+	Raw    string
+	Lparen Position
+	Rparen Position
+}
+
+// String returns the source text of the call.
+func (c *Call) String() string {
+	return c.Raw
+}
+
+// Arg returns the named argument with the giving name or nil if the call
+// lacks it.
+func (c *Call) Arg(name string) *NamedArg {
+	for _, arg := range c.Named {
+		if arg.Name == name {
+			return arg
+		}
+	}
+
+	return nil
+}
+
+// Errorf returns a *SyntaxError positioned at the giving node of the call.
+func (c *Call) Errorf(n Node, msg string) *SyntaxError {
+	if n == nil {
+		return &SyntaxError{Pos: c.Pos(), Msg: c.Name + ": " + msg}
+	}
+
+	return &SyntaxError{Pos: n.Pos(), Msg: c.Name + ": " + msg}
+}
+
+//==============================================================================
+
+// NamedArg defines a named argument of a method call eg ifVersion=7.
+type NamedArg struct {
+	span
+	Name  string
+	Value Node
+}
+
+//==============================================================================
+
+// Ident defines a bare word within a query, either a path segment or a
+// period delimited key within method arguments eg address.street.
+type Ident struct {
+	span
+	Name string
+}
+
+// Interface returns the name of the identifier.
+func (i *Ident) Interface() interface{} {
+	return i.Name
+}
+
+// StringLit defines a quoted string literal.
+type StringLit struct {
+	span
+	Value string
+}
+
+// Interface returns the decoded string.
+func (s *StringLit) Interface() interface{} {
+	return s.Value
+}
+
+// NumberLit defines a integer or floating point literal.
+type NumberLit struct {
+	span
+	Raw   string
+	IsInt bool
+	Int   int64
+	Float float64
+}
+
+// Interface returns the number as an int if it is one else as a float64.
+func (n *NumberLit) Interface() interface{} {
+	if n.IsInt {
+		return int(n.Int)
+	}
+
+	return n.Float
+}
+
+// BoolLit defines the true and false literals.
+type BoolLit struct {
+	span
+	Value bool
+}
+
+// Interface returns the boolean value.
+func (b *BoolLit) Interface() interface{} {
+	return b.Value
+}
+
+// NullLit defines the null literal.
+type NullLit struct {
+	span
+}
+
+// Interface returns nil.
+func (n *NullLit) Interface() interface{} {
+	return nil
+}
+
+// ObjectIDLit defines a object id literal eg oid("5707a1d1e4b0e5a0c8f2b1a3")
+// or ObjectId("5707a1d1e4b0e5a0c8f2b1a3").
+type ObjectIDLit struct {
+	span
+	Hex string
+}
+
+// Interface returns the id as a data.ObjectID.
+func (o *ObjectIDLit) Interface() interface{} {
+	return data.ObjectID(o.Hex)
+}
+
+// Field defines a single key-value pair of an object literal.
+type Field struct {
+	Key    string
+	KeyPos Position
+	Value  Value
+}
+
+// ObjectLit defines a json-like object literal eg {name:"alex", age: 1}.
+type ObjectLit struct {
+	span
+	Fields []*Field
+}
+
+// Interface returns the object as a map.
+func (o *ObjectLit) Interface() interface{} {
+	obj := make(map[string]interface{}, len(o.Fields))
+
+	for _, field := range o.Fields {
+		obj[field.Key] = field.Value.Interface()
+	}
+
+	return obj
+}
+
+// ArrayLit defines a list literal eg [1,"two",3].
+type ArrayLit struct {
+	span
+	Items []Value
+}
+
+// Interface returns the array as a slice.
+func (a *ArrayLit) Interface() interface{} {
+	items := make([]interface{}, 0, len(a.Items))
+
+	for _, item := range a.Items {
+		items = append(items, item.Interface())
+	}
+
+	return items
+}
+
+//==============================================================================
+
+// BinaryExpr defines a comparison or logical expression eg age > 21 or
+// a == 1 && b != 2. Op holds the operator text which is one of ==, !=, <, <=,
+// >, >=, in, not in, && and ||.
+type BinaryExpr struct {
+	span
+	Op    string
+	OpPos Position
+	Left  Node
+	Right Node
+}
+
+// UnaryExpr defines a negated expression eg !(age > 21) or a negated field
+// name eg -age. Op holds the operator text which is either ! or -.
+type UnaryExpr struct {
+	span
+	Op string
+	X  Node
+}
+
+//==============================================================================
+
+// newNumber returns a NumberLit for the giving number token.
+func newNumber(tok Token) (*NumberLit, error) {
+	num := NumberLit{
+		span: span{start: tok.Pos, end: tok.End},
+		Raw:  tok.Text,
+	}
+
+	if in, err := strconv.ParseInt(tok.Text, 10, 64); err == nil {
+		num.IsInt = true
+		num.Int = in
+		num.Float = float64(in)
+		return &num, nil
+	}
+
+	fl, err := strconv.ParseFloat(tok.Text, 64)
+	if err != nil {
+		return nil, &SyntaxError{Pos: tok.Pos, Msg: "invalid number " + strconv.Quote(tok.Text)}
+	}
+
+	num.Float = fl
+	return &num, nil
+}
+
+//==============================================================================
@@ -0,0 +1,478 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+//==============================================================================
+
+// Position defines the location of a token or node within a query string.
+// Offset is a zero based byte offset while Line and Column are one based.
+type Position struct {
+	Offset int
+	Line   int
+	Column int
+}
+
+// String returns a human readable version of the position.
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, column %d", p.Line, p.Column)
+}
+
+// SyntaxError defines the error returned when a query string is malformed. It
+// carries the position in the query where the problem was found, and the
+// error which caused it if any.
+type SyntaxError struct {
+	Pos Position
+	Msg string
+	Err error
+}
+
+// Error returns the error message for this syntax error.
+func (s *SyntaxError) Error() string {
+	return fmt.Sprintf("%s: %s", s.Pos, s.Msg)
+}
+
+// Unwrap returns the error which caused the syntax error, if any.
+func (s *SyntaxError) Unwrap() error {
+	return s.Err
+}
+
+//==============================================================================
+
+// TokenType defines the kind of a token produced by the lexer.
+type TokenType int
+
+// contains the set of tokens understood by the lexer.
+const (
+	EOF TokenType = iota
+	IDENT
+	STRING
+	NUMBER
+	LPAREN
+	RPAREN
+	LBRACE
+	RBRACE
+	LBRACKET
+	RBRACKET
+	COMMA
+	COLON
+	DOT
+	EQ
+	NEQ
+	LT
+	LTE
+	GT
+	GTE
+	AND
+	OR
+	NOT
+	MINUS
+	ARROW
+	ASSIGN
+)
+
+// tokenNames provides the printable names for the token types.
+var tokenNames = map[TokenType]string{
+	EOF:      "end of query",
+	IDENT:    "identifier",
+	STRING:   "string",
+	NUMBER:   "number",
+	LPAREN:   "'('",
+	RPAREN:   "')'",
+	LBRACE:   "'{'",
+	RBRACE:   "'}'",
+	LBRACKET: "'['",
+	RBRACKET: "']'",
+	COMMA:    "','",
+	COLON:    "':'",
+	DOT:      "'.'",
+	EQ:       "'=='",
+	NEQ:      "'!='",
+	LT:       "'<'",
+	LTE:      "'<='",
+	GT:       "'>'",
+	GTE:      "'>='",
+	AND:      "'&&'",
+	OR:       "'||'",
+	NOT:      "'!'",
+	MINUS:    "'-'",
+	ARROW:    "'->'",
+	ASSIGN:   "'='",
+}
+
+// String returns the printable name of the token type.
+func (t TokenType) String() string {
+	if name, ok := tokenNames[t]; ok {
+		return name
+	}
+
+	return fmt.Sprintf("token(%d)", int(t))
+}
+
+// punctuations maps the single character tokens to their types.
+var punctuations = map[rune]TokenType{
+	'(': LPAREN,
+	')': RPAREN,
+	'{': LBRACE,
+	'}': RBRACE,
+	'[': LBRACKET,
+	']': RBRACKET,
+	',': COMMA,
+	':': COLON,
+	'.': DOT,
+}
+
+// Token defines a single lexed item of a query string. Text holds the exact
+// source text of the token and Value the decoded content for strings.
+type Token struct {
+	Type  TokenType
+	Text  string
+	Value string
+	Pos   Position
+	End   Position
+}
+
+//==============================================================================
+
+// Lex splits the giving query string into its tokens, ending with an EOF
+// token. It returns a *SyntaxError if an invalid character, string or number
+// is found.
+func Lex(src string) ([]Token, error) {
+	lx := lexer{src: src, pos: Position{Line: 1, Column: 1}}
+
+	var tokens []Token
+
+	for {
+		tok, err := lx.next()
+		if err != nil {
+			return nil, err
+		}
+
+		tokens = append(tokens, tok)
+
+		if tok.Type == EOF {
+			return tokens, nil
+		}
+	}
+}
+
+// lexer provides the scanning state for lexing a query string.
+type lexer struct {
+	src string
+	pos Position
+}
+
+// peek returns the rune at the current position without consuming it.
+func (l *lexer) peek() rune {
+	if l.pos.Offset >= len(l.src) {
+		return utf8.RuneError
+	}
+
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos.Offset:])
+	return r
+}
+
+// done returns true/false if the lexer has consumed all of its source.
+func (l *lexer) done() bool {
+	return l.pos.Offset >= len(l.src)
+}
+
+// advance consumes the current rune, updating the line and column counts.
+func (l *lexer) advance() rune {
+	r, size := utf8.DecodeRuneInString(l.src[l.pos.Offset:])
+	l.pos.Offset += size
+
+	if r == '\n' {
+		l.pos.Line++
+		l.pos.Column = 1
+		return r
+	}
+
+	l.pos.Column++
+	return r
+}
+
+// errorf returns a new *SyntaxError for the giving position.
+func (l *lexer) errorf(pos Position, format string, args ...interface{}) error {
+	return &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// token returns a token of the giving type spanning from start to the current
+// position.
+func (l *lexer) token(tt TokenType, start Position) Token {
+	return Token{
+		Type: tt,
+		Text: l.src[start.Offset:l.pos.Offset],
+		Pos:  start,
+		End:  l.pos,
+	}
+}
+
+// next scans and returns the next token within the source.
+func (l *lexer) next() (Token, error) {
+	for !l.done() && isSpace(l.peek()) {
+		l.advance()
+	}
+
+	start := l.pos
+
+	if l.done() {
+		return l.token(EOF, start), nil
+	}
+
+	r := l.peek()
+
+	if tt, ok := punctuations[r]; ok {
+		l.advance()
+		return l.token(tt, start), nil
+	}
+
+	switch {
+	case isIdentStart(r):
+		for !l.done() && isIdentPart(l.peek()) && !strings.HasPrefix(l.src[l.pos.Offset:], "->") {
+			l.advance()
+		}
+
+		return l.token(IDENT, start), nil
+
+	case isDigit(r) || r == '-':
+		return l.number(start)
+
+	case r == '"' || r == '\'':
+		return l.quoted(start, r)
+
+	case r == '`':
+		return l.raw(start)
+
+	case strings.ContainsRune("=!<>&|", r):
+		return l.operator(start)
+	}
+
+	return Token{}, l.errorf(start, "unexpected character %q", r)
+}
+
+// operators maps the comparison, logical and assignment operators to their
+// types.
+var operators = map[string]TokenType{
+	"==": EQ,
+	"!=": NEQ,
+	"<=": LTE,
+	">=": GTE,
+	"&&": AND,
+	"||": OR,
+	"<":  LT,
+	">":  GT,
+	"!":  NOT,
+	"=":  ASSIGN,
+}
+
+// operator scans a comparison or logical operator, preferring the two
+// character operators over their single character prefixes.
+func (l *lexer) operator(start Position) (Token, error) {
+	if l.pos.Offset+2 <= len(l.src) {
+		if tt, ok := operators[l.src[l.pos.Offset:l.pos.Offset+2]]; ok {
+			l.advance()
+			l.advance()
+			return l.token(tt, start), nil
+		}
+	}
+
+	r := l.advance()
+
+	if tt, ok := operators[string(r)]; ok {
+		return l.token(tt, start), nil
+	}
+
+	return Token{}, l.errorf(start, "unexpected character %q", r)
+}
+
+// number scans a integer or floating point number, a '-' which is not
+// followed by digits is returned as a MINUS token eg sort(-age) or an ARROW
+// token when followed by '>'.
+func (l *lexer) number(start Position) (Token, error) {
+	if l.peek() == '-' {
+		l.advance()
+
+		if l.peek() == '>' {
+			l.advance()
+			return l.token(ARROW, start), nil
+		}
+
+		if !isDigit(l.peek()) {
+			return l.token(MINUS, start), nil
+		}
+	}
+
+	l.digits()
+
+	// A period is only part of the number when digits follow it, this
+	// allows numbers to be followed by a method call segment.
+	if l.peek() == '.' && l.pos.Offset+1 < len(l.src) && isDigit(rune(l.src[l.pos.Offset+1])) {
+		l.advance()
+		l.digits()
+	}
+
+	if r := l.peek(); r == 'e' || r == 'E' {
+		l.advance()
+
+		if r := l.peek(); r == '+' || r == '-' {
+			l.advance()
+		}
+
+		if !isDigit(l.peek()) {
+			return Token{}, l.errorf(start, "malformed number exponent")
+		}
+
+		l.digits()
+	}
+
+	if !l.done() && isIdentPart(l.peek()) {
+		return Token{}, l.errorf(start, "malformed number %q", l.src[start.Offset:l.pos.Offset+1])
+	}
+
+	return l.token(NUMBER, start), nil
+}
+
+// digits consumes a run of decimal digits.
+func (l *lexer) digits() {
+	for !l.done() && isDigit(l.peek()) {
+		l.advance()
+	}
+}
+
+// quoted scans a single or double quoted string, decoding its escape
+// sequences.
+func (l *lexer) quoted(start Position, quote rune) (Token, error) {
+	l.advance()
+
+	var value []byte
+
+	for {
+		if l.done() {
+			return Token{}, l.errorf(start, "unterminated string")
+		}
+
+		at := l.pos
+		r := l.advance()
+
+		if r == quote {
+			tok := l.token(STRING, start)
+			tok.Value = string(value)
+			return tok, nil
+		}
+
+		if r != '\\' {
+			value = append(value, l.src[at.Offset:l.pos.Offset]...)
+			continue
+		}
+
+		escPos := at
+		if l.done() {
+			return Token{}, l.errorf(start, "unterminated string")
+		}
+
+		switch esc := l.advance(); esc {
+		case '"', '\'', '\\', '/', '`':
+			value = append(value, byte(esc))
+		case 'n':
+			value = append(value, '\n')
+		case 't':
+			value = append(value, '\t')
+		case 'r':
+			value = append(value, '\r')
+		case 'b':
+			value = append(value, '\b')
+		case 'f':
+			value = append(value, '\f')
+		case 'x':
+			code, err := l.hex(escPos, 2)
+			if err != nil {
+				return Token{}, err
+			}
+			value = append(value, byte(code))
+		case 'u':
+			code, err := l.hex(escPos, 4)
+			if err != nil {
+				return Token{}, err
+			}
+			value = append(value, string(rune(code))...)
+		default:
+			return Token{}, l.errorf(escPos, "invalid escape sequence '\\%c'", esc)
+		}
+	}
+}
+
+// hex reads the giving number of hexadecimal digits for an escape sequence.
+func (l *lexer) hex(pos Position, size int) (int64, error) {
+	if l.pos.Offset+size > len(l.src) {
+		return 0, l.errorf(pos, "incomplete escape sequence")
+	}
+
+	digits := l.src[l.pos.Offset : l.pos.Offset+size]
+
+	code, err := strconv.ParseInt(digits, 16, 32)
+	if err != nil {
+		return 0, l.errorf(pos, "invalid escape sequence %q", digits)
+	}
+
+	for i := 0; i < size; i++ {
+		l.advance()
+	}
+
+	return code, nil
+}
+
+// raw scans a backtick delimited string which has no escape sequences. The
+// string ends with the same number of backticks it started with, which allows
+// backticks to be used within the string eg ``a`b``. As every opening backtick
+// is part of the fence a raw string can not be empty, '' or "" being used
+// instead.
+func (l *lexer) raw(start Position) (Token, error) {
+	var fence int
+	for l.peek() == '`' {
+		l.advance()
+		fence++
+	}
+
+	delim := strings.Repeat("`", fence)
+	body := l.pos.Offset
+
+	end := strings.Index(l.src[body:], delim)
+	if end < 0 {
+		return Token{}, l.errorf(start, "unterminated raw string")
+	}
+
+	for l.pos.Offset < body+end+fence {
+		l.advance()
+	}
+
+	tok := l.token(STRING, start)
+	tok.Value = l.src[body : body+end]
+	return tok, nil
+}
+
+//==============================================================================
+
+// isSpace returns true/false if the rune is a whitespace character.
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
+
+// isDigit returns true/false if the rune is a decimal digit.
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+// isIdentStart returns true/false if the rune can begin an identifier.
+func isIdentStart(r rune) bool {
+	return r == '_' || r == '$' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z')
+}
+
+// isIdentPart returns true/false if the rune can be part of an identifier.
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || isDigit(r) || r == '-'
+}
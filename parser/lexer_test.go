@@ -0,0 +1,57 @@
+package parser_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery/parser"
+)
+
+//==============================================================================
+
+// TestRawStrings validates the lexing of backtick fenced raw strings.
+func TestRawStrings(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	cases := []struct {
+		src   string
+		value string
+	}{
+		{"`x`", "x"},
+		{"``x``", "x"},
+		{"``a`b``", "a`b"},
+		{"```a``b```", "a``b"},
+		{"`\\n`", "\\n"},
+	}
+
+	t.Logf("Given the need to lex raw strings")
+	{
+		for _, tc := range cases {
+			t.Logf("\tWhen giving the raw string %s", tc.src)
+			{
+				toks, err := parser.Lex(tc.src)
+				if err != nil {
+					t.Fatalf("\t%s\tShould have lexed the string: %s", tests.Failed, err)
+				}
+
+				if len(toks) != 2 || toks[0].Type != parser.STRING || toks[0].Value != tc.value {
+					t.Fatalf("\t%s\tShould have lexed a single string %q: %+v", tests.Failed, tc.value, toks)
+				}
+				t.Logf("\t%s\tShould have lexed a single string %q", tests.Success, tc.value)
+			}
+		}
+
+		for _, src := range []string{"``", "``x`"} {
+			t.Logf("\tWhen giving the unterminated raw string %s", src)
+			{
+				if _, err := parser.Lex(src); err == nil {
+					t.Fatalf("\t%s\tShould have failed to lex the string", tests.Failed)
+				}
+				t.Logf("\t%s\tShould have failed to lex the string", tests.Success)
+			}
+		}
+	}
+}
+
+//==============================================================================
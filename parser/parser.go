@@ -1,10 +1,11 @@
+// Package parser provides the lexer and parser for the coquery query language,
+// turning a query string such as docs.users.find(id,4).collects(name) into
+// its document path and method calls with typed arguments.
 package parser
 
 import (
-	"bufio"
-	"bytes"
+	"encoding/hex"
 	"fmt"
-	"regexp"
 	"strings"
 )
 
@@ -14,167 +15,546 @@ import (
 // 	Error(context interface{}, name string, err error, message string, data ...interface{})
 // }
 
-// section defines a regexp to part specific section of a query request string.
-var section = regexp.MustCompile("([a-zA-Z0-9_\\-]+)\\((.+)\\)")
+//==============================================================================
+
+// Parse parses the giving query string into its path segments and method
+// calls. A *SyntaxError with the offending position is returned if the query
+// is malformed.
+//
+// A query is made up of period delimited segments, where the leading segments
+// are bare path names and the rest are method calls:
+//
+//	docs.users.find(id,4).collects(name,address.street)
+//
+// Method arguments can be identifiers, strings (single, double or backtick
+// quoted), numbers, true, false, null, object ids (oid("...")), objects and
+// arrays. Arguments can also be expressions using the comparison operators
+// (==, !=, <, <=, >, >=, in, not in), the logical operators (&&, ||, !) and
+// parentheses for grouping eg where(age > 21 && status in ["active"]). A
+// field name can be negated with '-' eg sort(name,-age) and a field can point
+// to a document with '->' eg expand(author -> docs.users).
+func Parse(query string) (*Query, error) {
+	tokens, err := Lex(query)
+	if err != nil {
+		return nil, err
+	}
+
+	p := parser{src: query, tokens: tokens}
+	return p.query()
+}
 
 // ParseQuery returns the giving information as regarding the necessary data to
-// be processed.
+// be processed, splitting the query into its path and method call sections.
+// It returns nil if the query is malformed.
 func ParseQuery(context interface{}, data string) []string {
+	q, err := Parse(data)
+	if err != nil {
+		return nil
+	}
 
 	var parts []string
 
-	// We need to adjust the final data to be able to capture its entity.
-	data = fmt.Sprintf("%s.", data)
+	for _, seg := range q.Path {
+		parts = append(parts, seg.Name)
+	}
 
-	buf := bytes.NewBufferString(data)
-	read := bufio.NewReader(buf)
+	for _, call := range q.Calls {
+		parts = append(parts, call.Raw)
+	}
 
-	for {
+	return parts
+}
+
+// SplitQuery returns a method name and the content of that method name for a
+// query section .eg SplitQuery("find(id,1)") => returns (find, "id,1").
+// It returns empty values if the section is not a single valid method call.
+func SplitQuery(context interface{}, sec string) (method string, content string, contentPart []string) {
+	q, err := Parse(sec)
+	if err != nil || len(q.Path) != 0 || len(q.Calls) != 1 {
+		return
+	}
+
+	call := q.Calls[0]
+
+	method = call.Name
+	content = sec[call.Lparen.Offset+1 : call.Rparen.Offset]
+
+	for _, arg := range call.Args {
+		contentPart = append(contentPart, sec[arg.Pos().Offset:arg.End().Offset])
+	}
+
+	return
+}
 
-		line, err := read.ReadString('.')
+//==============================================================================
+
+// parser provides a recursive descent parser over a lexed query.
+type parser struct {
+	src    string
+	tokens []Token
+	index  int
+}
+
+// peek returns the current token.
+func (p *parser) peek() Token {
+	return p.tokens[p.index]
+}
+
+// next returns the current token and moves to the next.
+func (p *parser) next() Token {
+	tok := p.tokens[p.index]
+
+	if tok.Type != EOF {
+		p.index++
+	}
+
+	return tok
+}
+
+// expect consumes the current token if it matches the giving type else returns
+// a *SyntaxError.
+func (p *parser) expect(tt TokenType, context string) (Token, error) {
+	tok := p.next()
+	if tok.Type != tt {
+		return tok, p.unexpected(tok, fmt.Sprintf("expected %s %s", tt, context))
+	}
+
+	return tok, nil
+}
+
+// unexpected returns a *SyntaxError describing the unexpected token.
+func (p *parser) unexpected(tok Token, msg string) error {
+	if tok.Type == EOF {
+		return &SyntaxError{Pos: tok.Pos, Msg: fmt.Sprintf("unexpected end of query, %s", msg)}
+	}
+
+	return &SyntaxError{Pos: tok.Pos, Msg: fmt.Sprintf("unexpected %q, %s", tok.Text, msg)}
+}
+
+// query parses the complete query.
+func (p *parser) query() (*Query, error) {
+	q := Query{Source: p.src}
+
+	for {
+		tok, err := p.expect(IDENT, "for query segment")
 		if err != nil {
-			break
+			return nil, err
 		}
 
-		var hasOps bool
+		if p.peek().Type != LPAREN {
+			if len(q.Calls) > 0 {
+				return nil, &SyntaxError{Pos: tok.Pos, Msg: fmt.Sprintf("path segment %q must come before method calls", tok.Text)}
+			}
 
-		if strings.Contains(line, "(") {
-			hasOps = true
-		}
+			q.Path = append(q.Path, &Ident{span: span{start: tok.Pos, end: tok.End}, Name: tok.Text})
+		} else {
+			call, err := p.call(tok)
+			if err != nil {
+				return nil, err
+			}
 
-		if !hasOps {
-			line = strings.TrimSuffix(line, ".")
-			parts = append(parts, line)
-			continue
+			q.Calls = append(q.Calls, call)
 		}
 
-		fline := strings.TrimSuffix(line, ".")
-		if section.MatchString(line) && strings.HasSuffix(fline, ")") {
-			line = strings.TrimSuffix(line, ".")
-			parts = append(parts, line)
+		switch next := p.next(); next.Type {
+		case EOF:
+			return &q, nil
+		case DOT:
 			continue
+		default:
+			return nil, p.unexpected(next, "expected '.' between query segments")
 		}
+	}
+}
 
-		var mline string
-		for {
+// call parses the argument list of a method call.
+func (p *parser) call(name Token) (*Call, error) {
+	lparen := p.next()
 
-			// Read next so we can capture possible failure.
-			xline, err := read.ReadString('.')
+	call := Call{
+		Name:   name.Text,
+		Lparen: lparen.Pos,
+	}
 
-			if err != nil {
-				break
+	if p.peek().Type != RPAREN {
+		for {
+			if p.named() {
+				arg, err := p.namedArg(&call)
+				if err != nil {
+					return nil, err
+				}
+
+				call.Named = append(call.Named, arg)
+			} else {
+				if len(call.Named) > 0 {
+					return nil, p.unexpected(p.peek(), "expected named arguments to follow the other arguments")
+				}
+
+				arg, err := p.expr()
+				if err != nil {
+					return nil, err
+				}
+
+				call.Args = append(call.Args, arg)
 			}
 
-			if strings.HasSuffix(mline, ")") {
-				xline = strings.TrimSuffix(xline, ".")
-				mline = mline + xline
-				break
+			if p.peek().Type == COMMA {
+				p.next()
+				continue
 			}
 
-			mline = mline + xline
+			break
 		}
+	}
+
+	rparen, err := p.expect(RPAREN, fmt.Sprintf("to close arguments of %q", name.Text))
+	if err != nil {
+		return nil, err
+	}
+
+	call.Rparen = rparen.Pos
+	call.span = span{start: name.Pos, end: rparen.End}
+	call.Raw = p.src[name.Pos.Offset:rparen.End.Offset]
+
+	return &call, nil
+}
+
+// named returns true/false if the current tokens begin a named argument.
+func (p *parser) named() bool {
+	return p.peek().Type == IDENT && p.index+1 < len(p.tokens) && p.tokens[p.index+1].Type == ASSIGN
+}
 
-		line = line + mline
+// namedArg parses a named argument eg ifVersion=7, rejecting names already
+// given to the call.
+func (p *parser) namedArg(call *Call) (*NamedArg, error) {
+	name := p.next()
 
-		line = strings.TrimSuffix(line, ".")
-		parts = append(parts, line)
+	if call.Arg(name.Text) != nil {
+		return nil, p.unexpected(name, fmt.Sprintf("duplicate argument %q", name.Text))
 	}
 
-	return parts
+	p.next()
+
+	value, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	return &NamedArg{
+		span:  span{start: name.Pos, end: value.End()},
+		Name:  name.Text,
+		Value: value,
+	}, nil
 }
 
-// runes provides a map of special symbols with their endings to allows us
-// capture adequately proper parts of a query.
-var runes = map[string]string{
-	"{": "}", "}": "{",
-	"(": ")", ")": "(",
-	"[": "]", "]": "[",
-	"\"": "\"", "'": "'",
-	"`": "`",
+// expr parses a argument expression, starting with the lowest precedence
+// operator.
+func (p *parser) expr() (Node, error) {
+	return p.binary(OR, "||", p.and)
 }
 
-// SplitQuery returns a method name and the content of that mkethod name for a
-// query section .eg SplitQuery("find(id,1)") => returns (find, "id,1").
-func SplitQuery(context interface{}, sec string) (method string, content string, contentPart []string) {
-	if !section.MatchString(sec) {
-		return
+// and parses a chain of && expressions.
+func (p *parser) and() (Node, error) {
+	return p.binary(AND, "&&", p.unary)
+}
+
+// binary parses a left associative chain of the giving logical operator
+// whose operands are parsed by the next function.
+func (p *parser) binary(tt TokenType, op string, next func() (Node, error)) (Node, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
 	}
 
-	subs := section.FindStringSubmatch(sec)
-	method = subs[1]
-	content = subs[2]
+	for p.peek().Type == tt {
+		opTok := p.next()
+
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
 
-	// We need to adjust the final data to be able to capture its entity.
-	data := fmt.Sprintf("%s,", content)
+		left = &BinaryExpr{
+			span:  span{start: left.Pos(), end: right.End()},
+			Op:    op,
+			OpPos: opTok.Pos,
+			Left:  left,
+			Right: right,
+		}
+	}
 
-	buf := bytes.NewBufferString(data)
-	read := bufio.NewReader(buf)
+	return left, nil
+}
 
-	for {
+// unary parses a negated expression or field name.
+func (p *parser) unary() (Node, error) {
+	switch p.peek().Type {
+	case NOT:
+		not := p.next()
 
-		line, err := read.ReadString(',')
+		x, err := p.unary()
 		if err != nil {
-			break
+			return nil, err
 		}
 
-		line = strings.TrimSpace(line)
+		return &UnaryExpr{span: span{start: not.Pos, end: x.End()}, Op: "!", X: x}, nil
 
-		// Get the prefix of this part.
-		pl := string(line[0])
+	case MINUS:
+		minus := p.next()
 
-		// Do we have a possible special rune, if not add to list and continue
-		if _, ok := runes[pl]; !ok {
-			line = strings.TrimSuffix(line, ",")
-			contentPart = append(contentPart, line)
-			continue
+		if p.peek().Type != IDENT {
+			return nil, p.unexpected(p.peek(), "expected a field name after '-'")
+		}
+
+		x, err := p.value()
+		if err != nil {
+			return nil, err
 		}
 
-		el := runes[pl]
+		return &UnaryExpr{span: span{start: minus.Pos, end: x.End()}, Op: "-", X: x}, nil
+	}
 
-		// If we have such a special character, then check if the special character
-		// also ends the item else then setup the depth level and
-		// the state we are in.
-		if strings.HasSuffix(line, el) {
-			line = strings.TrimSuffix(line, ",")
-			contentPart = append(contentPart, line)
-			continue
+	return p.comparison()
+}
+
+// comparisons maps the comparison tokens to their operator text.
+var comparisons = map[TokenType]string{
+	EQ:    "==",
+	NEQ:   "!=",
+	LT:    "<",
+	LTE:   "<=",
+	GT:    ">",
+	GTE:   ">=",
+	ARROW: "->",
+}
+
+// comparison parses a comparison between two operands, if no comparison
+// operator follows the first operand, it is returned alone.
+func (p *parser) comparison() (Node, error) {
+	left, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	opTok := p.peek()
+
+	var op string
+
+	switch {
+	case comparisons[opTok.Type] != "":
+		op = comparisons[opTok.Type]
+		p.next()
+
+	case opTok.Type == IDENT && opTok.Text == "in":
+		op = "in"
+		p.next()
+
+	case opTok.Type == IDENT && opTok.Text == "not" && p.tokens[p.index+1].Type == IDENT && p.tokens[p.index+1].Text == "in":
+		op = "not in"
+		p.next()
+		p.next()
+
+	default:
+		return left, nil
+	}
+
+	right, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+
+	return &BinaryExpr{
+		span:  span{start: left.Pos(), end: right.End()},
+		Op:    op,
+		OpPos: opTok.Pos,
+		Left:  left,
+		Right: right,
+	}, nil
+}
+
+// primary parses a parenthesized expression or a literal value.
+func (p *parser) primary() (Node, error) {
+	if p.peek().Type != LPAREN {
+		return p.value()
+	}
+
+	p.next()
+
+	x, err := p.expr()
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := p.expect(RPAREN, "to close expression"); err != nil {
+		return nil, err
+	}
+
+	return x, nil
+}
+
+// value parses a single literal value.
+func (p *parser) value() (Value, error) {
+	tok := p.next()
+
+	switch tok.Type {
+	case STRING:
+		return &StringLit{span: span{start: tok.Pos, end: tok.End}, Value: tok.Value}, nil
+
+	case NUMBER:
+		return newNumber(tok)
+
+	case LBRACE:
+		return p.object(tok)
+
+	case LBRACKET:
+		return p.array(tok)
+
+	case IDENT:
+		sp := span{start: tok.Pos, end: tok.End}
+
+		switch tok.Text {
+		case "true", "false":
+			return &BoolLit{span: sp, Value: tok.Text == "true"}, nil
+		case "null":
+			return &NullLit{span: sp}, nil
+		}
+
+		if p.peek().Type == LPAREN {
+			return p.objectID(tok)
+		}
+
+		// Identifiers can be period delimited keys eg address.street.
+		name := tok.Text
+		for p.peek().Type == DOT {
+			p.next()
+
+			part, err := p.expect(IDENT, fmt.Sprintf("after %q", name+"."))
+			if err != nil {
+				return nil, err
+			}
+
+			name = name + "." + part.Text
+			sp.end = part.End
 		}
 
-		depth := 1
-		subs := []string{line}
+		return &Ident{span: sp, Name: name}, nil
+	}
+
+	return nil, p.unexpected(tok, "expected a value")
+}
+
+// objectIDLen defines the length of the hexadecimal form of an object id.
+const objectIDLen = 24
+
+// objectID parses a object id literal, which is the only supported function
+// style value.
+func (p *parser) objectID(name Token) (*ObjectIDLit, error) {
+	if name.Text != "oid" && name.Text != "ObjectId" {
+		return nil, &SyntaxError{Pos: name.Pos, Msg: fmt.Sprintf("unknown value function %q, expected oid or ObjectId", name.Text)}
+	}
+
+	p.next()
+
+	id, err := p.expect(STRING, fmt.Sprintf("as argument of %q", name.Text))
+	if err != nil {
+		return nil, err
+	}
 
-	iloop:
+	if _, err := hex.DecodeString(id.Value); err != nil || len(id.Value) != objectIDLen {
+		return nil, &SyntaxError{Pos: id.Pos, Msg: fmt.Sprintf("invalid object id %q, expected %d hexadecimal characters", id.Value, objectIDLen)}
+	}
+
+	rparen, err := p.expect(RPAREN, fmt.Sprintf("to close %q", name.Text))
+	if err != nil {
+		return nil, err
+	}
+
+	return &ObjectIDLit{
+		span: span{start: name.Pos, end: rparen.End},
+		Hex:  strings.ToLower(id.Value),
+	}, nil
+}
+
+// object parses an object literal, whose keys can be identifiers or strings.
+func (p *parser) object(lbrace Token) (*ObjectLit, error) {
+	obj := ObjectLit{span: span{start: lbrace.Pos}}
+
+	if p.peek().Type != RBRACE {
 		for {
-			if depth <= 0 {
-				break iloop
+			key := p.next()
+
+			var field Field
+
+			switch key.Type {
+			case IDENT:
+				field.Key = key.Text
+			case STRING:
+				field.Key = key.Value
+			default:
+				return nil, p.unexpected(key, "expected object key")
 			}
 
-			mline, err := read.ReadString(',')
+			field.KeyPos = key.Pos
+
+			if _, err := p.expect(COLON, fmt.Sprintf("after object key %q", field.Key)); err != nil {
+				return nil, err
+			}
+
+			val, err := p.value()
 			if err != nil {
-				break iloop
+				return nil, err
+			}
+
+			field.Value = val
+			obj.Fields = append(obj.Fields, &field)
+
+			if p.peek().Type == COMMA {
+				p.next()
+				continue
 			}
 
-			// mline = strings.TrimSpace(mline)
+			break
+		}
+	}
+
+	rbrace, err := p.expect(RBRACE, "to close object")
+	if err != nil {
+		return nil, err
+	}
+
+	obj.end = rbrace.End
+	return &obj, nil
+}
+
+// array parses an array literal.
+func (p *parser) array(lbracket Token) (*ArrayLit, error) {
+	arr := ArrayLit{span: span{start: lbracket.Pos}}
 
-			if strings.HasSuffix(mline, el) && depth > 0 {
-				subs = append(subs, mline)
-				depth--
-				continue iloop
+	if p.peek().Type != RBRACKET {
+		for {
+			val, err := p.value()
+			if err != nil {
+				return nil, err
 			}
 
-			if strings.HasPrefix(mline, pl) {
-				subs = append(subs, mline)
-				depth++
-				continue iloop
+			arr.Items = append(arr.Items, val)
+
+			if p.peek().Type == COMMA {
+				p.next()
+				continue
 			}
 
-			subs = append(subs, mline)
+			break
 		}
+	}
 
-		subline := strings.Join(subs, "")
-		subline = strings.TrimSuffix(subline, ",")
-		contentPart = append(contentPart, subline)
+	rbracket, err := p.expect(RBRACKET, "to close array")
+	if err != nil {
+		return nil, err
 	}
 
-	return
+	arr.end = rbracket.End
+	return &arr, nil
 }
+
+//==============================================================================
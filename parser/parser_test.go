@@ -39,14 +39,14 @@ func TestQuerySpliting(t *testing.T) {
 			t.Logf("\t%s\tShould have retrieved the appropriate method and contents of the query", tests.Success)
 		}
 
-		qs := "kv(id,\"{\"name\":\"bug.\"}\")"
+		qs := "kv(id,\"{\\\"name\\\":\\\"bug.\\\"}\")"
 		t.Logf("\tWhen giving a query method call %q", qs)
 		{
 
 			method, content, contents := parser.SplitQuery(context, qs)
 			// fmt.Printf("Method: %s Content: %s Contents: %q\n", method, content, contents)
 
-			if method != "kv" || content != "id,\"{\"name\":\"bug.\"}\"" || len(contents) < 2 {
+			if method != "kv" || content != "id,\"{\\\"name\\\":\\\"bug.\\\"}\"" || len(contents) < 2 {
 				t.Fatalf("\t%s\tShould have retrieved the appropriate method and contents of the query: Method: %q Content: %q", tests.Failed, method, content)
 			}
 			t.Logf("\t%s\tShould have retrieved the appropriate method and contents of the query", tests.Success)
@@ -198,7 +198,7 @@ func TestMixedParsing(t *testing.T) {
 	t.Logf("Given the need to be able to parser a query request string")
 	{
 
-		q := "docs.user.kv(id,\"{\"name\":\"bug.\"}\")"
+		q := "docs.user.kv(id,\"{\\\"name\\\":\\\"bug.\\\"}\")"
 
 		t.Logf("\tWhen giving a query string %q", q)
 		{
@@ -242,3 +242,233 @@ func TestHexParsing(t *testing.T) {
 
 	}
 }
+
+//==============================================================================
+
+// TestTypedArguments validates that method arguments are parsed into their
+// typed literal values, even when strings hold periods and commas.
+func TestTypedArguments(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to parse typed method arguments")
+	{
+
+		q := `docs.users.find(id,3).mutate({email:"a.b@c.com", tags:['x,y', 2.5], admin: true, left: null})`
+		t.Logf("\tWhen giving a query string %q", q)
+		{
+			query, err := parser.Parse(q)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have parsed the query without error: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have parsed the query without error", tests.Success)
+
+			if len(query.Path) != 2 || len(query.Calls) != 2 {
+				t.Fatalf("\t%s\tShould have retrieved 2 path segments and 2 calls: %d %d", tests.Failed, len(query.Path), len(query.Calls))
+			}
+			t.Logf("\t%s\tShould have retrieved 2 path segments and 2 calls", tests.Success)
+
+			if num, ok := query.Calls[0].Args[1].(*parser.NumberLit); !ok || !num.IsInt || num.Int != 3 {
+				t.Fatalf("\t%s\tShould have retrieved integer argument 3: %#v", tests.Failed, query.Calls[0].Args[1])
+			}
+			t.Logf("\t%s\tShould have retrieved integer argument 3", tests.Success)
+
+			obj, ok := query.Calls[1].Args[0].(*parser.ObjectLit)
+			if !ok {
+				t.Fatalf("\t%s\tShould have retrieved an object argument", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have retrieved an object argument", tests.Success)
+
+			mo := obj.Interface().(map[string]interface{})
+
+			if mo["email"] != "a.b@c.com" || mo["admin"] != true || mo["left"] != nil {
+				t.Fatalf("\t%s\tShould have retrieved the object values: %+v", tests.Failed, mo)
+			}
+			t.Logf("\t%s\tShould have retrieved the object values", tests.Success)
+
+			tags := mo["tags"].([]interface{})
+			if len(tags) != 2 || tags[0] != "x,y" || tags[1] != 2.5 {
+				t.Fatalf("\t%s\tShould have retrieved the array values: %+v", tests.Failed, tags)
+			}
+			t.Logf("\t%s\tShould have retrieved the array values", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
+
+// TestSyntaxErrors validates that malformed queries return a positioned
+// syntax error.
+func TestSyntaxErrors(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	cases := []struct {
+		query  string
+		line   int
+		column int
+	}{
+		{"docs.users.find(id,3", 1, 21},
+		{"docs.users.find(id,'alex)", 1, 20},
+		{"docs.users.find(id,3).collects(name,)", 1, 37},
+		{"docs.users.find(id,3)\n.mutate({name \"alex\"})", 2, 15},
+		{"docs.users.find(id,3).age", 1, 23},
+		{"docs.users.findN(10x)", 1, 18},
+		{"docs.users.find(id,#)", 1, 20},
+		{"docs.users.where(age > 3 = 3)", 1, 26},
+		{"docs.users.mutate({age:3}, ifVersion=2, 4)", 1, 41},
+		{"docs.users.mutate({age:3}, ifVersion=2, ifVersion=3)", 1, 41},
+		{"docs.users.mutate({age:3}, ifVersion=)", 1, 38},
+		{"docs.users.where((age > 3)", 1, 27},
+		{"docs.users.where(age > )", 1, 24},
+		{"docs.users.sort(-)", 1, 18},
+	}
+
+	t.Logf("Given the need to report malformed queries")
+	{
+		for _, tc := range cases {
+			t.Logf("\tWhen giving a query string %q", tc.query)
+			{
+				_, err := parser.Parse(tc.query)
+				if err == nil {
+					t.Fatalf("\t%s\tShould have failed to parse the query", tests.Failed)
+				}
+
+				serr, ok := err.(*parser.SyntaxError)
+				if !ok {
+					t.Fatalf("\t%s\tShould have returned a *parser.SyntaxError: %T", tests.Failed, err)
+				}
+
+				if serr.Pos.Line != tc.line || serr.Pos.Column != tc.column {
+					t.Fatalf("\t%s\tShould have reported line %d, column %d: %s", tests.Failed, tc.line, tc.column, serr)
+				}
+				t.Logf("\t%s\tShould have reported line %d, column %d: %s", tests.Success, tc.line, tc.column, serr)
+			}
+		}
+	}
+}
+
+//==============================================================================
+
+// TestObjectIDArguments validates the parsing of object id literals.
+func TestObjectIDArguments(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to parse object id arguments")
+	{
+
+		q := `docs.users.find(_id,oid("5707a1d1e4b0e5a0c8f2b1a3"))`
+		t.Logf("\tWhen giving a query string %q", q)
+		{
+			query, err := parser.Parse(q)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have parsed the query without error: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have parsed the query without error", tests.Success)
+
+			if id, ok := query.Calls[0].Args[1].(*parser.ObjectIDLit); !ok || id.Hex != "5707a1d1e4b0e5a0c8f2b1a3" {
+				t.Fatalf("\t%s\tShould have retrieved an object id argument: %#v", tests.Failed, query.Calls[0].Args[1])
+			}
+			t.Logf("\t%s\tShould have retrieved an object id argument", tests.Success)
+		}
+
+		q = `docs.users.find(_id,oid("5707a1"))`
+		t.Logf("\tWhen giving a query string %q", q)
+		{
+			if _, err := parser.Parse(q); err == nil {
+				t.Fatalf("\t%s\tShould have failed to parse a short object id", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have failed to parse a short object id", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
+
+// TestExpressionArguments validates the parsing of comparison and logical
+// expressions within method arguments.
+func TestExpressionArguments(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to parse expression arguments")
+	{
+
+		q := `docs.users.where(age >= 21 || name == "alex" && !(tags not in ["a",'b']))`
+		t.Logf("\tWhen giving a query string %q", q)
+		{
+			query, err := parser.Parse(q)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have parsed the query without error: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have parsed the query without error", tests.Success)
+
+			or, ok := query.Calls[0].Args[0].(*parser.BinaryExpr)
+			if !ok || or.Op != "||" {
+				t.Fatalf("\t%s\tShould have retrieved a '||' expression: %#v", tests.Failed, query.Calls[0].Args[0])
+			}
+			t.Logf("\t%s\tShould have retrieved a '||' expression", tests.Success)
+
+			if cmp, ok := or.Left.(*parser.BinaryExpr); !ok || cmp.Op != ">=" || cmp.Left.(*parser.Ident).Name != "age" {
+				t.Fatalf("\t%s\tShould have retrieved a '>=' comparison on the left: %#v", tests.Failed, or.Left)
+			}
+			t.Logf("\t%s\tShould have retrieved a '>=' comparison on the left", tests.Success)
+
+			and, ok := or.Right.(*parser.BinaryExpr)
+			if !ok || and.Op != "&&" {
+				t.Fatalf("\t%s\tShould have retrieved a '&&' expression on the right: %#v", tests.Failed, or.Right)
+			}
+			t.Logf("\t%s\tShould have retrieved a '&&' expression on the right", tests.Success)
+
+			not, ok := and.Right.(*parser.UnaryExpr)
+			if !ok {
+				t.Fatalf("\t%s\tShould have retrieved a negated expression: %#v", tests.Failed, and.Right)
+			}
+			t.Logf("\t%s\tShould have retrieved a negated expression", tests.Success)
+
+			if nin, ok := not.X.(*parser.BinaryExpr); !ok || nin.Op != "not in" {
+				t.Fatalf("\t%s\tShould have retrieved a 'not in' comparison: %#v", tests.Failed, not.X)
+			}
+			t.Logf("\t%s\tShould have retrieved a 'not in' comparison", tests.Success)
+		}
+	}
+}
+
+// TestNamedArguments validates the parsing of named arguments.
+func TestNamedArguments(t *testing.T) {
+	tests.ResetLog()
+	defer tests.DisplayLog()
+
+	t.Logf("Given the need to parse named arguments")
+	{
+
+		q := `docs.users.find(id,3).mutate({views:2}, ifVersion=7)`
+		t.Logf("\tWhen giving a query string %q", q)
+		{
+			query, err := parser.Parse(q)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have parsed the query without error: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have parsed the query without error", tests.Success)
+
+			call := query.Calls[1]
+
+			if len(call.Args) != 1 || len(call.Named) != 1 {
+				t.Fatalf("\t%s\tShould have retrieved one argument and one named argument: %#v", tests.Failed, call)
+			}
+			t.Logf("\t%s\tShould have retrieved one argument and one named argument", tests.Success)
+
+			arg := call.Arg("ifVersion")
+			if arg == nil {
+				t.Fatalf("\t%s\tShould have retrieved the ifVersion argument", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have retrieved the ifVersion argument", tests.Success)
+
+			if num, ok := arg.Value.(*parser.NumberLit); !ok || num.Int != 7 {
+				t.Fatalf("\t%s\tShould have retrieved the value 7: %#v", tests.Failed, arg.Value)
+			}
+			t.Logf("\t%s\tShould have retrieved the value 7", tests.Success)
+		}
+	}
+}
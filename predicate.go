@@ -0,0 +1,187 @@
+package coquery
+
+import (
+	"fmt"
+
+	"github.com/influx6/coquery/parser"
+)
+
+//==============================================================================
+
+// contains the operators used by Predicate nodes.
+const (
+	OpAnd = "and"
+	OpOr  = "or"
+	OpNot = "not"
+	OpEq  = "eq"
+	OpNe  = "ne"
+	OpGt  = "gt"
+	OpGte = "gte"
+	OpLt  = "lt"
+	OpLte = "lte"
+	OpIn  = "in"
+	OpNin = "nin"
+)
+
+// Predicate defines a node of the filter tree generated from a where(...)
+// query method. Logical nodes (and, or, not) hold their Operands while
+// comparison nodes hold the record Field and the Value compared against, for
+// the in and nin operators, Value is a []interface{}.
+type Predicate struct {
+	Op       string       `json:"op" bson:"op"`
+	Field    string       `json:"field,omitempty" bson:"field,omitempty"`
+	Value    interface{}  `json:"value,omitempty" bson:"value,omitempty"`
+	Operands []*Predicate `json:"operands,omitempty" bson:"operands,omitempty"`
+}
+
+// String returns a readable version of the predicate.
+func (p *Predicate) String() string {
+	switch p.Op {
+	case OpAnd, OpOr, OpNot:
+		return fmt.Sprintf("%s%s", p.Op, p.Operands)
+	default:
+		return fmt.Sprintf("%s(%s,%#v)", p.Op, p.Field, p.Value)
+	}
+}
+
+// Fields returns the record fields compared by the predicate and its operands,
+// a nil predicate compares none.
+func (p *Predicate) Fields() []string {
+	if p == nil {
+		return nil
+	}
+
+	if p.Field != "" {
+		return []string{p.Field}
+	}
+
+	var fields []string
+
+	for _, operand := range p.Operands {
+		fields = append(fields, operand.Fields()...)
+	}
+
+	return fields
+}
+
+//==============================================================================
+
+// binaryOps maps the parser expression operators to their predicate operators.
+var binaryOps = map[string]string{
+	"&&":     OpAnd,
+	"||":     OpOr,
+	"==":     OpEq,
+	"!=":     OpNe,
+	">":      OpGt,
+	">=":     OpGte,
+	"<":      OpLt,
+	"<=":     OpLte,
+	"in":     OpIn,
+	"not in": OpNin,
+}
+
+// flippedOps maps the comparison operators to their counterparts when the
+// field and value sides are swapped eg 21 < age becomes age > 21.
+var flippedOps = map[string]string{
+	OpEq:  OpEq,
+	OpNe:  OpNe,
+	OpGt:  OpLt,
+	OpGte: OpLte,
+	OpLt:  OpGt,
+	OpLte: OpGte,
+}
+
+// NewPredicate returns the predicate tree for the giving argument of a
+// where(...) call. A bare field name eg where(active) is taken as active ==
+// true. A *parser.SyntaxError is returned for expressions which do not
+// compare a field against a value.
+func NewPredicate(call *parser.Call, arg parser.Node) (*Predicate, error) {
+	switch expr := arg.(type) {
+	case *parser.Ident:
+		return &Predicate{Op: OpEq, Field: expr.Name, Value: true}, nil
+
+	case *parser.UnaryExpr:
+		if expr.Op != "!" {
+			return nil, call.Errorf(expr, fmt.Sprintf("operator %q can not be used within a filter", expr.Op))
+		}
+
+		operand, err := NewPredicate(call, expr.X)
+		if err != nil {
+			return nil, err
+		}
+
+		return &Predicate{Op: OpNot, Operands: []*Predicate{operand}}, nil
+
+	case *parser.BinaryExpr:
+		op := binaryOps[expr.Op]
+
+		if op == OpAnd || op == OpOr {
+			left, err := NewPredicate(call, expr.Left)
+			if err != nil {
+				return nil, err
+			}
+
+			right, err := NewPredicate(call, expr.Right)
+			if err != nil {
+				return nil, err
+			}
+
+			// Flatten chains of the same operator eg a && b && c into a single
+			// node.
+			var operands []*Predicate
+			for _, sub := range []*Predicate{left, right} {
+				if sub.Op == op {
+					operands = append(operands, sub.Operands...)
+					continue
+				}
+
+				operands = append(operands, sub)
+			}
+
+			return &Predicate{Op: op, Operands: operands}, nil
+		}
+
+		if op == "" {
+			return nil, call.Errorf(expr, fmt.Sprintf("operator %q can not be used within a filter", expr.Op))
+		}
+
+		return comparison(call, expr, op)
+	}
+
+	return nil, call.Errorf(arg, "expected a comparison eg age > 21")
+}
+
+// comparison returns the predicate for a comparison expression.
+func comparison(call *parser.Call, expr *parser.BinaryExpr, op string) (*Predicate, error) {
+	field, fok := expr.Left.(*parser.Ident)
+	value, vok := expr.Right.(parser.Value)
+
+	// Allow the value to come first for the ordering operators eg 21 < age.
+	if !fok && flippedOps[op] != "" {
+		if lv, ok := expr.Left.(parser.Value); ok {
+			if rf, ok := expr.Right.(*parser.Ident); ok {
+				field, fok = rf, true
+				value, vok = lv, true
+				op = flippedOps[op]
+			}
+		}
+	}
+
+	if !fok {
+		return nil, call.Errorf(expr.Left, fmt.Sprintf("expected a field name on the left of %q", expr.Op))
+	}
+
+	if !vok {
+		return nil, call.Errorf(expr.Right, fmt.Sprintf("expected a value on the right of %q", expr.Op))
+	}
+
+	if op == OpIn || op == OpNin {
+		if _, ok := value.(*parser.ArrayLit); !ok {
+			return nil, call.Errorf(value, fmt.Sprintf("expected an array on the right of %q", expr.Op))
+		}
+	}
+
+	return &Predicate{Op: op, Field: field.Name, Value: value.Interface()}, nil
+}
+
+//==============================================================================
@@ -0,0 +1,374 @@
+package cohttp
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/influx6/coquery/data"
+)
+
+//==============================================================================
+
+// ErrInvalidCredentials is returned when the credentials of a request fail
+// to authenticate it.
+var ErrInvalidCredentials = errors.New("Invalid Credentials")
+
+// Authenticator defines a interface for authenticating the callers of http
+// requests, given the request and its body. The principal of the caller is
+// returned, else an error if the credentials are invalid. A nil principal and
+// error are returned when the request lacks the credentials the Authenticator
+// expects, leaving it to the next Authenticator.
+type Authenticator interface {
+	Authenticate(req *http.Request, body []byte) (*data.Principal, error)
+}
+
+// now returns the current time, used when checking the age of credentials.
+var now = time.Now
+
+//==============================================================================
+
+// APIKeyHeader defines the default header holding the key of a request
+// authenticated by APIKeys.
+const APIKeyHeader = "X-API-Key"
+
+// APIKeys provides a Authenticator of static API keys sent in the Header
+// (APIKeyHeader by default), mapping each key to its principal.
+type APIKeys struct {
+	Header string
+	Keys   map[string]*data.Principal
+}
+
+// Authenticate implements the Authenticator interface.
+func (a *APIKeys) Authenticate(req *http.Request, body []byte) (*data.Principal, error) {
+	header := a.Header
+	if header == "" {
+		header = APIKeyHeader
+	}
+
+	key := req.Header.Get(header)
+	if key == "" {
+		return nil, nil
+	}
+
+	for k, p := range a.Keys {
+		if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+			return p, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s : Unknown API Key", ErrInvalidCredentials)
+}
+
+//==============================================================================
+
+// contains the headers of requests signed for HMACAuth.
+const (
+	HMACKeyHeader       = "X-Coquery-Key"
+	HMACTimestampHeader = "X-Coquery-Timestamp"
+	HMACSignatureHeader = "X-Coquery-Signature"
+)
+
+// HMACKey defines the secret a caller signs its requests with and its
+// principal.
+type HMACKey struct {
+	Secret    []byte
+	Principal *data.Principal
+}
+
+// HMACAuth provides a Authenticator of requests signed with a shared secret
+// (see SignRequest). The signature is the hex encoded HMAC-SHA256 of the
+// request's method, url, timestamp and body, each on its own line. Requests
+// whose timestamp differs from the current time by more than MaxSkew, five
+// minutes by default, are rejected to limit their replay.
+type HMACAuth struct {
+	Keys    map[string]HMACKey
+	MaxSkew time.Duration
+}
+
+// Authenticate implements the Authenticator interface.
+func (h *HMACAuth) Authenticate(req *http.Request, body []byte) (*data.Principal, error) {
+	signature := req.Header.Get(HMACSignatureHeader)
+	if signature == "" {
+		return nil, nil
+	}
+
+	key, ok := h.Keys[req.Header.Get(HMACKeyHeader)]
+	if !ok {
+		return nil, fmt.Errorf("%s : Unknown Signing Key", ErrInvalidCredentials)
+	}
+
+	stamp := req.Header.Get(HMACTimestampHeader)
+
+	secs, err := strconv.ParseInt(stamp, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%s : Invalid Timestamp", ErrInvalidCredentials)
+	}
+
+	skew := h.MaxSkew
+	if skew == 0 {
+		skew = 5 * time.Minute
+	}
+
+	if age := now().Sub(time.Unix(secs, 0)); age > skew || age < -skew {
+		return nil, fmt.Errorf("%s : Expired Signature", ErrInvalidCredentials)
+	}
+
+	sig, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(sig, signRequest(key.Secret, req, stamp, body)) {
+		return nil, fmt.Errorf("%s : Invalid Signature", ErrInvalidCredentials)
+	}
+
+	return key.Principal, nil
+}
+
+// SignRequest sets the headers signing the request with the giving key for
+// HMACAuth, the body being the body the request is sent with.
+func SignRequest(req *http.Request, key string, secret []byte, body []byte) {
+	stamp := strconv.FormatInt(now().Unix(), 10)
+
+	req.Header.Set(HMACKeyHeader, key)
+	req.Header.Set(HMACTimestampHeader, stamp)
+	req.Header.Set(HMACSignatureHeader, hex.EncodeToString(signRequest(secret, req, stamp, body)))
+}
+
+// signRequest returns the HMAC-SHA256 signature of the request.
+func signRequest(secret []byte, req *http.Request, stamp string, body []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	fmt.Fprintf(mac, "%s\n%s\n%s\n", strings.ToUpper(req.Method), req.URL.RequestURI(), stamp)
+	mac.Write(body)
+	return mac.Sum(nil)
+}
+
+//==============================================================================
+
+// JWTAuth provides a Authenticator of JSON Web Tokens sent as bearer tokens
+// in the Authorization header, verified with local keys. Keys maps the key id
+// ("kid") of a token's header to its key, with Key used for tokens without a
+// known key id. Keys are a []byte secret for the HS256, HS384 and HS512
+// algorithms, a *rsa.PublicKey for RS256, RS384 and RS512 or a
+// *ecdsa.PublicKey for ES256, ES384 and ES512.
+//
+// The expiry and not before claims are checked allowing for Leeway, as are
+// the issuer and audience claims when Issuer and Audience are set. The
+// principal's id is the subject claim and its roles those listed by the
+// RolesClaim, "roles" by default, as a list or a space separated string.
+type JWTAuth struct {
+	Key        interface{}
+	Keys       map[string]interface{}
+	Issuer     string
+	Audience   string
+	Leeway     time.Duration
+	RolesClaim string
+}
+
+// Authenticate implements the Authenticator interface.
+func (j *JWTAuth) Authenticate(req *http.Request, body []byte) (*data.Principal, error) {
+	auth := req.Header.Get("Authorization")
+	if len(auth) < 7 || !strings.EqualFold(auth[:7], "Bearer ") {
+		return nil, nil
+	}
+
+	claims, err := j.Verify(strings.TrimSpace(auth[7:]))
+	if err != nil {
+		return nil, err
+	}
+
+	rolesClaim := j.RolesClaim
+	if rolesClaim == "" {
+		rolesClaim = "roles"
+	}
+
+	p := data.Principal{Claims: claims}
+	p.ID, _ = claims["sub"].(string)
+
+	switch roles := claims[rolesClaim].(type) {
+	case string:
+		p.Roles = strings.Fields(roles)
+	case []interface{}:
+		for _, role := range roles {
+			if name, ok := role.(string); ok {
+				p.Roles = append(p.Roles, name)
+			}
+		}
+	}
+
+	return &p, nil
+}
+
+// Verify verifies the signature and claims of the token, returning its
+// claims.
+func (j *JWTAuth) Verify(token string) (map[string]interface{}, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%s : Malformed Token", ErrInvalidCredentials)
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%s : Malformed Token Header", ErrInvalidCredentials)
+	}
+
+	key := j.Key
+	if k, ok := j.Keys[header.Kid]; ok {
+		key = k
+	}
+
+	if key == nil {
+		return nil, fmt.Errorf("%s : Unknown Token Key[%s]", ErrInvalidCredentials, header.Kid)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%s : Malformed Token Signature", ErrInvalidCredentials)
+	}
+
+	if err := verifySignature(header.Alg, key, parts[0]+"."+parts[1], sig); err != nil {
+		return nil, fmt.Errorf("%s : %s", ErrInvalidCredentials, err)
+	}
+
+	var claims map[string]interface{}
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%s : Malformed Token Claims", ErrInvalidCredentials)
+	}
+
+	current := now()
+
+	if exp, ok := claims["exp"].(float64); ok && current.After(time.Unix(int64(exp), 0).Add(j.Leeway)) {
+		return nil, fmt.Errorf("%s : Expired Token", ErrInvalidCredentials)
+	}
+
+	if nbf, ok := claims["nbf"].(float64); ok && current.Before(time.Unix(int64(nbf), 0).Add(-j.Leeway)) {
+		return nil, fmt.Errorf("%s : Token Not Yet Valid", ErrInvalidCredentials)
+	}
+
+	if j.Issuer != "" && claims["iss"] != j.Issuer {
+		return nil, fmt.Errorf("%s : Invalid Token Issuer", ErrInvalidCredentials)
+	}
+
+	if j.Audience != "" && !hasAudience(claims["aud"], j.Audience) {
+		return nil, fmt.Errorf("%s : Invalid Token Audience", ErrInvalidCredentials)
+	}
+
+	return claims, nil
+}
+
+// decodeSegment decodes the base64url encoded JSON segment of a token.
+func decodeSegment(segment string, v interface{}) error {
+	raw, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(raw, v)
+}
+
+// verifySignature verifies the signature of the signed content with the key
+// of the giving algorithm, the type of the key having to match the algorithm.
+func verifySignature(alg string, key interface{}, signed string, sig []byte) error {
+	var hashed func() hash.Hash
+	var ch crypto.Hash
+
+	switch strings.TrimLeft(alg, "HRSE") {
+	case "256":
+		hashed, ch = sha256.New, crypto.SHA256
+	case "384":
+		hashed, ch = sha512.New384, crypto.SHA384
+	case "512":
+		hashed, ch = sha512.New, crypto.SHA512
+	default:
+		return fmt.Errorf("Unsupported Algorithm[%s]", alg)
+	}
+
+	switch {
+	case strings.HasPrefix(alg, "HS"):
+		secret, ok := key.([]byte)
+		if !ok {
+			return fmt.Errorf("Algorithm[%s] Requires A Secret", alg)
+		}
+
+		mac := hmac.New(hashed, secret)
+		mac.Write([]byte(signed))
+
+		if !hmac.Equal(sig, mac.Sum(nil)) {
+			return errors.New("Invalid Token Signature")
+		}
+
+	case strings.HasPrefix(alg, "RS"):
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("Algorithm[%s] Requires A RSA Key", alg)
+		}
+
+		h := hashed()
+		h.Write([]byte(signed))
+
+		if err := rsa.VerifyPKCS1v15(pub, ch, h.Sum(nil), sig); err != nil {
+			return errors.New("Invalid Token Signature")
+		}
+
+	case strings.HasPrefix(alg, "ES"):
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return fmt.Errorf("Algorithm[%s] Requires A ECDSA Key", alg)
+		}
+
+		size := (pub.Curve.Params().BitSize + 7) / 8
+		if len(sig) != 2*size {
+			return errors.New("Invalid Token Signature")
+		}
+
+		h := hashed()
+		h.Write([]byte(signed))
+
+		r := new(big.Int).SetBytes(sig[:size])
+		s := new(big.Int).SetBytes(sig[size:])
+
+		if !ecdsa.Verify(pub, h.Sum(nil), r, s) {
+			return errors.New("Invalid Token Signature")
+		}
+
+	default:
+		return fmt.Errorf("Unsupported Algorithm[%s]", alg)
+	}
+
+	return nil
+}
+
+// hasAudience returns true/false if the audience claim, a string or list of
+// strings, holds the giving audience.
+func hasAudience(claim interface{}, audience string) bool {
+	switch aud := claim.(type) {
+	case string:
+		return aud == audience
+	case []interface{}:
+		for _, item := range aud {
+			if item == audience {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+//==============================================================================
@@ -0,0 +1,189 @@
+package cohttp_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/protocols/cohttp"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+var events eventlog
+
+// eventlog provides a logger which discards its events.
+type eventlog struct{}
+
+// Log discards the standard log reports.
+func (l eventlog) Log(context interface{}, name string, message string, data ...interface{}) {}
+
+// Error discards the error reports.
+func (l eventlog) Error(context interface{}, name string, err error, message string, data ...interface{}) {
+}
+
+//==============================================================================
+
+// token returns a JWT of the giving claims signed by the sign function.
+func token(alg string, claims map[string]interface{}, sign func(signed []byte) []byte) string {
+	header, _ := json.Marshal(map[string]string{"alg": alg, "typ": "JWT"})
+	body, _ := json.Marshal(claims)
+
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(body)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(sign([]byte(signed)))
+}
+
+// hmacSHA256 returns the HMAC-SHA256 of the content.
+func hmacSHA256(secret []byte, content []byte) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(content)
+	return mac.Sum(nil)
+}
+
+//==============================================================================
+
+// TestAuthenticators validates the authentication of http requests.
+func TestAuthenticators(t *testing.T) {
+	t.Logf("Given the need to authenticate the callers of http requests")
+	{
+		alex := &data.Principal{ID: "alex"}
+
+		t.Logf("\tWhen giving requests with API keys")
+		{
+			auth := &cohttp.APIKeys{Keys: map[string]*data.Principal{"4ab3": alex}}
+
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set(cohttp.APIKeyHeader, "4ab3")
+
+			if p, err := auth.Authenticate(req, nil); err != nil || p != alex {
+				t.Fatalf("\t%s\tShould have authenticated the known key: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have authenticated the known key", tests.Success)
+
+			req.Header.Set(cohttp.APIKeyHeader, "4ab4")
+
+			if _, err := auth.Authenticate(req, nil); err == nil {
+				t.Fatalf("\t%s\tShould have failed the unknown key", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have failed the unknown key", tests.Success)
+		}
+
+		t.Logf("\tWhen giving signed requests")
+		{
+			secret := []byte("s3cr3t")
+			auth := &cohttp.HMACAuth{Keys: map[string]cohttp.HMACKey{"app": {Secret: secret, Principal: alex}}}
+
+			body := []byte(`{"queries":["docs.users.findN(1)"]}`)
+
+			req, _ := http.NewRequest("POST", "/coquery", bytes.NewReader(body))
+			cohttp.SignRequest(req, "app", secret, body)
+
+			if p, err := auth.Authenticate(req, body); err != nil || p != alex {
+				t.Fatalf("\t%s\tShould have authenticated the signed request: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have authenticated the signed request", tests.Success)
+
+			if _, err := auth.Authenticate(req, []byte(`{"queries":["docs.users.findN(-1)"]}`)); err == nil {
+				t.Fatalf("\t%s\tShould have failed the altered body", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have failed the altered body", tests.Success)
+		}
+
+		t.Logf("\tWhen giving requests with bearer tokens")
+		{
+			secret := []byte("s3cr3t")
+			rsaKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+
+			auth := &cohttp.JWTAuth{Key: secret, Keys: map[string]interface{}{}, Issuer: "coquery"}
+
+			hs256 := token("HS256", map[string]interface{}{
+				"sub":   "alex",
+				"iss":   "coquery",
+				"roles": []string{"admin"},
+				"exp":   time.Now().Add(time.Hour).Unix(),
+			}, func(signed []byte) []byte {
+				return hmacSHA256(secret, signed)
+			})
+
+			req, _ := http.NewRequest("GET", "/", nil)
+			req.Header.Set("Authorization", "Bearer "+hs256)
+
+			p, err := auth.Authenticate(req, nil)
+			if err != nil || p.ID != "alex" || !p.HasRole("admin") {
+				t.Fatalf("\t%s\tShould have authenticated the HS256 token: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have authenticated the HS256 token", tests.Success)
+
+			expired := token("HS256", map[string]interface{}{
+				"sub": "alex",
+				"iss": "coquery",
+				"exp": time.Now().Add(-time.Hour).Unix(),
+			}, func(signed []byte) []byte {
+				return hmacSHA256(secret, signed)
+			})
+
+			if _, err := auth.Verify(expired); err == nil {
+				t.Fatalf("\t%s\tShould have failed the expired token", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have failed the expired token", tests.Success)
+
+			rs256 := token("RS256", map[string]interface{}{"sub": "ruth", "iss": "coquery"}, func(signed []byte) []byte {
+				digest := sha256.Sum256(signed)
+				sig, _ := rsa.SignPKCS1v15(rand.Reader, rsaKey, crypto.SHA256, digest[:])
+				return sig
+			})
+
+			if _, err := auth.Verify(rs256); err == nil {
+				t.Fatalf("\t%s\tShould have failed the RS256 token with a secret", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have failed the RS256 token with a secret", tests.Success)
+
+			auth.Key = &rsaKey.PublicKey
+
+			if claims, err := auth.Verify(rs256); err != nil || claims["sub"] != "ruth" {
+				t.Fatalf("\t%s\tShould have verified the RS256 token: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have verified the RS256 token", tests.Success)
+		}
+
+		t.Logf("\tWhen requiring authentication")
+		{
+			server := cohttp.New(events, coquery.NewDiffs(events), storage.New("id"))
+			server.UseAuthenticators(&cohttp.APIKeys{Keys: map[string]*data.Principal{"4ab3": alex}})
+			server.RequireAuthentication()
+
+			req, _ := http.NewRequest("GET", "/_schema", nil)
+			res := httptest.NewRecorder()
+			server.ServeHTTP(res, req)
+
+			if res.Code != http.StatusUnauthorized {
+				t.Fatalf("\t%s\tShould have rejected the anonymous request: %d", tests.Failed, res.Code)
+			}
+			t.Logf("\t%s\tShould have rejected the anonymous request", tests.Success)
+
+			req.Header.Set(cohttp.APIKeyHeader, "4ab3")
+			res = httptest.NewRecorder()
+			server.ServeHTTP(res, req)
+
+			if res.Code != http.StatusOK {
+				t.Fatalf("\t%s\tShould have served the authenticated request: %d", tests.Failed, res.Code)
+			}
+			t.Logf("\t%s\tShould have served the authenticated request", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
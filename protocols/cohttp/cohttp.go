@@ -4,12 +4,16 @@
 package cohttp
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"os"
 	"os/signal"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/influx6/coquery"
 	"github.com/influx6/coquery/data"
@@ -43,7 +47,48 @@ func (h *ResWriter) Write(context interface{}, rs *coquery.Response, re coquery.
 
 	if re != nil {
 		h.Error(context, "cohttp.ResWriter.Write", re, "Completed")
-		h.res.WriteHeader(http.StatusBadRequest)
+
+		// Field validation failures are replied as JSON, allowing clients
+		// to report them against the fields, as are exceeded limits naming
+		// the limit hit.
+		var status int
+
+		switch re.(type) {
+		case *coquery.ValidationError:
+			status = http.StatusUnprocessableEntity
+		case *coquery.LimitError:
+			status = http.StatusBadRequest
+		}
+
+		if status != 0 {
+			data, err := json.Marshal(re)
+			if err != nil {
+				h.Error(context, "cohttp.ResWriter.Write", err, "Info : JSON.Marshal")
+				return err
+			}
+
+			h.res.Header().Set("Content-Type", "application/json")
+			h.res.WriteHeader(status)
+			_, err = h.res.Write(data)
+			return err
+		}
+
+		// Version conflicts are reported distinctly, allowing clients to reload
+		// the records and retry, as are queries denied access.
+		switch rerr := re.(type) {
+		case *coquery.ConflictError:
+			h.res.WriteHeader(http.StatusConflict)
+		case *coquery.CoError:
+			if rerr.IError == coquery.ErrAccessDenied {
+				h.res.WriteHeader(http.StatusForbidden)
+				break
+			}
+
+			h.res.WriteHeader(http.StatusBadRequest)
+		default:
+			h.res.WriteHeader(http.StatusBadRequest)
+		}
+
 		_, err := h.res.Write([]byte(re.Error()))
 		if err != nil {
 			h.Error(context, "cohttp.ResWriter.Write", err, "Info : Response Write Error")
@@ -94,6 +139,9 @@ type CoqueryHTTP interface {
 	http.Handler
 	ListenAndServe(context interface{}, addr string)
 	EnableCORS()
+	UseAuthenticators(auths ...Authenticator)
+	RequireAuthentication()
+	UseRateLimiter(limiter *RateLimiter)
 }
 
 // New returns a new CoqueryHTTP http server to respond to all coquery requests.
@@ -113,7 +161,10 @@ func New(e EventLog, diff coquery.Diffs, store storage.Store) CoqueryHTTP {
 type httpCoquery struct {
 	EventLog
 	coquery.Engine
-	useCORS bool
+	useCORS     bool
+	requireAuth bool
+	auths       []Authenticator
+	limiter     *RateLimiter
 }
 
 // ListenAndServe runs a http server with the httpCoquery instance wired
@@ -140,6 +191,133 @@ func (h *httpCoquery) EnableCORS() {
 	h.useCORS = true
 }
 
+// SchemaPath defines the url path suffix which describes the routes, documents
+// and query methods served, eg GET /_schema.
+const SchemaPath = "/_schema"
+
+// MaxBodySize sets the largest request body read in bytes, larger requests
+// being rejected with a 413 status.
+var MaxBodySize int64 = 1 << 20
+
+// serveSchema writes the JSON description of the engine to the response.
+func (h *httpCoquery) serveSchema(res http.ResponseWriter) {
+	h.Log("HTTPCoquery", "serveSchema", "Started")
+
+	data, err := json.Marshal(h.Describe("HTTPCoquery"))
+	if err != nil {
+		h.Error("HTTPCoquery", "serveSchema", err, "Completed")
+		res.WriteHeader(http.StatusInternalServerError)
+		res.Write([]byte(err.Error()))
+		return
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+	res.Header().Set("Content-Length", fmt.Sprintf("%d", len(data)))
+	res.Write(data)
+
+	h.Log("HTTPCoquery", "serveSchema", "Completed")
+}
+
+// UseAuthenticators adds the giving authenticators, which are tried in order
+// for every request until one returns the principal of its caller.
+func (h *httpCoquery) UseAuthenticators(auths ...Authenticator) {
+	h.auths = append(h.auths, auths...)
+}
+
+// RequireAuthentication flips the flag to reject requests which none of the
+// authenticators authenticate to true, else such requests are anonymous.
+func (h *httpCoquery) RequireAuthentication() {
+	h.requireAuth = true
+}
+
+// UseRateLimiter sets the RateLimiter charged with the queries of every
+// request, replacing any set before.
+func (h *httpCoquery) UseRateLimiter(limiter *RateLimiter) {
+	h.limiter = limiter
+}
+
+// rateLimit charges the request's queries to the RateLimiter if any, setting
+// the X-RateLimit-* headers of the response. False is returned if the caller
+// exceeded its rate limit, the 429 response having been written.
+func (h *httpCoquery) rateLimit(res http.ResponseWriter, req *http.Request, rctx *data.RequestContext) bool {
+	if h.limiter == nil {
+		return true
+	}
+
+	st, ok := h.limiter.Allow(req, rctx.Principal, rctx.Queries)
+	return h.rated(res, rctx.RequestID, st, ok)
+}
+
+// rated sets the X-RateLimit-* headers of the response from the status of the
+// budget charged. False is returned if the budget lacked the tokens needed,
+// the 429 response having been written, with a Retry-After header unless the
+// request can never be allowed.
+func (h *httpCoquery) rated(res http.ResponseWriter, rid string, st RateStatus, ok bool) bool {
+	if st.Limit > 0 {
+		res.Header().Set("X-RateLimit-Limit", strconv.Itoa(st.Limit))
+		res.Header().Set("X-RateLimit-Remaining", strconv.Itoa(st.Remaining))
+		res.Header().Set("X-RateLimit-Reset", strconv.Itoa(Seconds(st.Reset)))
+	}
+
+	if ok {
+		return true
+	}
+
+	cerr := &coquery.CoError{
+		Rid:    rid,
+		Msg:    fmt.Sprintf("Too Many Requests : Retry After %ds", Seconds(st.RetryAfter)),
+		IError: ErrRateLimited,
+	}
+
+	if st.Exceeds {
+		cerr.Msg = fmt.Sprintf("Too Many Queries : Request Exceeds The Limit Of %d", st.Limit)
+	}
+
+	h.Error("HTTPCoquery", "ServeHTTP", cerr, "Completed : Rate Limited")
+
+	body, err := json.Marshal(cerr)
+	if err != nil {
+		body = []byte(cerr.Error())
+	}
+
+	res.Header().Set("Content-Type", "application/json")
+
+	if !st.Exceeds {
+		res.Header().Set("Retry-After", strconv.Itoa(Seconds(st.RetryAfter)))
+	}
+
+	res.WriteHeader(http.StatusTooManyRequests)
+	res.Write(body)
+	return false
+}
+
+// Seconds returns the duration in whole seconds, rounded up, as replied by
+// the Retry-After and X-RateLimit-Reset headers.
+func Seconds(d time.Duration) int {
+	return int((d + time.Second - 1) / time.Second)
+}
+
+// authenticate returns the principal of the request's caller, which is nil
+// for anonymous callers.
+func (h *httpCoquery) authenticate(req *http.Request, body []byte) (*data.Principal, error) {
+	for _, auth := range h.auths {
+		p, err := auth.Authenticate(req, body)
+		if err != nil {
+			return nil, err
+		}
+
+		if p != nil {
+			return p, nil
+		}
+	}
+
+	if h.requireAuth {
+		return nil, fmt.Errorf("%s : Authentication Required", ErrInvalidCredentials)
+	}
+
+	return nil, nil
+}
+
 // ServeHTTP provides the http.Handler ServeHTTP method to serve http requests
 // to a coquery.Engine.
 func (h *httpCoquery) ServeHTTP(res http.ResponseWriter, req *http.Request) {
@@ -170,26 +348,79 @@ func (h *httpCoquery) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 		return
 	}
 
+	// The body is read upfront as authenticators may need it eg to verify
+	// its signature.
+	var body []byte
+
+	if req.Body != nil {
+		defer req.Body.Close()
+
+		var err error
+		if body, err = ioutil.ReadAll(http.MaxBytesReader(res, req.Body, MaxBodySize)); err != nil {
+			if _, ok := err.(*http.MaxBytesError); ok {
+				res.WriteHeader(http.StatusRequestEntityTooLarge)
+			} else {
+				res.WriteHeader(http.StatusBadRequest)
+			}
+
+			res.Write([]byte(err.Error()))
+			h.Error("HTTPCoquery", "ServeHTTP", err, "Completed : Read Body")
+			return
+		}
+
+		req.Body = ioutil.NopCloser(bytes.NewReader(body))
+	}
+
+	principal, err := h.authenticate(req, body)
+	if err != nil {
+		// Failed authentications are charged to the caller, limiting those
+		// guessing credentials.
+		if h.limiter != nil {
+			if st, ok := h.limiter.Fail(req); !h.rated(res, "", st, ok) {
+				return
+			}
+		}
+
+		res.WriteHeader(http.StatusUnauthorized)
+		res.Write([]byte(err.Error()))
+		h.Error("HTTPCoquery", "ServeHTTP", err, "Completed : Authentication")
+		return
+	}
+
+	// Describe the routes, documents and query methods being served.
+	if method == "get" && strings.HasSuffix(req.URL.Path, SchemaPath) {
+		h.serveSchema(res)
+		h.Log("HTTPCoquery", "ServeHTTP", "Completed")
+		return
+	}
+
 	req.ParseForm()
 	// req.ParseMultipartForm(maxMemory int64)
 
 	var rctx data.RequestContext
 
 	// contentType := req.Header.Get("Content-Type")
-	defer req.Body.Close()
 
 	if method == "post" {
 
-		if err := json.NewDecoder(req.Body).Decode(&rctx); err != nil {
+		if err := json.Unmarshal(body, &rctx); err != nil {
 			res.WriteHeader(http.StatusBadRequest)
 			res.Write([]byte(err.Error()))
 			h.Error("HTTPCoquery", "ServeHTTP", err, "Completed : JSON Encoding")
 			return
 		}
 
+		rctx.Principal = principal
+
 		res.Header().Set("X-Coquery-Request-ID", rctx.RequestID)
 
-		h.Serve("httpCoquery", &rctx, &ResWriter{
+		if !h.rateLimit(res, req, &rctx) {
+			return
+		}
+
+		// The request's context abandons the work done for it once the
+		// client goes away.
+		h.Serve("httpCoquery", rctx.WithContext(req.Context()), &ResWriter{
 			EventLog: h.EventLog,
 			res:      res,
 			req:      req,
@@ -217,10 +448,15 @@ func (h *httpCoquery) ServeHTTP(res http.ResponseWriter, req *http.Request) {
 	}
 
 	rctx.Queries = []string{qrs}
+	rctx.Principal = principal
 
 	res.Header().Set("X-Coquery-Request-ID", rctx.RequestID)
 
-	h.Serve(rctx.RequestID, &rctx, &ResWriter{
+	if !h.rateLimit(res, req, &rctx) {
+		return
+	}
+
+	h.Serve(rctx.RequestID, rctx.WithContext(req.Context()), &ResWriter{
 		EventLog: h.EventLog,
 		res:      res,
 		req:      req,
@@ -0,0 +1,53 @@
+package cohttp_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/protocols/cohttp"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+// TestMaxBodySize validates the rejection of request bodies larger than
+// cohttp.MaxBodySize.
+func TestMaxBodySize(t *testing.T) {
+	t.Logf("Given the need to cap the size of request bodies")
+	{
+		server := cohttp.New(events, coquery.NewDiffs(events), storage.New("id"))
+
+		defer func(size int64) { cohttp.MaxBodySize = size }(cohttp.MaxBodySize)
+		cohttp.MaxBodySize = 64
+
+		t.Logf("\tWhen sending a body larger than the cap")
+		{
+			req, _ := http.NewRequest("POST", "/", bytes.NewReader(make([]byte, 65)))
+			res := httptest.NewRecorder()
+			server.ServeHTTP(res, req)
+
+			if res.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("\t%s\tShould have rejected the body as too large: %d", tests.Failed, res.Code)
+			}
+			t.Logf("\t%s\tShould have rejected the body as too large", tests.Success)
+		}
+
+		t.Logf("\tWhen sending a body within the cap")
+		{
+			req, _ := http.NewRequest("POST", "/", bytes.NewReader([]byte(`{"request_id":"532UFY"}`)))
+			res := httptest.NewRecorder()
+			server.ServeHTTP(res, req)
+
+			if res.Code == http.StatusRequestEntityTooLarge {
+				t.Fatalf("\t%s\tShould have read the body", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have read the body", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
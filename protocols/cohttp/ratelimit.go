@@ -0,0 +1,313 @@
+package cohttp
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/parser"
+)
+
+//==============================================================================
+
+// ErrRateLimited is held by the *coquery.CoError replied with a 429 status to
+// callers exceeding their rate limit.
+var ErrRateLimited = errors.New("Rate Limit Exceeded")
+
+// WriteMethods lists the query methods whose queries are charged to the
+// writes budget of a RateLimiter, other queries being charged to its reads
+// budget.
+var WriteMethods = []string{"mutate", "create", "remove"}
+
+// Rate defines a token bucket budget of Requests queries every Per duration,
+// where up to Burst queries, Requests by default, may be made at once. A zero
+// Rate leaves its queries unlimited.
+type Rate struct {
+	Requests int
+	Per      time.Duration
+	Burst    int
+}
+
+// RateKey defines a function returning the key whose budgets a request is
+// charged to, given the request and its principal which is nil for anonymous
+// callers.
+type RateKey func(req *http.Request, p *data.Principal) string
+
+// KeyByIP keys requests by the ip of their remote address.
+func KeyByIP(req *http.Request, p *data.Principal) string {
+	host, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		host = req.RemoteAddr
+	}
+
+	return "ip:" + host
+}
+
+// KeyByPrincipal keys requests by the id of their principal, falling back to
+// their ip for anonymous callers.
+func KeyByPrincipal(req *http.Request, p *data.Principal) string {
+	if p != nil && p.ID != "" {
+		return "principal:" + p.ID
+	}
+
+	return KeyByIP(req, p)
+}
+
+// KeyByAPIKey returns a RateKey keying requests by the API key sent in the
+// giving header (APIKeyHeader by default), falling back to their ip. It is
+// meant for use with the APIKeys authenticator, which rejects unknown keys
+// before they are charged.
+func KeyByAPIKey(header string) RateKey {
+	if header == "" {
+		header = APIKeyHeader
+	}
+
+	return func(req *http.Request, p *data.Principal) string {
+		if key := req.Header.Get(header); key != "" {
+			return "key:" + key
+		}
+
+		return KeyByIP(req, p)
+	}
+}
+
+//==============================================================================
+
+// RateStatus defines the state of the budget a request was charged to,
+// reported in the X-RateLimit-* headers of the response. Exceeds is true for
+// requests needing more tokens than the burst of the budget, which are never
+// allowed and so have no RetryAfter.
+type RateStatus struct {
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+	Exceeds    bool
+}
+
+// RateLimiter provides token bucket rate limiting of the queries of callers,
+// each caller, as keyed by Key (KeyByPrincipal by default), having separate
+// budgets for reads and writes. Every query of a request is charged a token,
+// the request being rejected if either budget lacks the tokens it needs.
+type RateLimiter struct {
+	Reads  Rate
+	Writes Rate
+	Key    RateKey
+
+	ml      sync.Mutex
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+// NewRateLimiter returns a new instance of a RateLimiter with the giving
+// budgets.
+func NewRateLimiter(reads Rate, writes Rate) *RateLimiter {
+	return &RateLimiter{Reads: reads, Writes: writes}
+}
+
+// bucket defines the tokens left to a caller and when they were counted.
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// Allow charges the queries of the request to the budgets of its caller,
+// returning the status of the budget charged, the writes budget when the
+// request has writes. False is returned without charging either budget if
+// they lack the tokens needed.
+func (r *RateLimiter) Allow(req *http.Request, p *data.Principal, queries []string) (RateStatus, bool) {
+	var reads, writes int
+
+	for _, query := range queries {
+		if isWrite(query) {
+			writes++
+			continue
+		}
+
+		reads++
+	}
+
+	keyFn := r.Key
+	if keyFn == nil {
+		keyFn = KeyByPrincipal
+	}
+
+	return r.charge(keyFn(req, p), reads, writes)
+}
+
+// Fail charges a failed authentication of the request to the reads budget of
+// its ip, whatever the Key of the limiter, so callers guessing credentials are
+// limited as well. False is returned if the budget lacks the token.
+func (r *RateLimiter) Fail(req *http.Request) (RateStatus, bool) {
+	return r.charge(KeyByIP(req, nil), 1, 0)
+}
+
+// charge charges the reads and writes to the budgets of the giving key.
+func (r *RateLimiter) charge(key string, reads int, writes int) (RateStatus, bool) {
+	current := now()
+
+	r.ml.Lock()
+	defer r.ml.Unlock()
+
+	if r.buckets == nil {
+		r.buckets = make(map[string]*bucket)
+	}
+
+	r.sweep(current)
+
+	rb := r.bucket(r.Reads, "read:"+key, current)
+	wb := r.bucket(r.Writes, "write:"+key, current)
+
+	rs, rok := status(r.Reads, rb, reads)
+	ws, wok := status(r.Writes, wb, writes)
+
+	if !rok || !wok {
+		st := rs
+		if !wok {
+			st = ws
+		}
+
+		if !rok && !wok && rs.RetryAfter > ws.RetryAfter {
+			st.RetryAfter = rs.RetryAfter
+		}
+
+		if rs.Exceeds || ws.Exceeds {
+			st.Exceeds = true
+			st.RetryAfter = 0
+		}
+
+		return st, false
+	}
+
+	if rb != nil {
+		rb.tokens -= float64(reads)
+	}
+
+	if wb != nil {
+		wb.tokens -= float64(writes)
+	}
+
+	if writes > 0 && wb != nil {
+		ws, _ = status(r.Writes, wb, 0)
+		return ws, true
+	}
+
+	rs, _ = status(r.Reads, rb, 0)
+	return rs, true
+}
+
+// bucket returns the refilled bucket of the giving key, which is nil for
+// unlimited rates.
+func (r *RateLimiter) bucket(rate Rate, key string, current time.Time) *bucket {
+	if rate.Requests <= 0 || rate.Per <= 0 {
+		return nil
+	}
+
+	b, ok := r.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(rate.burst()), updated: current}
+		r.buckets[key] = b
+		return b
+	}
+
+	b.tokens = math.Min(float64(rate.burst()), b.tokens+rate.earned(current.Sub(b.updated)))
+	b.updated = current
+	return b
+}
+
+// sweep drops the buckets of callers idle long enough for their buckets to
+// be full, at most once a minute.
+func (r *RateLimiter) sweep(current time.Time) {
+	if current.Sub(r.swept) < time.Minute {
+		return
+	}
+
+	r.swept = current
+
+	for key, b := range r.buckets {
+		rate := r.Reads
+		if strings.HasPrefix(key, "write:") {
+			rate = r.Writes
+		}
+
+		if b.tokens+rate.earned(current.Sub(b.updated)) >= float64(rate.burst()) {
+			delete(r.buckets, key)
+		}
+	}
+}
+
+//==============================================================================
+
+// burst returns the capacity of the rate's buckets.
+func (r Rate) burst() int {
+	if r.Burst > 0 {
+		return r.Burst
+	}
+
+	return r.Requests
+}
+
+// earned returns the tokens earned over the giving duration.
+func (r Rate) earned(elapsed time.Duration) float64 {
+	return float64(r.Requests) * elapsed.Seconds() / r.Per.Seconds()
+}
+
+// wait returns the time taken to earn the giving tokens.
+func (r Rate) wait(tokens float64) time.Duration {
+	if tokens <= 0 {
+		return 0
+	}
+
+	return time.Duration(tokens * float64(r.Per) / float64(r.Requests))
+}
+
+// status returns the status of the bucket and if it holds the tokens needed.
+func status(rate Rate, b *bucket, needed int) (RateStatus, bool) {
+	if b == nil {
+		return RateStatus{}, true
+	}
+
+	st := RateStatus{
+		Limit:     rate.burst(),
+		Remaining: int(math.Floor(b.tokens)),
+		Reset:     rate.wait(float64(rate.burst()) - b.tokens),
+	}
+
+	if needed > rate.burst() {
+		st.Exceeds = true
+		return st, false
+	}
+
+	if float64(needed) > b.tokens {
+		st.RetryAfter = rate.wait(float64(needed) - b.tokens)
+		return st, false
+	}
+
+	return st, true
+}
+
+// isWrite returns true/false if the query calls any of the WriteMethods,
+// queries failing to parse are left to the engine to reject.
+func isWrite(query string) bool {
+	q, err := parser.Parse(query)
+	if err != nil {
+		return false
+	}
+
+	for _, call := range q.Calls {
+		for _, name := range WriteMethods {
+			if strings.EqualFold(call.Name, name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+//==============================================================================
@@ -0,0 +1,151 @@
+package cohttp_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/protocols/cohttp"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+// TestRateLimiter validates the rate limiting of the queries of http requests.
+func TestRateLimiter(t *testing.T) {
+	t.Logf("Given the need to rate limit the callers of http requests")
+	{
+		server := cohttp.New(events, coquery.NewDiffs(events), storage.New("id"))
+		server.UseRateLimiter(cohttp.NewRateLimiter(
+			cohttp.Rate{Requests: 2, Per: time.Minute},
+			cohttp.Rate{Requests: 1, Per: time.Minute},
+		))
+
+		serve := func(addr string, query string) *httptest.ResponseRecorder {
+			body, _ := json.Marshal(map[string]interface{}{"request_id": "532UFY", "queries": []string{query}})
+
+			req, _ := http.NewRequest("POST", "/", bytes.NewReader(body))
+			req.RemoteAddr = addr
+
+			res := httptest.NewRecorder()
+			server.ServeHTTP(res, req)
+			return res
+		}
+
+		t.Logf("\tWhen making reads within the budget")
+		{
+			for remaining := 1; remaining >= 0; remaining-- {
+				res := serve("10.0.0.1:4001", "docs.users.findN(1)")
+				if res.Code == http.StatusTooManyRequests {
+					t.Fatalf("\t%s\tShould have served the read", tests.Failed)
+				}
+				t.Logf("\t%s\tShould have served the read", tests.Success)
+
+				if got := res.Header().Get("X-RateLimit-Remaining"); got != strconv.Itoa(remaining) {
+					t.Fatalf("\t%s\tShould have %d reads remaining: %s", tests.Failed, remaining, got)
+				}
+				t.Logf("\t%s\tShould have %d reads remaining", tests.Success, remaining)
+			}
+		}
+
+		t.Logf("\tWhen making reads beyond the budget")
+		{
+			res := serve("10.0.0.1:4001", "docs.users.findN(1)")
+			if res.Code != http.StatusTooManyRequests {
+				t.Fatalf("\t%s\tShould have rejected the read: %d", tests.Failed, res.Code)
+			}
+			t.Logf("\t%s\tShould have rejected the read", tests.Success)
+
+			if got := res.Header().Get("Retry-After"); got != "30" {
+				t.Fatalf("\t%s\tShould have asked to retry after 30 seconds: %s", tests.Failed, got)
+			}
+			t.Logf("\t%s\tShould have asked to retry after 30 seconds", tests.Success)
+
+			var cerr map[string]string
+			if err := json.Unmarshal(res.Body.Bytes(), &cerr); err != nil || cerr["error"] != cohttp.ErrRateLimited.Error() {
+				t.Fatalf("\t%s\tShould have replied a CoError: %s", tests.Failed, res.Body.String())
+			}
+			t.Logf("\t%s\tShould have replied a CoError", tests.Success)
+		}
+
+		t.Logf("\tWhen making writes after exhausting the reads")
+		{
+			if res := serve("10.0.0.1:4001", "docs.users.find(id,1).mutate({name:'alex'})"); res.Code == http.StatusTooManyRequests {
+				t.Fatalf("\t%s\tShould have served the write from its own budget", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have served the write from its own budget", tests.Success)
+
+			if res := serve("10.0.0.1:4001", "docs.users.find(id,1).remove()"); res.Code != http.StatusTooManyRequests {
+				t.Fatalf("\t%s\tShould have rejected the write beyond its budget: %d", tests.Failed, res.Code)
+			}
+			t.Logf("\t%s\tShould have rejected the write beyond its budget", tests.Success)
+		}
+
+		t.Logf("\tWhen making reads from another caller")
+		{
+			if res := serve("10.0.0.2:4001", "docs.users.findN(1)"); res.Code == http.StatusTooManyRequests {
+				t.Fatalf("\t%s\tShould have served the read from the caller's own budget", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have served the read from the caller's own budget", tests.Success)
+		}
+
+		t.Logf("\tWhen making more reads at once than the budget holds")
+		{
+			body, _ := json.Marshal(map[string]interface{}{"request_id": "532UFY", "queries": []string{"docs.users.findN(1)", "docs.users.findN(2)", "docs.users.findN(3)"}})
+
+			req, _ := http.NewRequest("POST", "/", bytes.NewReader(body))
+			req.RemoteAddr = "10.0.0.3:4001"
+
+			res := httptest.NewRecorder()
+			server.ServeHTTP(res, req)
+
+			if res.Code != http.StatusTooManyRequests || res.Header().Get("Retry-After") != "" {
+				t.Fatalf("\t%s\tShould have rejected the reads without a Retry-After: %d %q", tests.Failed, res.Code, res.Header().Get("Retry-After"))
+			}
+			t.Logf("\t%s\tShould have rejected the reads without a Retry-After", tests.Success)
+		}
+	}
+}
+
+// TestRateLimitedAuthentication validates the charging of failed
+// authentications to the rate limit of their caller.
+func TestRateLimitedAuthentication(t *testing.T) {
+	t.Logf("Given the need to rate limit callers guessing credentials")
+	{
+		server := cohttp.New(events, coquery.NewDiffs(events), storage.New("id"))
+		server.UseAuthenticators(&cohttp.APIKeys{Keys: map[string]*data.Principal{"4ab3": {ID: "alex"}}})
+		server.UseRateLimiter(cohttp.NewRateLimiter(cohttp.Rate{Requests: 1, Per: time.Minute}, cohttp.Rate{}))
+
+		guess := func(key string) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest("GET", "/?coquery=docs.users.findN(1)", nil)
+			req.RemoteAddr = "10.0.0.1:4001"
+			req.Header.Set(cohttp.APIKeyHeader, key)
+
+			res := httptest.NewRecorder()
+			server.ServeHTTP(res, req)
+			return res
+		}
+
+		t.Logf("\tWhen guessing keys beyond the budget")
+		{
+			if res := guess("1111"); res.Code != http.StatusUnauthorized {
+				t.Fatalf("\t%s\tShould have rejected the first guess as unauthorized: %d", tests.Failed, res.Code)
+			}
+			t.Logf("\t%s\tShould have rejected the first guess as unauthorized", tests.Success)
+
+			if res := guess("2222"); res.Code != http.StatusTooManyRequests {
+				t.Fatalf("\t%s\tShould have rate limited the next guess: %d", tests.Failed, res.Code)
+			}
+			t.Logf("\t%s\tShould have rate limited the next guess", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
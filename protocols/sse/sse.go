@@ -1 +1,488 @@
+// Package sse provides a Server-Sent Events endpoint streaming the deltas of
+// changed records to its clients as the coquery engine records them, in place
+// of clients polling for them with their last delta id.
 package sse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/protocols/cohttp"
+	"github.com/influx6/coquery/storage"
+	"github.com/pborman/uuid"
+)
+
+//==============================================================================
+
+// EventLog defines event logger that allows us to record events for a specific
+// action that occured.
+type EventLog interface {
+	Log(context interface{}, name string, message string, data ...interface{})
+	Error(context interface{}, name string, err error, message string, data ...interface{})
+}
+
+// WatchedDiffs defines the Diffs the endpoint streams, which must notify it
+// of the diffs put into them eg a coquery.DiffStore.
+type WatchedDiffs interface {
+	coquery.Diffs
+	coquery.DiffWatcher
+}
+
+//==============================================================================
+
+// contains the events sent to subscribers. A delta event lists the records
+// changed since the last event, while a reset event tells the subscriber its
+// last delta id is no longer known eg expired, and its records must be
+// reloaded.
+const (
+	EventDelta = "delta"
+	EventReset = "reset"
+)
+
+// KeepAlive sets the interval at which idle streams are sent a comment, which
+// keeps proxies from closing them.
+var KeepAlive = 30 * time.Second
+
+// ErrStreamUnsupported is returned when the response writer can not flush
+// the events as they are written.
+var ErrStreamUnsupported = errors.New("Streaming Unsupported")
+
+// ErrQueryRequired is returned when a subscriber gives no query whose records
+// the stream watches.
+var ErrQueryRequired = errors.New("Query Required")
+
+// Delta defines the data of a delta event, DeltaID being the id to resume the
+// stream from.
+type Delta struct {
+	DeltaID string   `json:"delta_id"`
+	Deltas  []string `json:"deltas"`
+}
+
+//==============================================================================
+
+// CoquerySSE provides a interface for a http handler serving streams of the
+// deltas of changed records.
+type CoquerySSE interface {
+	http.Handler
+	ListenAndServe(context interface{}, addr string)
+	EnableCORS()
+	UseAuthenticators(auths ...cohttp.Authenticator)
+	RequireAuthentication()
+	UseRateLimiter(limiter *cohttp.RateLimiter)
+}
+
+// New returns a new CoquerySSE streaming the deltas put into the giving
+// diffs, which should be the diffs the engine records the changes of the
+// store in. Streams only list the records replied to the query of their
+// subscriber, served live by the engine under the subscriber's principal.
+func New(e EventLog, engine coquery.Engine, diff WatchedDiffs, store storage.Store) CoquerySSE {
+	sse := sseCoquery{
+		EventLog: e,
+		diff:     diff,
+		key:      store.Key(),
+		live:     coquery.NewLiveQueries(e, engine, diff, store),
+	}
+
+	return &sse
+}
+
+//==============================================================================
+
+// sseCoquery provides the Server-Sent Events implementation of CoquerySSE.
+type sseCoquery struct {
+	EventLog
+	diff        WatchedDiffs
+	key         string
+	live        *coquery.LiveQueries
+	useCORS     bool
+	requireAuth bool
+	auths       []cohttp.Authenticator
+	limiter     *cohttp.RateLimiter
+}
+
+// ListenAndServe runs a http server with the sseCoquery instance wired
+// to serve its incoming requests.
+func (s *sseCoquery) ListenAndServe(context interface{}, addr string) {
+	s.Log(context, "ListenAndServe", "Started : Addr[%s]", addr)
+
+	// Lunch the http server in a goroutine.
+	go func() {
+		s.Log(context, "ListenAndServe", "Listening on: %s", addr)
+		http.ListenAndServe(addr, s)
+	}()
+
+	// Listen for an interrupt signal from the OS.
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	<-sigChan
+
+	s.Log(context, "ListenAndServe", "Completed")
+}
+
+// EnableCORS flips the flag to add CORS headers to all response to true.
+func (s *sseCoquery) EnableCORS() {
+	s.useCORS = true
+}
+
+// UseAuthenticators adds the giving authenticators, which are tried in order
+// with every subscription request until one returns the principal of its
+// caller.
+func (s *sseCoquery) UseAuthenticators(auths ...cohttp.Authenticator) {
+	s.auths = append(s.auths, auths...)
+}
+
+// RequireAuthentication flips the flag to reject subscriptions which none of
+// the authenticators authenticate to true, else such subscriptions are
+// anonymous.
+func (s *sseCoquery) RequireAuthentication() {
+	s.requireAuth = true
+}
+
+// UseRateLimiter sets the RateLimiter charged with the query of every
+// subscription and its failed authentications, replacing any set before.
+func (s *sseCoquery) UseRateLimiter(limiter *cohttp.RateLimiter) {
+	s.limiter = limiter
+}
+
+// authenticate returns the principal of the subscription's caller, which is
+// nil for anonymous callers.
+func (s *sseCoquery) authenticate(req *http.Request) (*data.Principal, error) {
+	for _, auth := range s.auths {
+		p, err := auth.Authenticate(req, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if p != nil {
+			return p, nil
+		}
+	}
+
+	if s.requireAuth {
+		return nil, fmt.Errorf("%s : Authentication Required", cohttp.ErrInvalidCredentials)
+	}
+
+	return nil, nil
+}
+
+// ServeHTTP provides the http.Handler ServeHTTP method, streaming delta
+// events to the subscriber until it disconnects. Subscribers give the query
+// whose replied records are watched, as with the records read over a
+// websocket connection, and may give the last_delta_id to resume from,
+// overridden by the Last-Event-ID header sent when reconnecting, and a
+// diff_watch list of record keys (comma separated or repeated) to further
+// limit the events to changes of those records.
+func (s *sseCoquery) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	s.Log("SSECoquery", "ServeHTTP", "Started : Request %s", req.URL.String())
+
+	principal, err := s.authenticate(req)
+	if err != nil {
+		// Failed authentications are charged to the caller, limiting those
+		// guessing credentials.
+		if s.limiter != nil {
+			if st, ok := s.limiter.Fail(req); !ok {
+				s.limited(res, st)
+				return
+			}
+		}
+
+		res.WriteHeader(http.StatusUnauthorized)
+		res.Write([]byte(err.Error()))
+		s.Error("SSECoquery", "ServeHTTP", err, "Completed : Authentication")
+		return
+	}
+
+	query := req.URL.Query().Get("query")
+	if query == "" {
+		res.WriteHeader(http.StatusBadRequest)
+		res.Write([]byte(ErrQueryRequired.Error()))
+		s.Error("SSECoquery", "ServeHTTP", ErrQueryRequired, "Completed")
+		return
+	}
+
+	if s.limiter != nil {
+		if st, ok := s.limiter.Allow(req, principal, []string{query}); !ok {
+			s.limited(res, st)
+			return
+		}
+	}
+
+	flusher, ok := res.(http.Flusher)
+	if !ok {
+		res.WriteHeader(http.StatusInternalServerError)
+		res.Write([]byte(ErrStreamUnsupported.Error()))
+		s.Error("SSECoquery", "ServeHTTP", ErrStreamUnsupported, "Completed")
+		return
+	}
+
+	lastID := req.URL.Query().Get("last_delta_id")
+	if id := req.Header.Get("Last-Event-ID"); id != "" {
+		lastID = id
+	}
+
+	var watch []string
+	for _, keys := range req.URL.Query()["diff_watch"] {
+		for _, key := range strings.Split(keys, ",") {
+			if key = strings.TrimSpace(key); key != "" {
+				watch = append(watch, key)
+			}
+		}
+	}
+
+	// The query is served live, so records entering its results are watched
+	// as the records it replied change.
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	w, rerr := s.subscribe(ctx, principal, query)
+	if rerr != nil {
+		s.denied(res, rerr)
+		return
+	}
+
+	// Changes only signal the stream to catch up with the diffs, so a slow
+	// subscriber never holds back the diffs being put.
+	changed := make(chan struct{}, 1)
+
+	unwatch := s.diff.Watch(func(id string, records []string) {
+		select {
+		case changed <- struct{}{}:
+		default:
+		}
+	})
+	defer unwatch()
+
+	if s.useCORS {
+		res.Header().Set("Access-Control-Allow-Origin", "*")
+	}
+
+	res.Header().Set("Content-Type", "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("Connection", "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+
+	// Subscribers without a last delta id start from the latest diff.
+	if lastID == "" {
+		lastID = s.latest()
+
+		if err := s.send(res, EventDelta, Delta{DeltaID: lastID}); err != nil {
+			s.Error("SSECoquery", "ServeHTTP", err, "Completed : Send")
+			return
+		}
+	}
+
+	if lastID, err = s.catchUp(res, lastID, w, watch); err != nil {
+		s.Error("SSECoquery", "ServeHTTP", err, "Completed : Send")
+		return
+	}
+
+	flusher.Flush()
+
+	ticker := time.NewTicker(KeepAlive)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			s.Log("SSECoquery", "ServeHTTP", "Completed")
+			return
+
+		case <-ticker.C:
+			if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+				s.Error("SSECoquery", "ServeHTTP", err, "Completed : Keep Alive")
+				return
+			}
+
+		case <-changed:
+			if lastID, err = s.catchUp(res, lastID, w, watch); err != nil {
+				s.Error("SSECoquery", "ServeHTTP", err, "Completed : Send")
+				return
+			}
+		}
+
+		flusher.Flush()
+	}
+}
+
+// catchUp sends the delta of the records changed since the giving diff, if
+// any of the records replied to the query and watched changed, returning the
+// id of the latest diff. A reset is sent if the diff is unknown.
+func (s *sseCoquery) catchUp(res http.ResponseWriter, lastID string, w *watcher, watch []string) (string, error) {
+	latest := s.latest()
+	if latest == lastID {
+		return lastID, nil
+	}
+
+	if lastID != "" && !s.diff.Has(lastID) {
+		return latest, s.send(res, EventReset, Delta{DeltaID: latest})
+	}
+
+	var changes []string
+
+	switch {
+	case lastID == "":
+		for key := range s.diff.Diffs() {
+			changes = append(changes, key)
+		}
+	default:
+		changes = s.diff.PullFrom(lastID)
+	}
+
+	changes = w.watched(changes)
+
+	if len(watch) > 0 {
+		watched := make(map[string]bool)
+		for _, key := range watch {
+			watched[key] = true
+		}
+
+		var found []string
+		for _, key := range changes {
+			if watched[key] {
+				found = append(found, key)
+			}
+		}
+
+		changes = found
+	}
+
+	if len(changes) == 0 {
+		return latest, nil
+	}
+
+	return latest, s.send(res, EventDelta, Delta{DeltaID: latest, Deltas: changes})
+}
+
+// subscribe serves the query live under the principal until the context is
+// done, returning the watcher tracking the records it replies once it first
+// replied. Its error is returned if the query first fails eg is denied.
+func (s *sseCoquery) subscribe(ctx context.Context, principal *data.Principal, query string) (*watcher, coquery.ResponseError) {
+	rctx := (&data.RequestContext{
+		RequestID: uuid.New(),
+		Principal: principal,
+		Queries:   []string{query},
+	}).WithContext(ctx)
+
+	w := watcher{read: make(map[string]bool)}
+
+	var once sync.Once
+	first := make(chan coquery.ResponseError, 1)
+
+	err := s.live.Subscribe("SSECoquery", rctx, func(rs *coquery.Response, rerr coquery.ResponseError) {
+		if rerr != nil {
+			s.Error("SSECoquery", "subscribe", rerr, "Info : Request ID[%s]", rctx.RequestID)
+		} else {
+			w.track(s.key, rs.Data)
+		}
+
+		once.Do(func() { first <- rerr })
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	select {
+	case err = <-first:
+	case <-ctx.Done():
+		err = &coquery.CoError{Rid: rctx.RequestID, Msg: "Subscription Cancelled", IError: ctx.Err()}
+	}
+
+	if err != nil {
+		s.live.Unsubscribe("SSECoquery", rctx.RequestID)
+		return nil, err
+	}
+
+	return &w, nil
+}
+
+// denied writes the error of a subscriber's query, replying a 403 status for
+// queries denied access.
+func (s *sseCoquery) denied(res http.ResponseWriter, err coquery.ResponseError) {
+	status := http.StatusBadRequest
+	if cerr, ok := err.(*coquery.CoError); ok && cerr.IError == coquery.ErrAccessDenied {
+		status = http.StatusForbidden
+	}
+
+	res.WriteHeader(status)
+	res.Write([]byte(err.Error()))
+	s.Error("SSECoquery", "ServeHTTP", err, "Completed : Query")
+}
+
+// limited writes the 429 response of a subscriber exceeding its rate limit,
+// with a Retry-After header unless the subscription can never be allowed.
+func (s *sseCoquery) limited(res http.ResponseWriter, st cohttp.RateStatus) {
+	if !st.Exceeds {
+		res.Header().Set("Retry-After", strconv.Itoa(cohttp.Seconds(st.RetryAfter)))
+	}
+
+	res.WriteHeader(http.StatusTooManyRequests)
+	res.Write([]byte(cohttp.ErrRateLimited.Error()))
+	s.Error("SSECoquery", "ServeHTTP", cohttp.ErrRateLimited, "Completed : Rate Limited")
+}
+
+// latest returns the id of the latest diff, which is empty for no diffs.
+func (s *sseCoquery) latest() string {
+	return coquery.LatestDiff(s.diff)
+}
+
+// send writes the event to the stream.
+func (s *sseCoquery) send(res http.ResponseWriter, event string, delta Delta) error {
+	body, err := json.Marshal(delta)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(res, "id: %s\nevent: %s\ndata: %s\n\n", delta.DeltaID, event, body)
+	return err
+}
+
+//==============================================================================
+
+// watcher tracks the keys of the records replied to the query of a stream,
+// as deltas are only sent for records its subscriber was allowed to read.
+type watcher struct {
+	rl   sync.Mutex
+	read map[string]bool
+}
+
+// track adds the keys of the replied records to those watched.
+func (w *watcher) track(key string, records data.Parameters) {
+	w.rl.Lock()
+	defer w.rl.Unlock()
+
+	for _, rec := range records {
+		if val, ok := rec[key]; ok {
+			w.read[fmt.Sprintf("%+v", val)] = true
+		}
+	}
+}
+
+// watched returns the keys of the changed records which are watched.
+func (w *watcher) watched(changes []string) []string {
+	w.rl.Lock()
+	defer w.rl.Unlock()
+
+	var found []string
+	for _, key := range changes {
+		if w.read[key] {
+			found = append(found, key)
+		}
+	}
+
+	return found
+}
+
+//==============================================================================
@@ -0,0 +1,334 @@
+package sse_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/protocols/cohttp"
+	"github.com/influx6/coquery/protocols/sse"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+var events eventlog
+
+// eventlog provides a logger which discards its events.
+type eventlog struct{}
+
+// Log discards the standard log reports.
+func (l eventlog) Log(context interface{}, name string, message string, data ...interface{}) {}
+
+// Error discards the error reports.
+func (l eventlog) Error(context interface{}, name string, err error, message string, data ...interface{}) {
+}
+
+var context = "testing"
+
+//==============================================================================
+
+// records provides a Document which replies the records 1, 2 and 3 to every
+// request.
+type records struct{}
+
+// Handle replies the records.
+func (records) Handle(context interface{}, reqs coquery.RecordRequests, res coquery.ResponseWriter) {
+	res.Write(context, &coquery.Response{
+		Req:  reqs[0],
+		Data: data.Parameters{{"id": 1}, {"id": 2}, {"id": 3}},
+	}, nil)
+}
+
+// newServer returns a CoquerySSE streaming the diffs of a engine serving the
+// records from the doc and secure routes, the later authorizing admins to
+// read the records and readers to read them without their keys.
+func newServer(diffs *coquery.DiffStore) sse.CoquerySSE {
+	store := storage.New("id")
+	engine := coquery.New(events, diffs, store)
+
+	engine.Route(context, "doc").
+		Document(context, "records", &coquery.BasicQueries{EventLog: events, Store: store}, records{})
+
+	engine.Route(context, "secure").
+		UseAuthorizer(context, &coquery.RuleAuthorizer{Rules: []coquery.Rule{
+			{Role: "admin", Doc: "*"},
+			{Role: "reader", Doc: "*", Hidden: []string{"id"}},
+		}}).
+		Document(context, "records", &coquery.BasicQueries{EventLog: events, Store: store}, records{})
+
+	return sse.New(events, engine, diffs, store)
+}
+
+//==============================================================================
+
+// event defines a event read from a stream.
+type event struct {
+	ID    string
+	Event string
+	Delta sse.Delta
+}
+
+// subscribe opens a stream of the server with the giving api key if any,
+// returning the channel of its events.
+func subscribe(t *testing.T, url string, lastID string, key string) (<-chan event, func()) {
+	req, _ := http.NewRequest("GET", url, nil)
+	if lastID != "" {
+		req.Header.Set("Last-Event-ID", lastID)
+	}
+
+	if key != "" {
+		req.Header.Set(cohttp.APIKeyHeader, key)
+	}
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("\t%s\tShould have subscribed to the stream: %s", tests.Failed, err)
+	}
+
+	events := make(chan event, 10)
+
+	go func() {
+		var ev event
+
+		scanner := bufio.NewScanner(res.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+
+			switch {
+			case strings.HasPrefix(line, "id: "):
+				ev.ID = strings.TrimPrefix(line, "id: ")
+			case strings.HasPrefix(line, "event: "):
+				ev.Event = strings.TrimPrefix(line, "event: ")
+			case strings.HasPrefix(line, "data: "):
+				json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &ev.Delta)
+			case line == "" && ev.Event != "":
+				events <- ev
+				ev = event{}
+			}
+		}
+	}()
+
+	return events, func() { res.Body.Close() }
+}
+
+// next returns the next event of the stream.
+func next(t *testing.T, events <-chan event) event {
+	select {
+	case ev := <-events:
+		return ev
+	case <-time.After(2 * time.Second):
+		t.Fatalf("\t%s\tShould have received a event", tests.Failed)
+	}
+
+	return event{}
+}
+
+//==============================================================================
+
+// TestSSE validates the streaming of deltas to subscribers.
+func TestSSE(t *testing.T) {
+	t.Logf("Given the need to stream the deltas of changed records")
+	{
+		diffs := coquery.NewDiffs(events)
+		first := diffs.Put([]string{"1"})
+
+		hs := httptest.NewServer(newServer(diffs))
+		defer hs.Close()
+
+		t.Logf("\tWhen resuming a stream watching some records")
+		{
+			stream, stop := subscribe(t, hs.URL+"?query=doc.records.findN(-1)&diff_watch=2,3", first, "")
+			defer stop()
+
+			second := diffs.Put([]string{"1", "2"})
+
+			ev := next(t, stream)
+			if ev.Event != sse.EventDelta || ev.ID != second || len(ev.Delta.Deltas) != 1 || ev.Delta.Deltas[0] != "2" {
+				t.Fatalf("\t%s\tShould have received the watched change: %+v", tests.Failed, ev)
+			}
+			t.Logf("\t%s\tShould have received the watched change", tests.Success)
+
+			diffs.Put([]string{"4"})
+			third := diffs.Put([]string{"3"})
+
+			ev = next(t, stream)
+			if ev.ID != third || len(ev.Delta.Deltas) != 1 || ev.Delta.Deltas[0] != "3" {
+				t.Fatalf("\t%s\tShould have skipped the unwatched change: %+v", tests.Failed, ev)
+			}
+			t.Logf("\t%s\tShould have skipped the unwatched change", tests.Success)
+		}
+
+		t.Logf("\tWhen records not replied to the query change")
+		{
+			stream, stop := subscribe(t, hs.URL+"?query=doc.records.findN(-1)", diffs.Put([]string{"2"}), "")
+			defer stop()
+
+			diffs.Put([]string{"4"})
+			last := diffs.Put([]string{"1"})
+
+			ev := next(t, stream)
+			if ev.ID != last || len(ev.Delta.Deltas) != 1 || ev.Delta.Deltas[0] != "1" {
+				t.Fatalf("\t%s\tShould have skipped the records not replied: %+v", tests.Failed, ev)
+			}
+			t.Logf("\t%s\tShould have skipped the records not replied", tests.Success)
+		}
+
+		t.Logf("\tWhen subscribing without a query")
+		{
+			res, err := http.Get(hs.URL)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have reached the server: %s", tests.Failed, err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != http.StatusBadRequest {
+				t.Fatalf("\t%s\tShould have rejected the subscriber: %d", tests.Failed, res.StatusCode)
+			}
+			t.Logf("\t%s\tShould have rejected the subscriber", tests.Success)
+		}
+
+		t.Logf("\tWhen resuming a stream from a unknown delta")
+		{
+			stream, stop := subscribe(t, hs.URL+"?query=doc.records.findN(-1)", "4ab3", "")
+			defer stop()
+
+			if ev := next(t, stream); ev.Event != sse.EventReset {
+				t.Fatalf("\t%s\tShould have received a reset: %+v", tests.Failed, ev)
+			}
+			t.Logf("\t%s\tShould have received a reset", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
+
+// TestSSEAuthentication validates the rejection of unauthenticated
+// subscribers.
+func TestSSEAuthentication(t *testing.T) {
+	t.Logf("Given the need to authenticate the subscribers of streams")
+	{
+		server := newServer(coquery.NewDiffs(events))
+		server.UseAuthenticators(&cohttp.APIKeys{Keys: map[string]*data.Principal{"4ab3": {ID: "alex"}}})
+		server.UseRateLimiter(cohttp.NewRateLimiter(cohttp.Rate{Requests: 1, Per: time.Minute}, cohttp.Rate{}))
+		server.RequireAuthentication()
+
+		hs := httptest.NewServer(server)
+		defer hs.Close()
+
+		t.Logf("\tWhen subscribing without credentials")
+		{
+			res, err := http.Get(hs.URL + "?query=doc.records.findN(-1)")
+			if err != nil {
+				t.Fatalf("\t%s\tShould have reached the server: %s", tests.Failed, err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != http.StatusUnauthorized || res.Header.Get("Content-Type") == "text/event-stream" {
+				t.Fatalf("\t%s\tShould have rejected the anonymous subscriber: %d", tests.Failed, res.StatusCode)
+			}
+			t.Logf("\t%s\tShould have rejected the anonymous subscriber", tests.Success)
+		}
+
+		t.Logf("\tWhen subscribing with credentials")
+		{
+			req, _ := http.NewRequest("GET", hs.URL+"?query=doc.records.findN(-1)", nil)
+			req.Header.Set(cohttp.APIKeyHeader, "4ab3")
+
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have reached the server: %s", tests.Failed, err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != http.StatusOK {
+				t.Fatalf("\t%s\tShould have streamed to the authenticated subscriber: %d", tests.Failed, res.StatusCode)
+			}
+			t.Logf("\t%s\tShould have streamed to the authenticated subscriber", tests.Success)
+		}
+
+		t.Logf("\tWhen guessing credentials past the rate limit")
+		{
+			res, err := http.Get(hs.URL + "?query=doc.records.findN(-1)")
+			if err != nil {
+				t.Fatalf("\t%s\tShould have reached the server: %s", tests.Failed, err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != http.StatusTooManyRequests || res.Header.Get("Retry-After") == "" {
+				t.Fatalf("\t%s\tShould have rate limited the subscriber: %d", tests.Failed, res.StatusCode)
+			}
+			t.Logf("\t%s\tShould have rate limited the subscriber", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
+
+// TestSSEAuthorization validates the streaming of deltas for the records the
+// subscriber's principal may read.
+func TestSSEAuthorization(t *testing.T) {
+	t.Logf("Given the need to authorize the records streamed to subscribers")
+	{
+		diffs := coquery.NewDiffs(events)
+
+		server := newServer(diffs)
+		server.UseAuthenticators(&cohttp.APIKeys{Keys: map[string]*data.Principal{
+			"a1": {ID: "alex", Roles: []string{"admin"}},
+			"r1": {ID: "ruth", Roles: []string{"reader"}},
+		}})
+
+		hs := httptest.NewServer(server)
+		defer hs.Close()
+
+		url := hs.URL + "?query=secure.records.findN(-1)"
+
+		t.Logf("\tWhen subscribing to a query the principal may not serve")
+		{
+			res, err := http.Get(url)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have reached the server: %s", tests.Failed, err)
+			}
+			res.Body.Close()
+
+			if res.StatusCode != http.StatusForbidden {
+				t.Fatalf("\t%s\tShould have denied the subscriber: %d", tests.Failed, res.StatusCode)
+			}
+			t.Logf("\t%s\tShould have denied the subscriber", tests.Success)
+		}
+
+		t.Logf("\tWhen records change")
+		{
+			last := diffs.Put([]string{"1"})
+
+			admin, stopAdmin := subscribe(t, url, last, "a1")
+			defer stopAdmin()
+
+			reader, stopReader := subscribe(t, url, last, "r1")
+			defer stopReader()
+
+			changed := diffs.Put([]string{"2"})
+
+			if ev := next(t, admin); ev.ID != changed || len(ev.Delta.Deltas) != 1 || ev.Delta.Deltas[0] != "2" {
+				t.Fatalf("\t%s\tShould have streamed the change to the admin: %+v", tests.Failed, ev)
+			}
+			t.Logf("\t%s\tShould have streamed the change to the admin", tests.Success)
+
+			select {
+			case ev := <-reader:
+				t.Fatalf("\t%s\tShould not have streamed the records hidden from the reader: %+v", tests.Failed, ev)
+			case <-time.After(200 * time.Millisecond):
+				t.Logf("\t%s\tShould not have streamed the records hidden from the reader", tests.Success)
+			}
+		}
+	}
+}
+
+//==============================================================================
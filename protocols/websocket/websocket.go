@@ -1 +1,595 @@
+// Package websocket provides a websocket protocol engine that builds on the
+// coquery base level engine, serving many requests over a single connection
+// and pushing the deltas of changed records to its clients as they happen.
 package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/protocols/cohttp"
+	"github.com/influx6/coquery/storage"
+	"github.com/pborman/uuid"
+)
+
+//==============================================================================
+
+// EventLog defines event logger that allows us to record events for a specific
+// action that occured.
+type EventLog interface {
+	Log(context interface{}, name string, message string, data ...interface{})
+	Error(context interface{}, name string, err error, message string, data ...interface{})
+}
+
+//==============================================================================
+
+// contains the timings of the connections, which are pinged every
+// PingInterval and closed when silent for longer than PongWait.
+var (
+	WriteWait    = 10 * time.Second
+	PongWait     = 60 * time.Second
+	PingInterval = 50 * time.Second
+)
+
+// MaxInFlight sets the number of requests of a connection served at once,
+// further requests not being read from the connection until one completes.
+// Messages larger than cohttp.MaxBodySize close the connection.
+var MaxInFlight = 8
+
+// ErrConnectionClosed is returned when writing to a closed connection.
+var ErrConnectionClosed = errors.New("Connection Closed")
+
+// conn provides a websocket connection which serializes the messages written
+// to it, as websocket connections allow a single writer. It tracks the keys of
+// the records replied over it, as deltas are only pushed for records its
+// principal was allowed to read.
+type conn struct {
+	id     string
+	ws     *websocket.Conn
+	wl     sync.Mutex
+	closed bool
+	rl     sync.Mutex
+	read   map[string]bool
+}
+
+// track adds the keys of the replied records to those read over the
+// connection, including the records held within the results of response packs
+// and the data of batched responses.
+func (c *conn) track(key string, records data.Parameters) {
+	c.rl.Lock()
+	defer c.rl.Unlock()
+
+	if c.read == nil {
+		c.read = make(map[string]bool)
+	}
+
+	c.add(key, records)
+}
+
+// add adds the keys of the records and the records they hold, expecting the
+// read lock held.
+func (c *conn) add(key string, records data.Parameters) {
+	for _, rec := range records {
+		for _, field := range []string{"results", "data"} {
+			if held, ok := rec[field].(data.Parameters); ok {
+				c.add(key, held)
+			}
+		}
+
+		if val, ok := rec[key]; ok {
+			c.read[fmt.Sprintf("%+v", val)] = true
+		}
+	}
+}
+
+// watched returns the keys of the changed records read over the connection.
+func (c *conn) watched(changes []string) []string {
+	c.rl.Lock()
+	defer c.rl.Unlock()
+
+	var found []string
+	for _, key := range changes {
+		if c.read[key] {
+			found = append(found, key)
+		}
+	}
+
+	return found
+}
+
+// send writes the message to the connection.
+func (c *conn) send(msg *data.SocketMessage) error {
+	c.wl.Lock()
+	defer c.wl.Unlock()
+
+	if c.closed {
+		return ErrConnectionClosed
+	}
+
+	c.ws.SetWriteDeadline(time.Now().Add(WriteWait))
+	return c.ws.WriteJSON(msg)
+}
+
+// ping writes a ping control message to the connection.
+func (c *conn) ping() error {
+	c.wl.Lock()
+	defer c.wl.Unlock()
+
+	if c.closed {
+		return ErrConnectionClosed
+	}
+
+	return c.ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(WriteWait))
+}
+
+// close closes the connection, failing the writes made after it.
+func (c *conn) close() {
+	c.wl.Lock()
+	defer c.wl.Unlock()
+
+	if !c.closed {
+		c.closed = true
+		c.ws.Close()
+	}
+}
+
+//==============================================================================
+
+// ResWriter provides a response writer for sending the coquery response of a
+// request as a message over its websocket connection.
+type ResWriter struct {
+	EventLog
+	conn *conn
+	rid  string
+	key  string
+}
+
+// Write implements the coquery.ResponseWriter interface onto the
+// ResWriter struct.
+func (w *ResWriter) Write(context interface{}, rs *coquery.Response, re coquery.ResponseError) error {
+	w.Log(context, "websocket.ResWriter.Write", "Started : Request ID[%s]", w.rid)
+
+	msg := data.SocketMessage{RequestID: w.rid}
+
+	if re != nil {
+		w.Error(context, "websocket.ResWriter.Write", re, "Info : Request ID[%s]", w.rid)
+
+		msg.Type = data.SocketError
+		msg.Message = re.Error()
+
+		// Errors are sent along as JSON, allowing clients to report eg the
+		// failing fields of a validation error.
+		if raw, err := json.Marshal(re); err == nil {
+			msg.Error = raw
+		}
+	} else {
+		var reply interface{} = rs.Data
+		if len(rs.Data) == 1 {
+			reply = rs.Data[0]
+		}
+
+		raw, err := json.Marshal(reply)
+		if err != nil {
+			w.Error(context, "websocket.ResWriter.Write", err, "Completed : JSON.Marshal")
+			return err
+		}
+
+		msg.Type = data.SocketReply
+		msg.Reply = raw
+
+		w.conn.track(w.key, rs.Data)
+	}
+
+	if err := w.conn.send(&msg); err != nil {
+		w.Error(context, "websocket.ResWriter.Write", err, "Completed : Send")
+		return err
+	}
+
+	w.Log(context, "websocket.ResWriter.Write", "Completed")
+	return nil
+}
+
+//==============================================================================
+
+// CoquerySocket provides a interface that combines the coquery.Engine and a
+// http handler upgrading its requests to websocket connections.
+type CoquerySocket interface {
+	coquery.Engine
+	http.Handler
+	ListenAndServe(context interface{}, addr string)
+	EnableCORS()
+	UseAuthenticators(auths ...cohttp.Authenticator)
+	RequireAuthentication()
+	UseRateLimiter(limiter *cohttp.RateLimiter)
+}
+
+// New returns a new CoquerySocket server to respond to all coquery requests
+// made over websockets. If the diffs implement coquery.DiffWatcher, their
+// deltas are pushed to the connections which read the changed records as
+// they are put and live queries are served.
+func New(e EventLog, diff coquery.Diffs, store storage.Store) CoquerySocket {
+	cos := socketCoquery{
+		EventLog: e,
+		Engine:   coquery.New(e, diff, store),
+		diff:     diff,
+		store:    store,
+	}
+
+	if watcher, ok := diff.(coquery.DiffWatcher); ok {
+		cos.live = coquery.NewLiveQueries(e, cos.Engine, watcher, store)
+	}
+
+	return &cos
+}
+
+//==============================================================================
+
+// socketCoquery provides a websocket implementation of the coquery engine.
+type socketCoquery struct {
+	EventLog
+	coquery.Engine
+	diff        coquery.Diffs
+	store       storage.Store
+	live        *coquery.LiveQueries
+	useCORS     bool
+	requireAuth bool
+	auths       []cohttp.Authenticator
+	limiter     *cohttp.RateLimiter
+}
+
+// ListenAndServe runs a http server with the socketCoquery instance wired
+// to serve its incoming connections.
+func (s *socketCoquery) ListenAndServe(context interface{}, addr string) {
+	s.Log(context, "ListenAndServe", "Started : Addr[%s]", addr)
+
+	// Lunch the http server in a goroutine.
+	go func() {
+		s.Log(context, "ListenAndServe", "Listening on: %s", addr)
+		http.ListenAndServe(addr, s)
+	}()
+
+	// Listen for an interrupt signal from the OS.
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, os.Interrupt)
+	<-sigChan
+
+	s.Log(context, "ListenAndServe", "Completed")
+}
+
+// EnableCORS flips the flag to accept connections from any origin to true,
+// else only connections from the host's own origin are accepted.
+func (s *socketCoquery) EnableCORS() {
+	s.useCORS = true
+}
+
+// UseAuthenticators adds the giving authenticators, which are tried in order
+// with the upgrade request of every connection until one returns the
+// principal of its caller.
+func (s *socketCoquery) UseAuthenticators(auths ...cohttp.Authenticator) {
+	s.auths = append(s.auths, auths...)
+}
+
+// RequireAuthentication flips the flag to reject connections which none of
+// the authenticators authenticate to true, else such connections are
+// anonymous.
+func (s *socketCoquery) RequireAuthentication() {
+	s.requireAuth = true
+}
+
+// UseRateLimiter sets the RateLimiter charged with the queries of every
+// request, replacing any set before.
+func (s *socketCoquery) UseRateLimiter(limiter *cohttp.RateLimiter) {
+	s.limiter = limiter
+}
+
+// authenticate returns the principal of the connection's caller, which is
+// nil for anonymous callers.
+func (s *socketCoquery) authenticate(req *http.Request) (*data.Principal, error) {
+	for _, auth := range s.auths {
+		p, err := auth.Authenticate(req, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		if p != nil {
+			return p, nil
+		}
+	}
+
+	if s.requireAuth {
+		return nil, fmt.Errorf("%s : Authentication Required", cohttp.ErrInvalidCredentials)
+	}
+
+	return nil, nil
+}
+
+// ServeHTTP provides the http.Handler ServeHTTP method, upgrading the request
+// to a websocket connection and serving the requests sent over it until it
+// closes.
+func (s *socketCoquery) ServeHTTP(res http.ResponseWriter, req *http.Request) {
+	s.Log("SocketCoquery", "ServeHTTP", "Started : Request %s", req.URL.String())
+
+	principal, err := s.authenticate(req)
+	if err != nil {
+		// Failed authentications are charged to the caller, limiting those
+		// guessing credentials.
+		if s.limiter != nil {
+			if st, ok := s.limiter.Fail(req); !ok {
+				res.Header().Set("Retry-After", strconv.Itoa(cohttp.Seconds(st.RetryAfter)))
+				res.WriteHeader(http.StatusTooManyRequests)
+				res.Write([]byte(cohttp.ErrRateLimited.Error()))
+				s.Error("SocketCoquery", "ServeHTTP", cohttp.ErrRateLimited, "Completed : Rate Limited")
+				return
+			}
+		}
+
+		res.WriteHeader(http.StatusUnauthorized)
+		res.Write([]byte(err.Error()))
+		s.Error("SocketCoquery", "ServeHTTP", err, "Completed : Authentication")
+		return
+	}
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+	}
+
+	if s.useCORS {
+		upgrader.CheckOrigin = func(*http.Request) bool { return true }
+	}
+
+	ws, err := upgrader.Upgrade(res, req, nil)
+	if err != nil {
+		s.Error("SocketCoquery", "ServeHTTP", err, "Completed : Upgrade")
+		return
+	}
+
+	c := &conn{id: uuid.New(), ws: ws}
+	defer c.close()
+
+	// The requests in flight are abandoned once the connection closes.
+	ctx, cancel := context.WithCancel(req.Context())
+	defer cancel()
+
+	// Push the deltas of changed records as they happen. Changes only signal
+	// the connection to catch up with the diffs, so a slow connection never
+	// holds back the diffs being put.
+	if watcher, ok := s.diff.(coquery.DiffWatcher); ok {
+		changed := make(chan struct{}, 1)
+
+		unwatch := watcher.Watch(func(id string, records []string) {
+			select {
+			case changed <- struct{}{}:
+			default:
+			}
+		})
+
+		defer unwatch()
+
+		go s.pushDeltas(ctx, c, changed)
+	}
+
+	go s.keepAlive(ctx, c)
+
+	ws.SetReadLimit(cohttp.MaxBodySize)
+	ws.SetReadDeadline(time.Now().Add(PongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(PongWait))
+	})
+
+	// Bound the requests served at once, a connection sending requests
+	// faster than they are served being held back from sending more.
+	max := MaxInFlight
+	if max < 1 {
+		max = 1
+	}
+
+	inflight := make(chan struct{}, max)
+
+	for {
+		var rctx data.RequestContext
+
+		if err := ws.ReadJSON(&rctx); err != nil {
+			// Malformed requests are reported, leaving the connection open.
+			switch err.(type) {
+			case *json.SyntaxError, *json.UnmarshalTypeError:
+				c.send(&data.SocketMessage{Type: data.SocketError, Message: err.Error()})
+				continue
+			}
+
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				s.Error("SocketCoquery", "ServeHTTP", err, "Info : Read")
+			}
+
+			break
+		}
+
+		if rctx.RequestID == "" {
+			rctx.RequestID = uuid.New()
+		}
+
+		rctx.Principal = principal
+
+		inflight <- struct{}{}
+
+		go func(rctx data.RequestContext) {
+			defer func() { <-inflight }()
+			s.serve(ctx, req, c, rctx)
+		}(rctx)
+	}
+
+	s.Log("SocketCoquery", "ServeHTTP", "Completed")
+}
+
+// serve serves the request sent over the connection, replying with its
+// response or error.
+func (s *socketCoquery) serve(ctx context.Context, req *http.Request, c *conn, rctx data.RequestContext) {
+	s.Log("SocketCoquery", "serve", "Started : Request ID[%s]", rctx.RequestID)
+
+	rw := &ResWriter{EventLog: s.EventLog, conn: c, rid: rctx.RequestID, key: s.store.Key()}
+
+	if s.limiter != nil {
+		if st, ok := s.limiter.Allow(req, rctx.Principal, rctx.Queries); !ok {
+			err := &coquery.CoError{
+				Rid:    rctx.RequestID,
+				Msg:    fmt.Sprintf("Too Many Requests : Retry After %s", st.RetryAfter),
+				IError: cohttp.ErrRateLimited,
+			}
+
+			if st.Exceeds {
+				err.Msg = fmt.Sprintf("Too Many Queries : Request Exceeds The Limit Of %d", st.Limit)
+			}
+
+			rw.Write("SocketCoquery", nil, err)
+			s.Error("SocketCoquery", "serve", err, "Completed : Rate Limited")
+			return
+		}
+	}
+
+	if rctx.Live {
+		s.serveLive(ctx, c, rw, rctx)
+		s.Log("SocketCoquery", "serve", "Completed")
+		return
+	}
+
+	s.Serve(rctx.RequestID, rctx.WithContext(ctx), rw)
+	s.Log("SocketCoquery", "serve", "Completed")
+}
+
+// serveLive subscribes to the live query of the request, pushing its results
+// over the connection until the connection closes, else ends the subscription
+// of a request without queries. Subscriptions are scoped to their connection.
+func (s *socketCoquery) serveLive(ctx context.Context, c *conn, rw *ResWriter, rctx data.RequestContext) {
+	if s.live == nil {
+		rw.Write("SocketCoquery", nil, &coquery.CoError{
+			Rid:    rctx.RequestID,
+			Msg:    "Live Queries Unsupported",
+			IError: coquery.ErrLiveQuery,
+		})
+		return
+	}
+
+	rid := rctx.RequestID
+	liveID := c.id + ":" + rid
+
+	if len(rctx.Queries) == 0 {
+		s.live.Unsubscribe("SocketCoquery", liveID)
+		return
+	}
+
+	sub := rctx.WithContext(ctx)
+	sub.RequestID = liveID
+
+	err := s.live.Subscribe("SocketCoquery", sub, func(res *coquery.Response, rerr coquery.ResponseError) {
+		if rerr != nil {
+			rw.Write("SocketCoquery", nil, rerr)
+			return
+		}
+
+		raw, err := json.Marshal(data.ResponsePack{
+			RecordKey: s.store.Key(),
+			RequestID: rid,
+			Results:   res.Data,
+		})
+		if err != nil {
+			s.Error("SocketCoquery", "serveLive", err, "Info : JSON.Marshal")
+			return
+		}
+
+		c.track(s.store.Key(), res.Data)
+		c.send(&data.SocketMessage{Type: data.SocketLive, RequestID: rid, Reply: raw})
+	})
+
+	if err != nil {
+		rw.Write("SocketCoquery", nil, err)
+	}
+}
+
+// pushDeltas sends the delta of the records changed since the last one sent
+// each time the connection is signalled, until the context is done or a send
+// fails.
+func (s *socketCoquery) pushDeltas(ctx context.Context, c *conn, changed <-chan struct{}) {
+	lastID := s.latest()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-changed:
+			var err error
+			if lastID, err = s.catchUp(c, lastID); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// catchUp sends the delta of the records read over the connection which
+// changed since the giving diff, returning the id of the latest diff. All the
+// known changes are considered if the diff is unknown.
+func (s *socketCoquery) catchUp(c *conn, lastID string) (string, error) {
+	latest := s.latest()
+	if latest == lastID {
+		return lastID, nil
+	}
+
+	var changes []string
+
+	switch {
+	case lastID == "" || !s.diff.Has(lastID):
+		for key := range s.diff.Diffs() {
+			changes = append(changes, key)
+		}
+
+		sort.Strings(changes)
+	default:
+		changes = s.diff.PullFrom(lastID)
+	}
+
+	if changes = c.watched(changes); len(changes) == 0 {
+		return latest, nil
+	}
+
+	return latest, c.send(&data.SocketMessage{
+		Type:    data.SocketDelta,
+		DeltaID: latest,
+		Deltas:  changes,
+	})
+}
+
+// latest returns the id of the latest diff, which is empty for no diffs.
+func (s *socketCoquery) latest() string {
+	return coquery.LatestDiff(s.diff)
+}
+
+// keepAlive pings the connection every PingInterval until the context is
+// done or a ping fails.
+func (s *socketCoquery) keepAlive(ctx context.Context, c *conn) {
+	ticker := time.NewTicker(PingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := c.ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+//==============================================================================
@@ -0,0 +1,256 @@
+package websocket_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ardanlabs/kit/tests"
+	gws "github.com/gorilla/websocket"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/client/web"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/protocols/cohttp"
+	"github.com/influx6/coquery/protocols/websocket"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+var events eventlog
+
+// eventlog provides a logger which discards its events.
+type eventlog struct{}
+
+// Log discards the standard log reports.
+func (l eventlog) Log(context interface{}, name string, message string, data ...interface{}) {}
+
+// Error discards the error reports.
+func (l eventlog) Error(context interface{}, name string, err error, message string, data ...interface{}) {
+}
+
+var context = "testing"
+
+//==============================================================================
+
+// greeter provides a Document which replies a greeting to every request.
+type greeter struct{}
+
+// Handle replies the greeting.
+func (greeter) Handle(context interface{}, reqs coquery.RecordRequests, res coquery.ResponseWriter) {
+	res.Write(context, &coquery.Response{
+		Req:  reqs[0],
+		Data: data.Parameters{{"id": 1, "greeting": "Hello World!"}},
+	}, nil)
+}
+
+// counter provides a Document which replies the number of times it was
+// requested.
+type counter struct {
+	hits int64
+}
+
+// Handle replies the record with the count of requests.
+func (c *counter) Handle(context interface{}, reqs coquery.RecordRequests, res coquery.ResponseWriter) {
+	res.Write(context, &coquery.Response{
+		Req:  reqs[0],
+		Data: data.Parameters{{"id": 1, "count": atomic.AddInt64(&c.hits, 1)}},
+	}, nil)
+}
+
+//==============================================================================
+
+// TestWebSocket validates the serving of requests and the pushing of deltas
+// over websockets.
+func TestWebSocket(t *testing.T) {
+	t.Logf("Given the need to serve coquery requests over websockets")
+	{
+		store := storage.New("id")
+		diffs := coquery.NewDiffs(events)
+
+		server := websocket.New(events, diffs, store)
+		server.Route(context, "doc").
+			Document(context, "greetings", &coquery.BasicQueries{EventLog: events, Store: store}, greeter{}).
+			Document(context, "counts", &coquery.BasicQueries{EventLog: events, Store: store}, &counter{})
+
+		hs := httptest.NewServer(server)
+		defer hs.Close()
+
+		deltas := make(chan []string, 1)
+
+		transport := web.NewSocket()
+		transport.Deltas = func(id string, records []string) {
+			select {
+			case deltas <- records:
+			default:
+			}
+		}
+
+		do := func(rid string, queries ...string) (data.ResponsePack, error) {
+			body, _ := json.Marshal(data.RequestContext{RequestID: rid, Queries: queries})
+			return transport.Do(hs.URL, bytes.NewReader(body))
+		}
+
+		t.Logf("\tWhen sending requests over a connection")
+		{
+			results := make(chan error, 3)
+
+			for _, rid := range []string{"a1", "b2", "c3"} {
+				go func(rid string) {
+					pack, err := do(rid, "doc.greetings.find(id,1)")
+					if err == nil && (pack.RequestID != rid || len(pack.Results) != 1) {
+						err = fmt.Errorf("unexpected reply %+v", pack)
+					}
+
+					results <- err
+				}(rid)
+			}
+
+			for i := 0; i < 3; i++ {
+				if err := <-results; err != nil {
+					t.Fatalf("\t%s\tShould have replied each request: %s", tests.Failed, err)
+				}
+			}
+			t.Logf("\t%s\tShould have replied each request", tests.Success)
+		}
+
+		t.Logf("\tWhen sending a invalid query")
+		{
+			if _, err := do("d4", "doc.greetings"); err == nil {
+				t.Fatalf("\t%s\tShould have replied the error", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have replied the error", tests.Success)
+		}
+
+		t.Logf("\tWhen records change")
+		{
+			diffs.Put([]string{"1", "4"})
+
+			select {
+			case records := <-deltas:
+				if len(records) != 1 || records[0] != "1" {
+					t.Fatalf("\t%s\tShould have pushed the changed records read over the connection: %s", tests.Failed, records)
+				}
+				t.Logf("\t%s\tShould have pushed the changed records read over the connection", tests.Success)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("\t%s\tShould have pushed the changed records read over the connection", tests.Failed)
+			}
+		}
+
+		t.Logf("\tWhen records not read over the connection change")
+		{
+			diffs.Put([]string{"4"})
+			diffs.Put([]string{"1"})
+
+			select {
+			case records := <-deltas:
+				if len(records) != 1 || records[0] != "1" {
+					t.Fatalf("\t%s\tShould not have pushed the unread records: %s", tests.Failed, records)
+				}
+				t.Logf("\t%s\tShould not have pushed the unread records", tests.Success)
+			case <-time.After(2 * time.Second):
+				t.Fatalf("\t%s\tShould have pushed the changed records read over the connection", tests.Failed)
+			}
+		}
+
+		t.Logf("\tWhen subscribing to a live query")
+		{
+			counts := make(chan float64, 4)
+
+			stop, err := transport.Subscribe(hs.URL, data.RequestContext{
+				RequestID: "e5",
+				Queries:   []string{"doc.counts.find(id,1)"},
+			}, func(pack data.ResponsePack, err error) {
+				if err == nil && pack.RequestID == "e5" && len(pack.Results) == 1 {
+					count, _ := pack.Results[0]["count"].(float64)
+					counts <- count
+				}
+			})
+
+			if err != nil {
+				t.Fatalf("\t%s\tShould have subscribed to the query: %s", tests.Failed, err)
+			}
+			defer stop()
+
+			expect := func(count float64) {
+				select {
+				case got := <-counts:
+					if got != count {
+						t.Fatalf("\t%s\tShould have pushed the count %v: %v", tests.Failed, count, got)
+					}
+					t.Logf("\t%s\tShould have pushed the count %v", tests.Success, count)
+				case <-time.After(2 * time.Second):
+					t.Fatalf("\t%s\tShould have pushed the count %v", tests.Failed, count)
+				}
+			}
+
+			expect(1)
+
+			diffs.Put([]string{"1"})
+			expect(2)
+		}
+	}
+}
+
+//==============================================================================
+
+// TestReadLimit validates the closing of connections sending messages larger
+// than cohttp.MaxBodySize.
+func TestReadLimit(t *testing.T) {
+	defer func(size int64) { cohttp.MaxBodySize = size }(cohttp.MaxBodySize)
+	cohttp.MaxBodySize = 256
+
+	t.Logf("Given the need to bound the messages read over websockets")
+	{
+		store := storage.New("id")
+
+		server := websocket.New(events, coquery.NewDiffs(events), store)
+		server.Route(context, "doc").
+			Document(context, "greetings", &coquery.BasicQueries{EventLog: events, Store: store}, greeter{})
+
+		hs := httptest.NewServer(server)
+		defer hs.Close()
+
+		ws, _, err := gws.DefaultDialer.Dial("ws"+strings.TrimPrefix(hs.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("\t%s\tShould have connected: %s", tests.Failed, err)
+		}
+		defer ws.Close()
+
+		ws.SetReadDeadline(time.Now().Add(2 * time.Second))
+
+		t.Logf("\tWhen sending a message within the limit")
+		{
+			if err := ws.WriteJSON(data.RequestContext{RequestID: "a1", Queries: []string{"doc.greetings.find(id,1)"}}); err != nil {
+				t.Fatalf("\t%s\tShould have sent the message: %s", tests.Failed, err)
+			}
+
+			var msg data.SocketMessage
+			if err := ws.ReadJSON(&msg); err != nil || msg.Type != data.SocketReply {
+				t.Fatalf("\t%s\tShould have replied the request: %+v : %v", tests.Failed, msg, err)
+			}
+			t.Logf("\t%s\tShould have replied the request", tests.Success)
+		}
+
+		t.Logf("\tWhen sending a message over the limit")
+		{
+			queries := []string{strings.Repeat("doc.greetings.find(id,1)", 16)}
+			if err := ws.WriteJSON(data.RequestContext{RequestID: "b2", Queries: queries}); err != nil {
+				t.Fatalf("\t%s\tShould have sent the message: %s", tests.Failed, err)
+			}
+
+			var msg data.SocketMessage
+			if err := ws.ReadJSON(&msg); !gws.IsCloseError(err, gws.CloseMessageTooBig) {
+				t.Fatalf("\t%s\tShould have closed the connection: %+v : %v", tests.Failed, msg, err)
+			}
+			t.Logf("\t%s\tShould have closed the connection", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
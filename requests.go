@@ -1,10 +1,10 @@
 package coquery
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"strconv"
 	"strings"
 	"time"
 
@@ -47,11 +47,23 @@ type RecordRequests []RecordRequest
 
 // Request presents a request to be served to the underline system which
 // allows each request access to its previous result and request apart from
-// its current request.
+// its current request. Ctx is the context.Context of the query the request
+// belongs to, processors abandon their work once it is done.
 type Request struct {
 	R            RecordRequest
 	Last         RecordRequest
 	LastResponse *Response
+	Ctx          context.Context
+}
+
+// Context returns the context.Context of the request, which is
+// context.Background if none was set.
+func (r *Request) Context() context.Context {
+	if r.Ctx == nil {
+		return context.Background()
+	}
+
+	return r.Ctx
 }
 
 // FindN defines a record request to retrieve data based on a set amount.
@@ -72,20 +84,22 @@ func (f *FindN) RequestID() string {
 	return f.RID
 }
 
-// Example returns a string that showcase a sample of this request.
+// Examples returns a string that showcase a sample of this request.
 // In truth this provides a code-level sample information and nothing more.
-func (f *FindN) Example() []string {
+func (f *FindN) Examples() []string {
 	return []string{"findN(10,20)", "findN(-1)", "findN(10)"}
 }
 
 //==============================================================================
 
-// Find defines a record retrieve request based on the KV query.
+// Find defines a record retrieve request based on the KV query. Value holds
+// the typed value from the query, which can be a string, int, float64, bool,
+// nil, data.ObjectID or a map or slice of those.
 type Find struct {
-	Doc   string `json:"doc" bson:"doc"`
-	RID   string `json:"rid" bson:"rid"`
-	Key   string `json:"key" bson:"key"`
-	Value string `json:"value" bson:"value"`
+	Doc   string      `json:"doc" bson:"doc"`
+	RID   string      `json:"rid" bson:"rid"`
+	Key   string      `json:"key" bson:"key"`
+	Value interface{} `json:"value" bson:"value"`
 }
 
 // RequestName returns the name for the giving request type.
@@ -98,10 +112,10 @@ func (f *Find) RequestID() string {
 	return f.RID
 }
 
-// Example returns a string that showcase a sample of this request.
+// Examples returns a string that showcase a sample of this request.
 // In truth this provides a code-level sample information and nothing more.
-func (f *Find) Example() []string {
-	return []string{"find(id,4023)", "find(name,'alex')"}
+func (f *Find) Examples() []string {
+	return []string{"find(id,4023)", "find(name,'alex')", "find(zip,\"02134\")", "find(_id,oid(\"5707a1d1e4b0e5a0c8f2b1a3\"))"}
 }
 
 //==============================================================================
@@ -123,20 +137,27 @@ func (f *Collects) RequestID() string {
 	return f.RID
 }
 
-// Example returns a string that showcase a sample of this request.
+// Examples returns a string that showcase a sample of this request.
 // In truth this provides a code-level sample information and nothing more.
-func (f *Collects) Example() []string {
+func (f *Collects) Examples() []string {
 	return []string{"collect(name,age,created_at)"}
 }
 
 //==============================================================================
 
 // Mutate provides json data to be saved/augmented into a new version of the
-// current document.
+// current document. When the data uses update operators eg {$inc:{views:1}},
+// Update holds the operations to be applied to the selected records instead.
+// IfVersion when set, requires the selected records to be at that version
+// else the mutation fails with a *ConflictError.
 type Mutate struct {
-	Doc       string         `json:"doc" bson:"doc"`
-	RID       string         `json:"rid" bson:"rid"`
-	Parameter data.Parameter `json:"params" bson:"params"`
+	Doc       string          `json:"doc" bson:"doc"`
+	RID       string          `json:"rid" bson:"rid"`
+	Parameter data.Parameter  `json:"params" bson:"params"`
+	Update    *storage.Update `json:"update,omitempty" bson:"update,omitempty"`
+	IfVersion *int64          `json:"if_version,omitempty" bson:"if_version,omitempty"`
+	Journal   *Journal        `json:"-" bson:"-"`
+	Principal *data.Principal `json:"-" bson:"-"`
 }
 
 // RequestID returns the request id for this request object.
@@ -149,24 +170,351 @@ func (f *Mutate) RequestName() string {
 	return "mutate"
 }
 
-// Example returns a string that showcase a sample of this request.
-func (f *Mutate) Example() []string {
-	return []string{"mutate({name:'alex'})"}
+// UseJournal sets the Journal the mutations are recorded into.
+func (f *Mutate) UseJournal(j *Journal) {
+	f.Journal = j
+}
+
+// UsePrincipal sets the principal making the request.
+func (f *Mutate) UsePrincipal(p *data.Principal) {
+	f.Principal = p
+}
+
+// Examples returns a string that showcase a sample of this request.
+func (f *Mutate) Examples() []string {
+	return []string{"mutate({name:'alex'})", "mutate({$inc:{views:1}, $push:{tags:'x'}, $unset:['tmp']})", "mutate({name:'alex'}, ifVersion=7)"}
+}
+
+//==============================================================================
+
+// Create defines a request to insert new records into a document. The record
+// keys are generated by the document, which rejects records whose keys exist
+// already. The created records are returned.
+type Create struct {
+	Doc       string          `json:"doc" bson:"doc"`
+	RID       string          `json:"rid" bson:"rid"`
+	Records   data.Parameters `json:"records" bson:"records"`
+	Journal   *Journal        `json:"-" bson:"-"`
+	Principal *data.Principal `json:"-" bson:"-"`
+}
+
+// RequestID returns the request id for this request object.
+func (f *Create) RequestID() string {
+	return f.RID
+}
+
+// RequestName returns the name for the giving request type.
+func (f *Create) RequestName() string {
+	return "create"
+}
+
+// UseJournal sets the Journal the created records are recorded into.
+func (f *Create) UseJournal(j *Journal) {
+	f.Journal = j
+}
+
+// UsePrincipal sets the principal making the request.
+func (f *Create) UsePrincipal(p *data.Principal) {
+	f.Principal = p
+}
+
+// Examples returns a string that showcase a sample of this request.
+func (f *Create) Examples() []string {
+	return []string{"create({name:'alex',age:20})", "create({name:'alex'},{name:'ruth'})", "create([{name:'alex'},{name:'ruth'}])"}
+}
+
+//==============================================================================
+
+// Remove defines a request to delete the records selected by the requests
+// before it eg find(id,3).remove(). The deleted records are returned.
+type Remove struct {
+	Doc       string          `json:"doc" bson:"doc"`
+	RID       string          `json:"rid" bson:"rid"`
+	Journal   *Journal        `json:"-" bson:"-"`
+	Principal *data.Principal `json:"-" bson:"-"`
+}
+
+// RequestID returns the request id for this request object.
+func (f *Remove) RequestID() string {
+	return f.RID
+}
+
+// RequestName returns the name for the giving request type.
+func (f *Remove) RequestName() string {
+	return "remove"
+}
+
+// UseJournal sets the Journal the removals are recorded into.
+func (f *Remove) UseJournal(j *Journal) {
+	f.Journal = j
+}
+
+// UsePrincipal sets the principal making the request.
+func (f *Remove) UsePrincipal(p *data.Principal) {
+	f.Principal = p
+}
+
+// Examples returns a string that showcase a sample of this request.
+func (f *Remove) Examples() []string {
+	return []string{"find(id,3).remove()", "where(age < 18).remove()"}
+}
+
+//==============================================================================
+
+// Where defines a record retrieve request which filters records using the
+// predicate tree built from a where(...) expression.
+type Where struct {
+	Doc       string     `json:"doc" bson:"doc"`
+	RID       string     `json:"rid" bson:"rid"`
+	Predicate *Predicate `json:"predicate" bson:"predicate"`
+}
+
+// RequestName returns the name for the giving request type.
+func (f *Where) RequestName() string {
+	return "where"
+}
+
+// RequestID returns the request id for this request object.
+func (f *Where) RequestID() string {
+	return f.RID
+}
+
+// Examples returns a string that showcase a sample of this request.
+// In truth this provides a code-level sample information and nothing more.
+func (f *Where) Examples() []string {
+	return []string{
+		"where(age >= 21)",
+		"where(age > 21 && status in [\"active\",\"pending\"])",
+		"where(name == 'alex' || !(address.zip not in [\"02134\"]))",
+	}
+}
+
+//==============================================================================
+
+// SortField defines a record key to sort by and its direction.
+type SortField struct {
+	Key  string `json:"key" bson:"key"`
+	Desc bool   `json:"desc" bson:"desc"`
+}
+
+// Sort defines a request to order records by the giving fields, where each
+// later field orders the records which are equal by the former.
+type Sort struct {
+	Doc    string      `json:"doc" bson:"doc"`
+	RID    string      `json:"rid" bson:"rid"`
+	Fields []SortField `json:"fields" bson:"fields"`
+}
+
+// RequestName returns the name for the giving request type.
+func (f *Sort) RequestName() string {
+	return "sort"
+}
+
+// RequestID returns the request id for this request object.
+func (f *Sort) RequestID() string {
+	return f.RID
+}
+
+// Examples returns a string that showcase a sample of this request.
+// In truth this provides a code-level sample information and nothing more.
+func (f *Sort) Examples() []string {
+	return []string{"sort(name)", "sort(-age,name)", "sort(address.zip,'-created_at')"}
+}
+
+//==============================================================================
+
+// Page defines a request to retrieve a page of records in the order of its
+// Sort fields, which always end with the record key so every record has a
+// unique position. When After is set, the page starts after the record it
+// points to, allowing backends to seek by key rather than skip records.
+// Records are expected to have all the sort fields, as those missing them
+// are never seen after the first page. A preceding where(...) is held in
+// Match, so backends can filter and seek in a single step.
+type Page struct {
+	Doc   string      `json:"doc" bson:"doc"`
+	RID   string      `json:"rid" bson:"rid"`
+	Size  int         `json:"size" bson:"size"`
+	Sort  []SortField `json:"sort" bson:"sort"`
+	Match *Predicate  `json:"match,omitempty" bson:"match,omitempty"`
+	After *Cursor     `json:"-" bson:"-"`
+}
+
+// RequestName returns the name for the giving request type.
+func (f *Page) RequestName() string {
+	return "page"
+}
+
+// RequestID returns the request id for this request object.
+func (f *Page) RequestID() string {
+	return f.RID
+}
+
+// Examples returns a string that showcase a sample of this request.
+// In truth this provides a code-level sample information and nothing more.
+func (f *Page) Examples() []string {
+	return []string{"page(20)", "where(age > 21).sort(-age).page(20)", "sort(-age).page(20,'eyJrIjpbIi1hZ2UiLCJpZCJdLCJ2IjpbMzAsNF19')"}
+}
+
+// Predicate returns the predicate selecting the records of the page, its
+// Match and the records after its cursor, which is nil when neither is set.
+func (f *Page) Predicate() *Predicate {
+	var conds []*Predicate
+
+	if f.Match != nil {
+		conds = append(conds, f.Match)
+	}
+
+	if f.After != nil {
+		conds = append(conds, f.After.After())
+	}
+
+	switch len(conds) {
+	case 0:
+		return nil
+	case 1:
+		return conds[0]
+	}
+
+	return &Predicate{Op: OpAnd, Operands: conds}
+}
+
+// Next returns the response for the giving page records, setting its next
+// cursor if more records remain after them.
+func (f *Page) Next(records data.Parameters, more bool) (*Response, error) {
+	res := Response{Req: f, Data: records}
+
+	if !more || len(records) == 0 {
+		return &res, nil
+	}
+
+	cursor, err := NewCursor(records[len(records)-1], f.Sort).Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	res.NextCursor = cursor
+	return &res, nil
+}
+
+//==============================================================================
+
+// contains the operations supported by Aggregate requests.
+const (
+	AggCount = "count"
+	AggSum   = "sum"
+	AggAvg   = "avg"
+	AggMin   = "min"
+	AggMax   = "max"
+)
+
+// Aggregate defines a request to compute a single value over records eg their
+// count or the sum of a field. When Group is set, a value is computed for each
+// distinct value of the Group field. A directly preceding where(...) is held
+// in Match, so backends can filter and aggregate in a single step.
+//
+// The response holds a record per group with the group value under "group"
+// and the computed value under the name of the operation eg
+// {"group":"NY","count":4}.
+type Aggregate struct {
+	Doc   string     `json:"doc" bson:"doc"`
+	RID   string     `json:"rid" bson:"rid"`
+	Op    string     `json:"op" bson:"op"`
+	Field string     `json:"field,omitempty" bson:"field,omitempty"`
+	Group string     `json:"group,omitempty" bson:"group,omitempty"`
+	Match *Predicate `json:"match,omitempty" bson:"match,omitempty"`
+}
+
+// RequestName returns the name for the giving request type.
+func (f *Aggregate) RequestName() string {
+	return "aggregate"
+}
+
+// RequestID returns the request id for this request object.
+func (f *Aggregate) RequestID() string {
+	return f.RID
+}
+
+// Examples returns a string that showcase a sample of this request.
+// In truth this provides a code-level sample information and nothing more.
+func (f *Aggregate) Examples() []string {
+	return []string{"count()", "sum(balance)", "where(age > 21).avg(age)", "groupBy(address.state).max(age)"}
+}
+
+//==============================================================================
+
+// Join defines a request to embed the records of another document within each
+// record, retrieving them with a single request against that document for all
+// the records. Records of the Path document whose Field value matches the
+// record's Local value are embedded under As.
+//
+// A join(books,uid) embeds the list of books whose uid is the record's key
+// under "books", while an expand(author -> docs.users) replaces the record's
+// author value, or each item of a list of author values, with the users record
+// it refers to.
+//
+// The records are resolved for the Principal making the request, so they are
+// served under the rules of the document they are held by.
+type Join struct {
+	Doc       string          `json:"doc" bson:"doc"`
+	RID       string          `json:"rid" bson:"rid"`
+	Path      string          `json:"path" bson:"path"`
+	Field     string          `json:"field" bson:"field"`
+	Local     string          `json:"local" bson:"local"`
+	As        string          `json:"as" bson:"as"`
+	Expand    bool            `json:"expand" bson:"expand"`
+	Resolver  Resolver        `json:"-" bson:"-"`
+	Principal *data.Principal `json:"-" bson:"-"`
+}
+
+// RequestName returns the name for the giving request type.
+func (f *Join) RequestName() string {
+	if f.Expand {
+		return "expand"
+	}
+
+	return "join"
+}
+
+// RequestID returns the request id for this request object.
+func (f *Join) RequestID() string {
+	return f.RID
+}
+
+// UseResolver sets the Resolver used to retrieve the records to embed.
+func (f *Join) UseResolver(r Resolver) {
+	f.Resolver = r
+}
+
+// UsePrincipal sets the principal making the request.
+func (f *Join) UsePrincipal(p *data.Principal) {
+	f.Principal = p
+}
+
+// Examples returns a string that showcase a sample of this request.
+// In truth this provides a code-level sample information and nothing more.
+func (f *Join) Examples() []string {
+	return []string{"join(books,uid)", "join(docs.books,uid,author_id)", "expand(author -> docs.users)", "expand(tags -> tags.slug)"}
 }
 
 //==============================================================================
 
 // BasicQueries provides a base level query processsor for the coquery library.
+// Methods provides the query methods it understands, when nil DefaultMethods
+// is used.
 type BasicQueries struct {
 	EventLog
 	storage.Store
-	Doc string
+	Doc     string
+	Methods *MethodRegistry
+	Limits  *Limits
 }
 
 // Generate takes the underline queries and generates the corresponding query
 // objects matching the giving functions, if it finds an unrecognized function,
-// it returns a ResponseError instead.
-func (b *BasicQueries) Generate(context interface{}, reqid string, doc string, queries []string) (RecordRequests, ResponseError) {
+// it returns a ResponseError instead. The methods are those of the Methods
+// registry, else of DefaultMethods. Queries exceeding the MaxDepth or
+// MaxRecords of the Limits, if any, are rejected with a *LimitError.
+func (b *BasicQueries) Generate(context interface{}, reqid string, doc string, calls []*parser.Call) (RecordRequests, ResponseError) {
 
 	// If we are alocated a custom document name, over-write the incoming with
 	// this.
@@ -174,173 +522,324 @@ func (b *BasicQueries) Generate(context interface{}, reqid string, doc string, q
 		doc = b.Doc
 	}
 
-	b.Log(context, "BasicQueries.Generate", "Started : Doc[%s] : Queries : %s", doc, queries)
+	b.Log(context, "BasicQueries.Generate", "Started : Doc[%s] : Queries : %s", doc, calls)
 
-	var reqs RecordRequests
+	methods := b.Methods
+	if methods == nil {
+		methods = DefaultMethods
+	}
 
-	for _, qs := range queries {
-
-		// Get the request name and its parameters
-		method, _, params := parser.SplitQuery(context, qs)
-
-		switch strings.ToLower(method) {
-		case "findn":
-
-			switch len(params) {
-			case 0:
-				reqs = append(reqs, &FindN{
-					Doc:    doc,
-					RID:    reqid,
-					Skip:   0,
-					Amount: -1,
-				})
-				continue
-			case 1:
-				count, err := strconv.Atoi(params[0])
-				if err != nil {
-					err := &CoError{
-						Rid:    reqid,
-						Msg:    fmt.Sprintf("Invalid Integer String"),
-						IError: err,
-					}
-
-					b.Error(context, "BasicQueries.Generate", err, "Completed")
-					return nil, err
-				}
-
-				reqs = append(reqs, &FindN{
-					Doc:    doc,
-					RID:    reqid,
-					Amount: count,
-					Skip:   0,
-				})
-				continue
-			default:
-				count, err := strconv.Atoi(params[0])
-				if err != nil {
-					err := &CoError{
-						Rid:    reqid,
-						Msg:    fmt.Sprintf("Invalid Integer String for Count"),
-						IError: err,
-					}
-
-					b.Error(context, "BasicQueries.Generate", err, "Completed")
-					return nil, err
-				}
-
-				skip, err := strconv.Atoi(params[1])
-				if err != nil {
-					err := &CoError{
-						Rid:    reqid,
-						Msg:    fmt.Sprintf("Invalid Integer String for Skip"),
-						IError: err,
-					}
-
-					b.Error(context, "BasicQueries.Generate", err, "Completed")
-					return nil, err
-				}
-
-				reqs = append(reqs, &FindN{
-					Doc:    doc,
-					RID:    reqid,
-					Amount: count,
-					Skip:   skip,
-				})
-
-				continue
-			}
+	if err := b.Limits.CheckQuery(reqid, calls); err != nil {
+		b.Error(context, "BasicQueries.Generate", err, "Completed")
+		return nil, err
+	}
 
-		case "find":
-
-			switch len(params) {
-			case 0:
-				err := &CoError{
-					Rid:    reqid,
-					Msg:    fmt.Sprintf("Expected key information"),
-					IError: fmt.Errorf("find requires record key as first argument"),
-				}
-
-				b.Error(context, "BasicQueries.Generate", err, "Completed")
-				return nil, err
-
-			case 1:
-				err := &CoError{
-					Rid:    reqid,
-					Msg:    fmt.Sprintf("Expected value information"),
-					IError: fmt.Errorf("find requires record value as second argument"),
-				}
-
-				b.Error(context, "BasicQueries.Generate", err, "Completed")
-				return nil, err
-
-			default:
-				reqs = append(reqs, &Find{
-					Doc:   doc,
-					RID:   reqid,
-					Key:   params[0],
-					Value: params[1],
-				})
-				continue
-			}
+	var reqs RecordRequests
 
-		case "collects":
+	for _, call := range calls {
+		if err := pendingGroup(reqs, call); err != nil {
+			err := &CoError{
+				Rid:    reqid,
+				Msg:    fmt.Sprintf("Expected aggregate after groupBy"),
+				IError: err,
+			}
 
-			params = append([]string{b.Store.Key()}, params...)
-			reqs = append(reqs, &Collects{
-				RID:  reqid,
-				Doc:  doc,
-				Keys: params,
-			})
-			continue
+			b.Error(context, "BasicQueries.Generate", err, "Completed")
+			return nil, err
+		}
 
-		case "mutate":
+		method, ok := methods.Method(call.Name)
+		if !ok {
+			err := &CoError{
+				Rid:    reqid,
+				Msg:    fmt.Sprintf("Invalid Query Method[%s]", call.Name),
+				IError: call.Errorf(nil, "unknown query method"),
+			}
 
-			if len(params) == 0 {
-				err := &CoError{
-					Rid:    reqid,
-					Msg:    fmt.Sprintf("Expected JSON data"),
-					IError: fmt.Errorf("Mutate requires json data as argument"),
-				}
+			b.Error(context, "BasicQueries.Generate", err, "Completed")
+			return nil, err
+		}
 
-				b.Error(context, "BasicQueries.Generate", err, "Completed")
-				return nil, err
+		mc, err := method.bind(call)
+		if err != nil {
+			err := &CoError{
+				Rid:    reqid,
+				Msg:    fmt.Sprintf("Invalid Arguments : %s", call.Raw),
+				IError: err,
 			}
 
-			pm := make(data.Parameter)
+			b.Error(context, "BasicQueries.Generate", err, "Completed")
+			return nil, err
+		}
 
-			if err := json.Unmarshal([]byte(params[0]), &pm); err != nil {
-				err := &CoError{
-					Rid:    reqid,
-					Msg:    fmt.Sprintf("Invalid JSON : %s", params[0]),
-					IError: err,
-				}
+		mc.RID = reqid
+		mc.Doc = doc
+		mc.Key = b.Store.Key()
+		mc.Reqs = reqs
 
-				b.Error(context, "BasicQueries.Generate", err, "Completed")
-				return nil, err
+		built, err := method.Build(mc)
+		if err != nil {
+			if rerr, ok := err.(ResponseError); ok {
+				b.Error(context, "BasicQueries.Generate", rerr, "Completed")
+				return nil, rerr
 			}
 
-			reqs = append(reqs, &Mutate{
-				RID:       reqid,
-				Doc:       doc,
-				Parameter: pm,
-			})
-
-			continue
-
-		default:
 			err := &CoError{
 				Rid:    reqid,
-				Msg:    fmt.Sprintf("Invalid Query Method[%s]", method),
-				IError: errors.New("404"),
+				Msg:    fmt.Sprintf("Invalid %s : %s", method.Name, call.Raw),
+				IError: err,
 			}
 
 			b.Error(context, "BasicQueries.Generate", err, "Completed")
 			return nil, err
 		}
+
+		reqs = built
+	}
+
+	if err := pendingGroup(reqs, nil); err != nil {
+		err := &CoError{
+			Rid:    reqid,
+			Msg:    fmt.Sprintf("Expected aggregate after groupBy"),
+			IError: err,
+		}
+
+		b.Error(context, "BasicQueries.Generate", err, "Completed")
+		return nil, err
+	}
+
+	if err := b.Limits.CheckRequests(reqid, reqs); err != nil {
+		b.Error(context, "BasicQueries.Generate", err, "Completed")
+		return nil, err
 	}
 
 	b.Log(context, "BasicQueries.Generate", "Completed")
 	return reqs, nil
 }
 
+// pendingGroup returns a *parser.SyntaxError if the last request is a
+// groupBy which the giving call, nil at the end of the query, does not
+// aggregate.
+func pendingGroup(reqs RecordRequests, call *parser.Call) error {
+	if len(reqs) == 0 {
+		return nil
+	}
+
+	group, ok := reqs[len(reqs)-1].(*groupBy)
+	if !ok || (call != nil && isAggregate(strings.ToLower(call.Name))) {
+		return nil
+	}
+
+	return group.call.Errorf(nil, "groupBy must be followed by count, sum, avg, min or max")
+}
+
+//==============================================================================
+
+// intArg returns the integer value of the giving argument else a
+// *parser.SyntaxError.
+func intArg(call *parser.Call, arg parser.Node) (int, error) {
+	num, ok := arg.(*parser.NumberLit)
+	if !ok || !num.IsInt {
+		return 0, call.Errorf(arg, "expected integer argument")
+	}
+
+	return int(num.Int), nil
+}
+
+// nameArg returns the key name provided by an identifier or string argument
+// else a *parser.SyntaxError.
+func nameArg(call *parser.Call, arg parser.Node) (string, error) {
+	switch name := arg.(type) {
+	case *parser.Ident:
+		return name.Name, nil
+	case *parser.StringLit:
+		return name.Value, nil
+	}
+
+	return "", call.Errorf(arg, "expected key name argument")
+}
+
+// sortArg returns the sort field for the giving argument, which is a key name
+// for ascending order or a key name prefixed with '-' for descending order
+// eg -age or '-age'.
+func sortArg(call *parser.Call, arg parser.Node) (SortField, error) {
+	if neg, ok := arg.(*parser.UnaryExpr); ok && neg.Op == "-" {
+		key, err := nameArg(call, neg.X)
+		if err != nil {
+			return SortField{}, err
+		}
+
+		return SortField{Key: key, Desc: true}, nil
+	}
+
+	key, err := nameArg(call, arg)
+	if err != nil {
+		return SortField{}, err
+	}
+
+	if strings.HasPrefix(key, "-") {
+		return SortField{Key: strings.TrimPrefix(key, "-"), Desc: true}, nil
+	}
+
+	return SortField{Key: key}, nil
+}
+
+// expandArg returns the Join for the arguments of a expand(local -> path)
+// call. The path is either a document of the same route eg users, a root and
+// document eg docs.users, or a root, document and key eg docs.users.uid. When
+// the key is not given, the referenced records are matched by a key of the
+// same name as this document's record key, the giving key.
+func expandArg(call *parser.Call, arg parser.Node, key string) (*Join, error) {
+	ref, ok := arg.(*parser.BinaryExpr)
+	if !ok || ref.Op != "->" {
+		return nil, call.Errorf(arg, "expected a reference eg author -> docs.users")
+	}
+
+	local, err := nameArg(call, ref.Left)
+	if err != nil {
+		return nil, err
+	}
+
+	target, ok := ref.Right.(*parser.Ident)
+	if !ok {
+		return nil, call.Errorf(ref.Right, "expected a document path eg docs.users")
+	}
+
+	join := Join{
+		Local:  local,
+		As:     local,
+		Field:  key,
+		Path:   target.Name,
+		Expand: true,
+	}
+
+	switch parts := strings.Split(target.Name, "."); len(parts) {
+	case 1, 2:
+	case 3:
+		join.Path = parts[0] + "." + parts[1]
+		join.Field = parts[2]
+	default:
+		return nil, call.Errorf(target, "expected a document path eg docs.users or docs.users.id")
+	}
+
+	return &join, nil
+}
+
+// isAggregate returns true/false if the giving lowercased method name is an
+// aggregate operation.
+func isAggregate(name string) bool {
+	switch name {
+	case AggCount, AggSum, AggAvg, AggMin, AggMax:
+		return true
+	}
+
+	return false
+}
+
+// withKey returns the sort fields ending with the record key in ascending
+// order, if they do not already include it.
+func withKey(fields []SortField, key string) []SortField {
+	for _, field := range fields {
+		if field.Key == key {
+			return fields
+		}
+	}
+
+	sorted := make([]SortField, 0, len(fields)+1)
+	sorted = append(sorted, fields...)
+
+	return append(sorted, SortField{Key: key})
+}
+
+// cursorArg returns the decoded cursor of the giving string argument, an empty
+// string or null returns a nil cursor.
+func cursorArg(call *parser.Call, arg parser.Node, sort []SortField) (*Cursor, error) {
+	switch cursor := arg.(type) {
+	case *parser.NullLit:
+		return nil, nil
+
+	case *parser.StringLit:
+		if cursor.Value == "" {
+			return nil, nil
+		}
+
+		after, err := DecodeCursor(cursor.Value, sort)
+		if err != nil {
+			return nil, call.Errorf(arg, "cursor is invalid or was created for a different sort order")
+		}
+
+		return after, nil
+	}
+
+	return nil, call.Errorf(arg, "expected cursor string argument")
+}
+
+// valueArg returns the go value of the giving literal argument else a
+// *parser.SyntaxError if the argument is an expression.
+func valueArg(call *parser.Call, arg parser.Node) (interface{}, error) {
+	val, ok := arg.(parser.Value)
+	if !ok {
+		return nil, call.Errorf(arg, "expected a value argument")
+	}
+
+	return val.Interface(), nil
+}
+
+// recordArgs returns the records provided by the giving object arguments or a
+// single array of objects.
+func recordArgs(call *parser.Call, params []parser.Node) (data.Parameters, error) {
+	if len(params) == 0 {
+		return nil, call.Errorf(nil, "expected atleast one record as argument")
+	}
+
+	if arr, ok := params[0].(*parser.ArrayLit); ok && len(params) == 1 {
+		params = nil
+
+		for _, item := range arr.Items {
+			params = append(params, item)
+		}
+
+		if len(params) == 0 {
+			return nil, call.Errorf(arr, "expected atleast one record within array")
+		}
+	}
+
+	var records data.Parameters
+
+	for _, param := range params {
+		record, err := objectArg(call, param)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(record) == 0 {
+			return nil, call.Errorf(param, "expected a record with atleast one field")
+		}
+
+		records = append(records, record)
+	}
+
+	return records, nil
+}
+
+// objectArg returns the giving object argument as a data.Parameter, a string
+// argument is decoded as JSON.
+func objectArg(call *parser.Call, arg parser.Node) (data.Parameter, error) {
+	switch obj := arg.(type) {
+	case *parser.ObjectLit:
+		return data.Parameter(obj.Interface().(map[string]interface{})), nil
+
+	case *parser.StringLit:
+		pm := make(data.Parameter)
+
+		if err := json.Unmarshal([]byte(obj.Value), &pm); err != nil {
+			return nil, call.Errorf(arg, err.Error())
+		}
+
+		return pm, nil
+	}
+
+	return nil, call.Errorf(arg, "expected object argument")
+}
+
 //==============================================================================
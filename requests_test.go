@@ -0,0 +1,506 @@
+package coquery_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery"
+	"github.com/influx6/coquery/data"
+	"github.com/influx6/coquery/parser"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+// generate parses the giving query and returns the requests generated by a
+// coquery.BasicQueries for its method calls.
+func generate(t *testing.T, query string) (coquery.RecordRequests, coquery.ResponseError) {
+	q, err := parser.Parse(query)
+	if err != nil {
+		t.Fatalf("\t%s\tShould have parsed query %q: %s", tests.Failed, query, err)
+	}
+
+	bq := coquery.BasicQueries{EventLog: events, Store: storage.New("id")}
+	return bq.Generate(context, "43D3UFZ6", "users", q.Calls)
+}
+
+// TestTypedFindValues validates that find requests carry the typed value
+// supplied within the query.
+func TestTypedFindValues(t *testing.T) {
+	t.Logf("Given the need to generate find requests with typed values")
+	{
+		cases := []struct {
+			query string
+			value interface{}
+		}{
+			{"docs.users.find(name,'alex')", "alex"},
+			{"docs.users.find(name,alex)", "alex"},
+			{"docs.users.find(zip,\"02134\")", "02134"},
+			{"docs.users.find(id,4023)", 4023},
+			{"docs.users.find(rate,0.5)", 0.5},
+			{"docs.users.find(active,true)", true},
+			{"docs.users.find(deleted_at,null)", nil},
+			{"docs.users.find(_id,oid(\"5707A1D1E4B0E5A0C8F2B1A3\"))", data.ObjectID("5707a1d1e4b0e5a0c8f2b1a3")},
+		}
+
+		for _, tc := range cases {
+			t.Logf("\tWhen giving the query %q", tc.query)
+			{
+				reqs, err := generate(t, tc.query)
+				if err != nil {
+					t.Fatalf("\t%s\tShould have generated requests: %s", tests.Failed, err)
+				}
+
+				find, ok := reqs[0].(*coquery.Find)
+				if !ok {
+					t.Fatalf("\t%s\tShould have generated a coquery.Find request: %T", tests.Failed, reqs[0])
+				}
+
+				if find.Value != tc.value {
+					t.Fatalf("\t%s\tShould have value %#v: %#v", tests.Failed, tc.value, find.Value)
+				}
+				t.Logf("\t%s\tShould have value %#v", tests.Success, tc.value)
+			}
+		}
+	}
+}
+
+//==============================================================================
+
+// TestWherePredicates validates the predicate trees generated for where
+// requests.
+func TestWherePredicates(t *testing.T) {
+	t.Logf("Given the need to generate where requests from filter expressions")
+	{
+		cases := []struct {
+			query string
+			pred  string
+		}{
+			{"docs.users.where(age > 21)", `gt(age,21)`},
+			{"docs.users.where(21 < age)", `gt(age,21)`},
+			{"docs.users.where(active)", `eq(active,true)`},
+			{"docs.users.where(a == 1 && b != 'x' && c <= 2.5)", `and[eq(a,1) ne(b,"x") lte(c,2.5)]`},
+			{"docs.users.where(a in [1,2] || !(b not in ['x']))", `or[in(a,[]interface {}{1, 2}) not[nin(b,[]interface {}{"x"})]]`},
+		}
+
+		for _, tc := range cases {
+			t.Logf("\tWhen giving the query %q", tc.query)
+			{
+				reqs, err := generate(t, tc.query)
+				if err != nil {
+					t.Fatalf("\t%s\tShould have generated requests: %s", tests.Failed, err)
+				}
+
+				where, ok := reqs[0].(*coquery.Where)
+				if !ok {
+					t.Fatalf("\t%s\tShould have generated a coquery.Where request: %T", tests.Failed, reqs[0])
+				}
+
+				if where.Predicate.String() != tc.pred {
+					t.Fatalf("\t%s\tShould have predicate %s: %s", tests.Failed, tc.pred, where.Predicate)
+				}
+				t.Logf("\t%s\tShould have predicate %s", tests.Success, tc.pred)
+			}
+		}
+
+		for _, query := range []string{"docs.users.where(1 == 2)", "docs.users.where(age in 3)", "docs.users.where(age > 1, b)", "docs.users.where(3)"} {
+			t.Logf("\tWhen giving the invalid query %q", query)
+			{
+				if _, err := generate(t, query); err == nil {
+					t.Fatalf("\t%s\tShould have failed to generate requests", tests.Failed)
+				}
+				t.Logf("\t%s\tShould have failed to generate requests", tests.Success)
+			}
+		}
+	}
+}
+
+//==============================================================================
+
+// TestSortFields validates the fields generated for sort requests.
+func TestSortFields(t *testing.T) {
+	t.Logf("Given the need to generate sort requests")
+	{
+		query := "docs.users.sort(-age,name,'-address.zip')"
+		t.Logf("\tWhen giving the query %q", query)
+		{
+			reqs, err := generate(t, query)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have generated requests: %s", tests.Failed, err)
+			}
+
+			sort, ok := reqs[0].(*coquery.Sort)
+			if !ok {
+				t.Fatalf("\t%s\tShould have generated a coquery.Sort request: %T", tests.Failed, reqs[0])
+			}
+
+			expected := []coquery.SortField{{Key: "age", Desc: true}, {Key: "name"}, {Key: "address.zip", Desc: true}}
+			if !reflect.DeepEqual(sort.Fields, expected) {
+				t.Fatalf("\t%s\tShould have sort fields %+v: %+v", tests.Failed, expected, sort.Fields)
+			}
+			t.Logf("\t%s\tShould have sort fields %+v", tests.Success, expected)
+		}
+
+		for _, query := range []string{"docs.users.sort()", "docs.users.sort(-3)", "docs.users.sort(age > 3)"} {
+			t.Logf("\tWhen giving the invalid query %q", query)
+			{
+				if _, err := generate(t, query); err == nil {
+					t.Fatalf("\t%s\tShould have failed to generate requests", tests.Failed)
+				}
+				t.Logf("\t%s\tShould have failed to generate requests", tests.Success)
+			}
+		}
+	}
+}
+
+//==============================================================================
+
+// TestPageRequests validates the generation of page requests and the round
+// trip of their cursors.
+func TestPageRequests(t *testing.T) {
+	t.Logf("Given the need to generate page requests")
+	{
+		query := "docs.users.find(active,true).sort(-age).page(20)"
+		t.Logf("\tWhen giving the query %q", query)
+		{
+			reqs, err := generate(t, query)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have generated requests: %s", tests.Failed, err)
+			}
+
+			if len(reqs) != 2 {
+				t.Fatalf("\t%s\tShould have folded the sort into the page request: %d", tests.Failed, len(reqs))
+			}
+			t.Logf("\t%s\tShould have folded the sort into the page request", tests.Success)
+
+			page, ok := reqs[1].(*coquery.Page)
+			if !ok {
+				t.Fatalf("\t%s\tShould have generated a coquery.Page request: %T", tests.Failed, reqs[1])
+			}
+
+			expected := []coquery.SortField{{Key: "age", Desc: true}, {Key: "id"}}
+			if page.Size != 20 || !reflect.DeepEqual(page.Sort, expected) {
+				t.Fatalf("\t%s\tShould have size 20 and sort fields %+v: %d %+v", tests.Failed, expected, page.Size, page.Sort)
+			}
+			t.Logf("\t%s\tShould have size 20 and sort fields %+v", tests.Success, expected)
+
+			res, rerr := page.Next(data.Parameters{{"id": 4, "age": 30, "_id": data.ObjectID("5707a1d1e4b0e5a0c8f2b1a3")}}, true)
+			if rerr != nil || res.NextCursor == "" {
+				t.Fatalf("\t%s\tShould have created a next cursor: %s", tests.Failed, rerr)
+			}
+			t.Logf("\t%s\tShould have created a next cursor", tests.Success)
+
+			next := "docs.users.sort(-age).page(20,'" + res.NextCursor + "')"
+
+			reqs, err = generate(t, next)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have generated requests for the next page: %s", tests.Failed, err)
+			}
+
+			after := reqs[0].(*coquery.Page).After
+			if after == nil || !reflect.DeepEqual(after.Values, []interface{}{int64(30), int64(4)}) {
+				t.Fatalf("\t%s\tShould have decoded the cursor values: %+v", tests.Failed, after)
+			}
+			t.Logf("\t%s\tShould have decoded the cursor values", tests.Success)
+
+			if after.After().String() != `or[lt(age,30) and[eq(age,30) gt(id,4)]]` {
+				t.Fatalf("\t%s\tShould have built the seek predicate: %s", tests.Failed, after.After())
+			}
+			t.Logf("\t%s\tShould have built the seek predicate", tests.Success)
+
+			if _, err := generate(t, "docs.users.sort(name).page(20,'"+res.NextCursor+"')"); err == nil {
+				t.Fatalf("\t%s\tShould have rejected a cursor for a different sort order", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have rejected a cursor for a different sort order", tests.Success)
+		}
+
+		query = "docs.users.where(age > 21).sort(-age).page(20)"
+		t.Logf("\tWhen giving the query %q", query)
+		{
+			reqs, err := generate(t, query)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have generated requests: %s", tests.Failed, err)
+			}
+
+			if len(reqs) != 1 {
+				t.Fatalf("\t%s\tShould have folded the where and sort into the page request: %d", tests.Failed, len(reqs))
+			}
+			t.Logf("\t%s\tShould have folded the where and sort into the page request", tests.Success)
+
+			page := reqs[0].(*coquery.Page)
+			page.After = &coquery.Cursor{Sort: page.Sort, Values: []interface{}{30, 4}}
+
+			if pred := page.Predicate().String(); pred != `and[gt(age,21) or[lt(age,30) and[eq(age,30) gt(id,4)]]]` {
+				t.Fatalf("\t%s\tShould have matched the where and the cursor: %s", tests.Failed, pred)
+			}
+			t.Logf("\t%s\tShould have matched the where and the cursor", tests.Success)
+		}
+
+		for _, query := range []string{"docs.users.page()", "docs.users.page(0)", "docs.users.page(10,'%%%')", "docs.users.page(10,4)"} {
+			t.Logf("\tWhen giving the invalid query %q", query)
+			{
+				if _, err := generate(t, query); err == nil {
+					t.Fatalf("\t%s\tShould have failed to generate requests", tests.Failed)
+				}
+				t.Logf("\t%s\tShould have failed to generate requests", tests.Success)
+			}
+		}
+	}
+}
+
+//==============================================================================
+
+// TestAggregateRequests validates the generation of aggregate requests.
+func TestAggregateRequests(t *testing.T) {
+	t.Logf("Given the need to generate aggregate requests")
+	{
+		cases := []struct {
+			query string
+			agg   coquery.Aggregate
+		}{
+			{"docs.users.count()", coquery.Aggregate{Op: "count"}},
+			{"docs.users.sum(balance)", coquery.Aggregate{Op: "sum", Field: "balance"}},
+			{"docs.users.groupBy(address.state).max(age)", coquery.Aggregate{Op: "max", Field: "age", Group: "address.state"}},
+			{"docs.users.where(age > 21).groupBy(state).count()", coquery.Aggregate{Op: "count", Group: "state", Match: &coquery.Predicate{Op: "gt", Field: "age", Value: 21}}},
+		}
+
+		for _, tc := range cases {
+			t.Logf("\tWhen giving the query %q", tc.query)
+			{
+				reqs, err := generate(t, tc.query)
+				if err != nil {
+					t.Fatalf("\t%s\tShould have generated requests: %s", tests.Failed, err)
+				}
+
+				if len(reqs) != 1 {
+					t.Fatalf("\t%s\tShould have generated a single request: %d", tests.Failed, len(reqs))
+				}
+
+				agg, ok := reqs[0].(*coquery.Aggregate)
+				if !ok {
+					t.Fatalf("\t%s\tShould have generated a coquery.Aggregate request: %T", tests.Failed, reqs[0])
+				}
+
+				tc.agg.Doc, tc.agg.RID = agg.Doc, agg.RID
+				if !reflect.DeepEqual(*agg, tc.agg) {
+					t.Fatalf("\t%s\tShould have generated %+v: %+v", tests.Failed, tc.agg, *agg)
+				}
+				t.Logf("\t%s\tShould have generated %+v", tests.Success, tc.agg)
+			}
+		}
+
+		for _, query := range []string{"docs.users.count(age)", "docs.users.sum()", "docs.users.groupBy(state)", "docs.users.groupBy(state).findN(2)"} {
+			t.Logf("\tWhen giving the invalid query %q", query)
+			{
+				if _, err := generate(t, query); err == nil {
+					t.Fatalf("\t%s\tShould have failed to generate requests", tests.Failed)
+				}
+				t.Logf("\t%s\tShould have failed to generate requests", tests.Success)
+			}
+		}
+	}
+}
+
+//==============================================================================
+
+// TestJoinRequests validates the generation of join and expand requests.
+func TestJoinRequests(t *testing.T) {
+	t.Logf("Given the need to generate join and expand requests")
+	{
+		cases := []struct {
+			query string
+			join  coquery.Join
+		}{
+			{"docs.users.find(id,3).join(books,uid)", coquery.Join{Path: "books", Field: "uid", Local: "id", As: "books"}},
+			{"docs.users.find(id,3).join(docs.books,uid,book_id)", coquery.Join{Path: "docs.books", Field: "uid", Local: "book_id", As: "books"}},
+			{"docs.books.find(id,3).expand(author -> docs.users)", coquery.Join{Path: "docs.users", Field: "id", Local: "author", As: "author", Expand: true}},
+			{"docs.books.find(id,3).expand(author->docs.users._id)", coquery.Join{Path: "docs.users", Field: "_id", Local: "author", As: "author", Expand: true}},
+		}
+
+		for _, tc := range cases {
+			t.Logf("\tWhen giving the query %q", tc.query)
+			{
+				reqs, err := generate(t, tc.query)
+				if err != nil {
+					t.Fatalf("\t%s\tShould have generated requests: %s", tests.Failed, err)
+				}
+
+				join, ok := reqs[1].(*coquery.Join)
+				if !ok {
+					t.Fatalf("\t%s\tShould have generated a coquery.Join request: %T", tests.Failed, reqs[1])
+				}
+
+				tc.join.Doc, tc.join.RID = join.Doc, join.RID
+				if !reflect.DeepEqual(*join, tc.join) {
+					t.Fatalf("\t%s\tShould have generated %+v: %+v", tests.Failed, tc.join, *join)
+				}
+				t.Logf("\t%s\tShould have generated %+v", tests.Success, tc.join)
+			}
+		}
+
+		for _, query := range []string{"docs.users.join(books)", "docs.users.expand(author)", "docs.users.expand(author -> 3)", "docs.users.expand(a -> b.c.d.e)", "docs.users.where(a -> b)"} {
+			t.Logf("\tWhen giving the invalid query %q", query)
+			{
+				if _, err := generate(t, query); err == nil {
+					t.Fatalf("\t%s\tShould have failed to generate requests", tests.Failed)
+				}
+				t.Logf("\t%s\tShould have failed to generate requests", tests.Success)
+			}
+		}
+	}
+}
+
+//==============================================================================
+
+// TestRemoveRequests validates the generation of remove requests.
+func TestRemoveRequests(t *testing.T) {
+	t.Logf("Given the need to generate remove requests")
+	{
+		query := "docs.users.find(id,3).remove()"
+		t.Logf("\tWhen giving the query %q", query)
+		{
+			reqs, err := generate(t, query)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have generated requests: %s", tests.Failed, err)
+			}
+
+			if _, ok := reqs[1].(*coquery.Remove); !ok {
+				t.Fatalf("\t%s\tShould have generated a coquery.Remove request: %T", tests.Failed, reqs[1])
+			}
+			t.Logf("\t%s\tShould have generated a coquery.Remove request", tests.Success)
+		}
+
+		for _, query := range []string{"docs.users.remove()", "docs.users.find(id,3).remove(id)"} {
+			t.Logf("\tWhen giving the invalid query %q", query)
+			{
+				if _, err := generate(t, query); err == nil {
+					t.Fatalf("\t%s\tShould have failed to generate requests", tests.Failed)
+				}
+				t.Logf("\t%s\tShould have failed to generate requests", tests.Success)
+			}
+		}
+	}
+}
+
+//==============================================================================
+
+// TestCreateRequests validates the generation of create requests.
+func TestCreateRequests(t *testing.T) {
+	t.Logf("Given the need to generate create requests")
+	{
+		cases := []struct {
+			query   string
+			records data.Parameters
+		}{
+			{"docs.users.create({name:'alex',age:20})", data.Parameters{{"name": "alex", "age": 20}}},
+			{"docs.users.create({name:'alex'},{name:'ruth'})", data.Parameters{{"name": "alex"}, {"name": "ruth"}}},
+			{"docs.users.create([{name:'alex'},{name:'ruth'}]).collects(name)", data.Parameters{{"name": "alex"}, {"name": "ruth"}}},
+		}
+
+		for _, tc := range cases {
+			t.Logf("\tWhen giving the query %q", tc.query)
+			{
+				reqs, err := generate(t, tc.query)
+				if err != nil {
+					t.Fatalf("\t%s\tShould have generated requests: %s", tests.Failed, err)
+				}
+
+				create, ok := reqs[0].(*coquery.Create)
+				if !ok {
+					t.Fatalf("\t%s\tShould have generated a coquery.Create request: %T", tests.Failed, reqs[0])
+				}
+
+				if !reflect.DeepEqual(create.Records, tc.records) {
+					t.Fatalf("\t%s\tShould have records %v: %v", tests.Failed, tc.records, create.Records)
+				}
+				t.Logf("\t%s\tShould have records %v", tests.Success, tc.records)
+			}
+		}
+
+		for _, query := range []string{"docs.users.create()", "docs.users.create({})", "docs.users.create([])", "docs.users.create(4)", "docs.users.find(id,3).create({name:'alex'})"} {
+			t.Logf("\tWhen giving the invalid query %q", query)
+			{
+				if _, err := generate(t, query); err == nil {
+					t.Fatalf("\t%s\tShould have failed to generate requests", tests.Failed)
+				}
+				t.Logf("\t%s\tShould have failed to generate requests", tests.Success)
+			}
+		}
+	}
+}
+
+// TestMutateUpdates validates the update operators of mutate requests.
+func TestMutateUpdates(t *testing.T) {
+	t.Logf("Given the need to generate mutate requests with update operators")
+	{
+		query := "docs.users.find(id,3).mutate({$inc:{views:1}, $push:{tags:'x'}, $unset:['tmp']})"
+
+		t.Logf("\tWhen giving the query %q", query)
+		{
+			reqs, err := generate(t, query)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have generated requests: %s", tests.Failed, err)
+			}
+
+			mux, ok := reqs[1].(*coquery.Mutate)
+			if !ok || mux.Update == nil {
+				t.Fatalf("\t%s\tShould have generated a coquery.Mutate request with an update: %#v", tests.Failed, reqs[1])
+			}
+			t.Logf("\t%s\tShould have generated a coquery.Mutate request with an update", tests.Success)
+
+			want := storage.Update{
+				Inc:   map[string]interface{}{"views": 1},
+				Push:  map[string]interface{}{"tags": "x"},
+				Unset: []string{"tmp"},
+			}
+
+			if !reflect.DeepEqual(*mux.Update, want) {
+				t.Fatalf("\t%s\tShould have update %#v: %#v", tests.Failed, want, *mux.Update)
+			}
+			t.Logf("\t%s\tShould have update %#v", tests.Success, want)
+		}
+
+		for _, query := range []string{"docs.users.mutate({$inc:{views:'a'}})", "docs.users.mutate({$rename:{a:'b'}})", "docs.users.mutate({name:'alex', $inc:{views:1}})"} {
+			t.Logf("\tWhen giving the invalid query %q", query)
+			{
+				if _, err := generate(t, query); err == nil {
+					t.Fatalf("\t%s\tShould have failed to generate requests", tests.Failed)
+				}
+				t.Logf("\t%s\tShould have failed to generate requests", tests.Success)
+			}
+		}
+	}
+}
+
+// TestMutateVersions validates the expected versions of mutate requests.
+func TestMutateVersions(t *testing.T) {
+	t.Logf("Given the need to generate mutate requests for expected record versions")
+	{
+		query := "docs.users.find(id,3).mutate({name:'alex'}, ifVersion=7)"
+
+		t.Logf("\tWhen giving the query %q", query)
+		{
+			reqs, err := generate(t, query)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have generated requests: %s", tests.Failed, err)
+			}
+
+			mux, ok := reqs[1].(*coquery.Mutate)
+			if !ok || mux.IfVersion == nil || *mux.IfVersion != 7 {
+				t.Fatalf("\t%s\tShould have generated a coquery.Mutate request expecting version 7: %#v", tests.Failed, reqs[1])
+			}
+			t.Logf("\t%s\tShould have generated a coquery.Mutate request expecting version 7", tests.Success)
+		}
+
+		for _, query := range []string{"docs.users.mutate({id:3}, ifVersion=7)", "docs.users.find(id,3).mutate({name:'alex'}, ifVersion='7')", "docs.users.find(id,3).mutate({name:'alex'}, version=7)", "docs.users.find(id,3, ifVersion=7)"} {
+			t.Logf("\tWhen giving the invalid query %q", query)
+			{
+				if _, err := generate(t, query); err == nil {
+					t.Fatalf("\t%s\tShould have failed to generate requests", tests.Failed)
+				}
+				t.Logf("\t%s\tShould have failed to generate requests", tests.Success)
+			}
+		}
+	}
+}
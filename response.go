@@ -10,9 +10,14 @@ type Identity interface {
 //==============================================================================
 
 // Response provides a response struct for replies to coquery requests.
+// NextCursor is set by paged requests when more records can be retrieved,
+// while Grant is set to the field rules the records were served under by the
+// Authorizer of their route, if any.
 type Response struct {
-	Req  RecordRequest   `json:"-" bson:"-"`
-	Data data.Parameters `json:"reply" bson:"reply"`
+	Req        RecordRequest   `json:"-" bson:"-"`
+	Data       data.Parameters `json:"reply" bson:"reply"`
+	NextCursor string          `json:"next_cursor,omitempty" bson:"next_cursor,omitempty"`
+	Grant      *Grant          `json:"-" bson:"-"`
 }
 
 // RequestID returns the request id for this response.
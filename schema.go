@@ -0,0 +1,103 @@
+package coquery
+
+import (
+	"fmt"
+
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+// SchemaDoc defines a interface for Doc providers which declare the schema of
+// their records, it is registered along with them by DocumentWith.
+type SchemaDoc interface {
+	Doc
+	Schema() *storage.Schema
+}
+
+// SchemaValidator defines a interface for requests which change records,
+// validating their changes against the schema of their document before the
+// document serves them.
+type SchemaValidator interface {
+	Validate(schema *storage.Schema) storage.FieldErrors
+}
+
+//==============================================================================
+
+// ValidationError is returned when the records given to a create or mutate
+// request fail the schema of their document, Fields holds the failures of the
+// individual fields.
+type ValidationError struct {
+	Rid    string              `json:"rid" bson:"rid"`
+	Msg    string              `json:"message" bson:"message"`
+	Fields storage.FieldErrors `json:"fields" bson:"fields"`
+}
+
+// Message returns the internal message for this error
+func (r *ValidationError) Message() string {
+	return r.Msg
+}
+
+// RequestID returns the response error requestID
+func (r *ValidationError) RequestID() string {
+	return r.Rid
+}
+
+// Error returns the error message for this response error.
+func (r *ValidationError) Error() string {
+	return fmt.Sprintf("%s : %s : %s", r.Rid, r.Msg, r.Fields.Error())
+}
+
+//==============================================================================
+
+// Validate validates the records being created, setting the defaults of their
+// missing fields. With several records, the field paths are prefixed by the
+// index of their record eg 1.name.
+func (f *Create) Validate(schema *storage.Schema) storage.FieldErrors {
+	var errs storage.FieldErrors
+
+	for index, rec := range f.Records {
+		for _, fe := range schema.Validate(rec) {
+			if len(f.Records) > 1 {
+				fe.Field = fmt.Sprintf("%d.%s", index, fe.Field)
+			}
+
+			errs = append(errs, fe)
+		}
+	}
+
+	return errs
+}
+
+// Validate validates the fields or update operations of the mutation.
+func (f *Mutate) Validate(schema *storage.Schema) storage.FieldErrors {
+	if f.Update != nil {
+		return schema.ValidateUpdate(f.Update)
+	}
+
+	return schema.ValidatePartial(f.Parameter)
+}
+
+// validateRequests validates the requests changing records against the giving
+// schema, returning a *ValidationError for the failures.
+func validateRequests(rid string, schema *storage.Schema, reqs RecordRequests) ResponseError {
+	var errs storage.FieldErrors
+
+	for _, req := range reqs {
+		if sv, ok := req.(SchemaValidator); ok {
+			errs = append(errs, sv.Validate(schema)...)
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+
+	return &ValidationError{
+		Rid:    rid,
+		Msg:    "Invalid Records",
+		Fields: errs,
+	}
+}
+
+//==============================================================================
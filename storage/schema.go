@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//==============================================================================
+
+// contains the field types supported by Field, a field without a type accepts
+// any value.
+const (
+	TypeAny     = ""
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeInteger = "integer"
+	TypeBool    = "bool"
+	TypeObject  = "object"
+	TypeArray   = "array"
+)
+
+// Field defines a record field of a Schema. The Default is set on records
+// lacking the field, Enum when set lists the only values allowed and Fields
+// describes the fields of a object field.
+type Field struct {
+	Name     string        `json:"name"`
+	Type     string        `json:"type,omitempty"`
+	Required bool          `json:"required,omitempty"`
+	Default  interface{}   `json:"default,omitempty"`
+	Enum     []interface{} `json:"enum,omitempty"`
+	Fields   []Field       `json:"fields,omitempty"`
+}
+
+// Schema defines the fields of the records of a document. A Strict schema
+// rejects fields it does not declare, except the record version.
+type Schema struct {
+	Fields []Field `json:"fields"`
+	Strict bool    `json:"strict,omitempty"`
+}
+
+// FieldError defines the validation failure of a record field, where Field is
+// the dotted path of the field eg address.city.
+type FieldError struct {
+	Field string `json:"field"`
+	Msg   string `json:"message"`
+}
+
+// Error returns the error message for the field.
+func (f FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", f.Field, f.Msg)
+}
+
+// FieldErrors defines the list of field validation failures of a record.
+type FieldErrors []FieldError
+
+// Error returns the error messages of the fields.
+func (f FieldErrors) Error() string {
+	var msgs []string
+
+	for _, fe := range f {
+		msgs = append(msgs, fe.Error())
+	}
+
+	return strings.Join(msgs, ", ")
+}
+
+//==============================================================================
+
+// Validate validates a complete record eg one being created, setting the
+// defaults of missing fields. Nil is returned if the record is valid.
+func (s *Schema) Validate(rec map[string]interface{}) FieldErrors {
+	var errs FieldErrors
+	s.validate(s.Fields, rec, "", false, &errs)
+	return errs
+}
+
+// ValidatePartial validates the fields given for a record eg by a mutation,
+// without requiring the fields missing from it. Object values given are
+// validated completely as they replace the field.
+func (s *Schema) ValidatePartial(rec map[string]interface{}) FieldErrors {
+	var errs FieldErrors
+	s.validate(s.Fields, rec, "", true, &errs)
+	return errs
+}
+
+// ValidateUpdate validates the operations of an Update against the fields
+// they change, rejecting the unset of required fields and the increment or
+// push of fields of other types.
+func (s *Schema) ValidateUpdate(upd *Update) FieldErrors {
+	var errs FieldErrors
+
+	for _, path := range sortedKeys(upd.Set) {
+		field, ok := s.field(path, &errs)
+		if ok && field != nil {
+			s.check(*field, path, upd.Set[path], &errs)
+		}
+	}
+
+	for _, path := range sortedKeys(upd.Inc) {
+		field, ok := s.field(path, &errs)
+		if !ok || field == nil {
+			continue
+		}
+
+		switch field.Type {
+		case TypeAny, TypeNumber:
+		case TypeInteger:
+			if !isWhole(upd.Inc[path]) {
+				errs = append(errs, FieldError{Field: path, Msg: "expected an integer increment"})
+			}
+		default:
+			errs = append(errs, FieldError{Field: path, Msg: fmt.Sprintf("can not increment a %s", field.Type)})
+		}
+	}
+
+	for _, ops := range []map[string]interface{}{upd.Push, upd.Pull} {
+		for _, path := range sortedKeys(ops) {
+			field, ok := s.field(path, &errs)
+			if ok && field != nil && field.Type != TypeAny && field.Type != TypeArray {
+				errs = append(errs, FieldError{Field: path, Msg: fmt.Sprintf("expected an array, found %s", field.Type)})
+			}
+		}
+	}
+
+	for _, path := range upd.Unset {
+		field, ok := s.field(path, &errs)
+		if ok && field != nil && field.Required {
+			errs = append(errs, FieldError{Field: path, Msg: "is required"})
+		}
+	}
+
+	return errs
+}
+
+//==============================================================================
+
+// validate validates the record against the giving fields, the path being the
+// dotted path of the record within the top level record.
+func (s *Schema) validate(fields []Field, rec map[string]interface{}, path string, partial bool, errs *FieldErrors) {
+	declared := make(map[string]bool)
+
+	for _, field := range fields {
+		declared[field.Name] = true
+		fpath := path + field.Name
+
+		value, ok := rec[field.Name]
+		if !ok && field.Default != nil && !partial {
+			rec[field.Name] = field.Default
+			continue
+		}
+
+		if !ok {
+			if field.Required && !partial {
+				*errs = append(*errs, FieldError{Field: fpath, Msg: "is required"})
+			}
+
+			continue
+		}
+
+		s.check(field, fpath, value, errs)
+	}
+
+	if !s.Strict {
+		return
+	}
+
+	for _, name := range sortedKeys(rec) {
+		if !declared[name] && !(path == "" && name == VersionKey) {
+			*errs = append(*errs, FieldError{Field: path + name, Msg: "is not a field of the schema"})
+		}
+	}
+}
+
+// check validates the value of the giving field.
+func (s *Schema) check(field Field, path string, value interface{}, errs *FieldErrors) {
+	if value == nil {
+		if field.Required {
+			*errs = append(*errs, FieldError{Field: path, Msg: "is required"})
+		}
+
+		return
+	}
+
+	if !isType(field.Type, value) {
+		*errs = append(*errs, FieldError{Field: path, Msg: fmt.Sprintf("expected %s, found %T", field.Type, value)})
+		return
+	}
+
+	if len(field.Enum) > 0 {
+		var found bool
+
+		for _, item := range field.Enum {
+			if sameValue(item, value) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			*errs = append(*errs, FieldError{Field: path, Msg: fmt.Sprintf("expected one of %v", field.Enum)})
+			return
+		}
+	}
+
+	if len(field.Fields) > 0 {
+		if obj, ok := objectOf(value); ok {
+			s.validate(field.Fields, obj, path+".", false, errs)
+		}
+	}
+}
+
+// field returns the field at the dotted path, which is nil for a path outside
+// the schema. False is returned and the error added if a strict schema does
+// not declare the path.
+func (s *Schema) field(path string, errs *FieldErrors) (*Field, bool) {
+	fields := s.Fields
+
+	var found *Field
+
+	for _, name := range strings.Split(path, ".") {
+		found = nil
+
+		for index := range fields {
+			if fields[index].Name == name {
+				found = &fields[index]
+				break
+			}
+		}
+
+		if found == nil {
+			break
+		}
+
+		fields = found.Fields
+	}
+
+	if found == nil && s.Strict && path != VersionKey {
+		*errs = append(*errs, FieldError{Field: path, Msg: "is not a field of the schema"})
+		return nil, false
+	}
+
+	return found, true
+}
+
+//==============================================================================
+
+// isType returns true/false if the value is of the giving field type.
+func isType(kind string, value interface{}) bool {
+	switch kind {
+	case TypeAny:
+		return true
+	case TypeString:
+		_, ok := value.(string)
+		return ok
+	case TypeBool:
+		_, ok := value.(bool)
+		return ok
+	case TypeNumber:
+		_, ok := number(value)
+		return ok
+	case TypeInteger:
+		return isWhole(value)
+	case TypeObject:
+		_, ok := objectOf(value)
+		return ok
+	case TypeArray:
+		rv := reflect.ValueOf(value)
+		return rv.Kind() == reflect.Slice || rv.Kind() == reflect.Array
+	}
+
+	return false
+}
+
+// isWhole returns true/false if the value is a number without a fraction.
+func isWhole(value interface{}) bool {
+	num, ok := number(value)
+	return ok && num == math.Trunc(num)
+}
+
+// objectOf returns the map of a object value, including map types eg
+// data.Parameter.
+func objectOf(value interface{}) (map[string]interface{}, bool) {
+	if obj, ok := value.(map[string]interface{}); ok {
+		return obj, true
+	}
+
+	rv := reflect.ValueOf(value)
+	if rv.Kind() != reflect.Map || rv.Type().Key().Kind() != reflect.String {
+		return nil, false
+	}
+
+	mtype := reflect.TypeOf(map[string]interface{}(nil))
+	if !rv.Type().ConvertibleTo(mtype) {
+		return nil, false
+	}
+
+	return rv.Convert(mtype).Interface().(map[string]interface{}), true
+}
+
+// sortedKeys returns the keys of the map in order, keeping errors stable.
+func sortedKeys(m map[string]interface{}) []string {
+	var keys []string
+
+	for key := range m {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	return keys
+}
+
+//==============================================================================
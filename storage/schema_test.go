@@ -0,0 +1,108 @@
+package storage_test
+
+import (
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+// TestSchema validates the validation of records against a schema.
+func TestSchema(t *testing.T) {
+	t.Logf("Given the need to validate records against a schema")
+	{
+		schema := storage.Schema{
+			Strict: true,
+			Fields: []storage.Field{
+				{Name: "id", Type: storage.TypeInteger},
+				{Name: "name", Type: storage.TypeString, Required: true},
+				{Name: "age", Type: storage.TypeInteger},
+				{Name: "role", Type: storage.TypeString, Default: "user", Enum: []interface{}{"user", "admin"}},
+				{Name: "tags", Type: storage.TypeArray},
+				{Name: "address", Type: storage.TypeObject, Fields: []storage.Field{
+					{Name: "city", Type: storage.TypeString, Required: true},
+				}},
+			},
+		}
+
+		t.Logf("\tWhen giving a valid record")
+		{
+			rec := map[string]interface{}{"id": 1, "name": "alex", "age": float64(20), "address": map[string]interface{}{"city": "Lagos"}}
+
+			if errs := schema.Validate(rec); errs != nil {
+				t.Fatalf("\t%s\tShould have validated the record: %s", tests.Failed, errs)
+			}
+			t.Logf("\t%s\tShould have validated the record", tests.Success)
+
+			if rec["role"] != "user" {
+				t.Fatalf("\t%s\tShould have set the default role: %#v", tests.Failed, rec["role"])
+			}
+			t.Logf("\t%s\tShould have set the default role", tests.Success)
+		}
+
+		t.Logf("\tWhen giving a invalid record")
+		{
+			errs := schema.Validate(map[string]interface{}{
+				"age":     20.5,
+				"role":    "root",
+				"address": map[string]interface{}{},
+				"email":   "alex@mail.com",
+			})
+
+			want := []string{"name", "age", "role", "address.city", "email"}
+
+			if len(errs) != len(want) {
+				t.Fatalf("\t%s\tShould have failed the fields %v: %s", tests.Failed, want, errs)
+			}
+
+			for index, field := range want {
+				if errs[index].Field != field {
+					t.Fatalf("\t%s\tShould have failed the fields %v: %s", tests.Failed, want, errs)
+				}
+			}
+			t.Logf("\t%s\tShould have failed the fields %v: %s", tests.Success, want, errs)
+		}
+
+		t.Logf("\tWhen giving a partial record")
+		{
+			if errs := schema.ValidatePartial(map[string]interface{}{"age": 30}); errs != nil {
+				t.Fatalf("\t%s\tShould have validated the partial record: %s", tests.Failed, errs)
+			}
+			t.Logf("\t%s\tShould have validated the partial record", tests.Success)
+
+			if errs := schema.ValidatePartial(map[string]interface{}{"name": 30}); len(errs) != 1 {
+				t.Fatalf("\t%s\tShould have failed the name field: %s", tests.Failed, errs)
+			}
+			t.Logf("\t%s\tShould have failed the name field", tests.Success)
+		}
+
+		t.Logf("\tWhen giving update operators")
+		{
+			upd, _ := storage.ParseUpdate(map[string]interface{}{
+				"$set": map[string]interface{}{"address.city": "Abuja"},
+				"$inc": map[string]interface{}{"age": 1, "_version": 1},
+			})
+
+			if errs := schema.ValidateUpdate(upd); errs != nil {
+				t.Fatalf("\t%s\tShould have validated the update: %s", tests.Failed, errs)
+			}
+			t.Logf("\t%s\tShould have validated the update", tests.Success)
+
+			upd, _ = storage.ParseUpdate(map[string]interface{}{
+				"$set":   map[string]interface{}{"address.city": 3},
+				"$inc":   map[string]interface{}{"name": 1},
+				"$push":  map[string]interface{}{"age": 1},
+				"$unset": []interface{}{"name"},
+			})
+
+			if errs := schema.ValidateUpdate(upd); len(errs) != 4 {
+				t.Fatalf("\t%s\tShould have failed the update of four fields: %s", tests.Failed, errs)
+			}
+			t.Logf("\t%s\tShould have failed the update of four fields", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
@@ -9,6 +9,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/influx6/coquery/data"
 	"gopkg.in/mgo.v2/bson"
 )
 
@@ -123,12 +124,15 @@ func (r RefList) Has(k string) bool {
 type Store interface {
 	ClearTainted()
 	ClearDeleted()
+	Untaint(...string)
 
 	Key() string
 	Has(string) bool
 	HasRecord(map[string]interface{}) bool
 
 	Add(map[string]interface{}) error
+	Update(map[string]interface{}, *Update) (map[string]interface{}, error)
+	Replace(map[string]interface{}) error
 	AddRef(map[string]interface{}, string) error
 	AdjustRef(string, string) error
 	ModRef(map[string]interface{}, string) error
@@ -145,6 +149,7 @@ type Store interface {
 
 	TaintedRecords() []string
 	DeletedRecords() []string
+	Patches() map[string]data.Patch
 
 	Length() int
 	Select(int, int) []map[string]interface{}
@@ -157,6 +162,7 @@ type under struct {
 	rl         sync.RWMutex
 	records    map[string]map[string]interface{}
 	tainted    map[string]bool
+	patches    map[string]data.Patch
 	deleted    map[string]bool
 	scans      map[string]int64
 	rfl        sync.RWMutex
@@ -171,6 +177,7 @@ func New(recordKey string) Store {
 		key:        recordKey,
 		records:    make(map[string]map[string]interface{}),
 		tainted:    make(map[string]bool),
+		patches:    make(map[string]data.Patch),
 		deleted:    make(map[string]bool),
 		scans:      make(map[string]int64),
 		active:     make(map[string]int64),
@@ -188,6 +195,7 @@ func NewExpirable(recordKey string, maxAge time.Duration) Store {
 		key:        recordKey,
 		records:    make(map[string]map[string]interface{}),
 		tainted:    make(map[string]bool),
+		patches:    make(map[string]data.Patch),
 		deleted:    make(map[string]bool),
 		scans:      make(map[string]int64),
 		active:     make(map[string]int64),
@@ -223,11 +231,26 @@ func (u *under) ClearDeleted() {
 	u.deleted = make(map[string]bool)
 }
 
-// ClearTainted resets the tainted map[string]interface{} lists, emptying all.
+// ClearTainted resets the tainted map[string]interface{} lists, emptying all
+// along with their patches.
 func (u *under) ClearTainted() {
 	u.rl.Lock()
 	defer u.rl.Unlock()
 	u.tainted = make(map[string]bool)
+	u.patches = make(map[string]data.Patch)
+}
+
+// Untaint drops the taint, patches and deletion recorded for the records of
+// the giving keys, whose changes were undone before being reported.
+func (u *under) Untaint(keys ...string) {
+	u.rl.Lock()
+	defer u.rl.Unlock()
+
+	for _, key := range keys {
+		delete(u.tainted, key)
+		delete(u.patches, key)
+		delete(u.deleted, key)
+	}
 }
 
 //==============================================================================
@@ -247,6 +270,33 @@ func (u *under) TaintedRecords() []string {
 	return records
 }
 
+// Patches returns the JSON Patch operations of the tainted records, keyed by
+// record, changing them from their state before they were first tainted.
+// Tainted records stored for the first time hold a patch adding them whole.
+func (u *under) Patches() map[string]data.Patch {
+	u.rl.RLock()
+	defer u.rl.RUnlock()
+
+	patches := make(map[string]data.Patch)
+
+	for key, patch := range u.patches {
+		patches[key] = append(data.Patch{}, patch...)
+	}
+
+	return patches
+}
+
+// patch records the operations changing the record before into the record
+// after. The store's lock must be held.
+func (u *under) patch(key string, before, after map[string]interface{}) {
+	patch, ok := u.patches[key]
+	if !ok {
+		patch = data.Patch{}
+	}
+
+	u.patches[key] = append(patch, data.Diff(before, after)...)
+}
+
 // DeletedRecords returns the deleted records in this map. That is records that
 // have been removed.
 func (u *under) DeletedRecords() []string {
@@ -394,6 +444,7 @@ func (u *under) Delete(key string) error {
 
 	delete(u.records, key)
 	delete(u.tainted, key)
+	delete(u.patches, key)
 
 	// Remove this map[string]interface{} from all refs.
 	for _, ref := range u.recordRefs {
@@ -419,6 +470,7 @@ func (u *under) Remove(rec map[string]interface{}) error {
 
 	delete(u.records, key)
 	delete(u.tainted, key)
+	delete(u.patches, key)
 	u.deleted[key] = true
 	return nil
 }
@@ -523,13 +575,15 @@ func (u *under) Add(rec map[string]interface{}) error {
 	}
 
 	// If the map[string]interface{} has a previous instance, then we need to merge it.
-	MergeMaps(inrec, rec)
-
 	u.rl.Lock()
 	defer u.rl.Unlock()
 
+	before := CopyMap(inrec)
+	MergeMaps(inrec, rec)
+
 	u.records[key] = inrec
 	u.tainted[key] = true
+	u.patch(key, before, inrec)
 
 	u.afl.RLock()
 	d := u.active[key]
@@ -543,6 +597,69 @@ func (u *under) Add(rec map[string]interface{}) error {
 	return nil
 }
 
+// Update applies the update operators to the stored copy of the giving
+// record, adding the record first if it is not stored yet. Unlike Add, fields
+// can be removed and change their types. A copy of the updated record is
+// returned.
+func (u *under) Update(rec map[string]interface{}, upd *Update) (map[string]interface{}, error) {
+	if !u.ValidRecord(rec) {
+		return nil, ErrNoKeyInRecord
+	}
+
+	key := fmt.Sprintf("%+v", rec[u.key])
+
+	u.rl.Lock()
+	defer u.rl.Unlock()
+
+	var before map[string]interface{}
+
+	inrec, ok := u.records[key]
+	if ok {
+		before = CopyMap(inrec)
+	} else {
+		inrec = CopyMap(rec)
+	}
+
+	if err := upd.Apply(inrec); err != nil {
+		return nil, err
+	}
+
+	u.records[key] = inrec
+	u.tainted[key] = true
+	u.patch(key, before, inrec)
+
+	u.afl.Lock()
+	u.active[key]++
+	u.afl.Unlock()
+
+	return CopyMap(inrec), nil
+}
+
+// Replace stores the giving record in place of any stored copy, rather than
+// merging the two as Add does. The record is tainted.
+func (u *under) Replace(rec map[string]interface{}) error {
+	if !u.ValidRecord(rec) {
+		return ErrNoKeyInRecord
+	}
+
+	key := fmt.Sprintf("%+v", rec[u.key])
+
+	u.rl.Lock()
+	defer u.rl.Unlock()
+
+	u.patch(key, u.records[key], rec)
+
+	u.records[key] = CopyMap(rec)
+	u.tainted[key] = true
+	delete(u.deleted, key)
+
+	u.afl.Lock()
+	u.active[key]++
+	u.afl.Unlock()
+
+	return nil
+}
+
 // ErrInvalidRefKey is returned when the reference key is not found in the
 // provided map[string]interface{}.
 var ErrInvalidRefKey = errors.New("Invalid Reference Key")
@@ -1,11 +1,14 @@
 package storage_test
 
 import (
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"testing"
 	"time"
 
 	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery/data"
 	"github.com/influx6/coquery/storage"
 )
 
@@ -226,3 +229,98 @@ func TestStorage(t *testing.T) {
 }
 
 //==============================================================================
+
+// TestStoragePatches validates the patches recorded for tainted records.
+func TestStoragePatches(t *testing.T) {
+	t.Logf("Given the need to record the changes of tainted records as patches")
+	{
+		so := storage.New("store_id")
+
+		original := map[string]interface{}{
+			"store_id": "1",
+			"name":     "alex",
+			"tags":     []interface{}{"a"},
+			"address":  map[string]interface{}{"city": "lagos", "zip": "100"},
+		}
+
+		so.Add(storage.CopyMap(original))
+
+		t.Logf("\tWhen updating a stored record")
+		{
+			_, err := so.Update(map[string]interface{}{"store_id": "1"}, &storage.Update{
+				Set:   map[string]interface{}{"address.city": "abuja"},
+				Push:  map[string]interface{}{"tags": "b"},
+				Unset: []string{"address.zip"},
+			})
+			if err != nil {
+				t.Fatalf("\t%s\tShould have updated the record: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have updated the record", tests.Success)
+
+			// Send the patch over the wire as clients receive it.
+			body, err := json.Marshal(so.Patches()["1"])
+			if err != nil {
+				t.Fatalf("\t%s\tShould have marshalled the patch: %s", tests.Failed, err)
+			}
+
+			var patch data.Patch
+			if err := json.Unmarshal(body, &patch); err != nil || len(patch) != 3 {
+				t.Fatalf("\t%s\tShould have recorded 3 operations: %s", tests.Failed, body)
+			}
+			t.Logf("\t%s\tShould have recorded 3 operations: %s", tests.Success, body)
+
+			stored, _ := so.Get("1")
+			if err := patch.Apply(original); err != nil || !reflect.DeepEqual(original, stored) {
+				t.Fatalf("\t%s\tShould have patched the record into the stored record: %v : %+v", tests.Failed, err, original)
+			}
+			t.Logf("\t%s\tShould have patched the record into the stored record", tests.Success)
+		}
+
+		t.Logf("\tWhen replacing a record not stored")
+		{
+			so.Replace(map[string]interface{}{"store_id": "2", "name": "ruth"})
+
+			record := make(map[string]interface{})
+			if err := so.Patches()["2"].Apply(record); err != nil || record["name"] != "ruth" {
+				t.Fatalf("\t%s\tShould have recorded the addition of the record: %v : %+v", tests.Failed, err, record)
+			}
+			t.Logf("\t%s\tShould have recorded the addition of the record", tests.Success)
+		}
+
+		t.Logf("\tWhen untainting a record whose changes were undone")
+		{
+			so.Untaint("2")
+
+			if _, ok := so.Patches()["2"]; ok {
+				t.Fatalf("\t%s\tShould have dropped the patch of the record", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have dropped the patch of the record", tests.Success)
+
+			for _, key := range so.TaintedRecords() {
+				if key == "2" {
+					t.Fatalf("\t%s\tShould have dropped the taint of the record", tests.Failed)
+				}
+			}
+			t.Logf("\t%s\tShould have dropped the taint of the record", tests.Success)
+		}
+
+		t.Logf("\tWhen removing and clearing records")
+		{
+			so.Remove(map[string]interface{}{"store_id": "1"})
+
+			if _, ok := so.Patches()["1"]; ok {
+				t.Fatalf("\t%s\tShould have dropped the patch of the removed record", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have dropped the patch of the removed record", tests.Success)
+
+			so.ClearTainted()
+
+			if len(so.Patches()) != 0 {
+				t.Fatalf("\t%s\tShould have cleared the patches", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have cleared the patches", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
@@ -0,0 +1,319 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+//==============================================================================
+
+// contains the field update operators supported by Update.
+const (
+	OpSet   = "$set"
+	OpInc   = "$inc"
+	OpPush  = "$push"
+	OpPull  = "$pull"
+	OpUnset = "$unset"
+)
+
+// VersionKey defines the record field holding the version of a record, which
+// is incremented by every mutation of the record.
+const VersionKey = "_version"
+
+// Version returns the version of the giving record, records lacking one are
+// at version 0.
+func Version(rec map[string]interface{}) int64 {
+	version, _ := number(rec[VersionKey])
+	return int64(version)
+}
+
+// Update defines a set of atomic field operations to be applied to a record,
+// where each operation's keys are record field names which may be dotted to
+// reach into embedded maps eg {$inc:{"stats.views":1}}.
+type Update struct {
+	Set   map[string]interface{} `json:"$set,omitempty" bson:"$set,omitempty"`
+	Inc   map[string]interface{} `json:"$inc,omitempty" bson:"$inc,omitempty"`
+	Push  map[string]interface{} `json:"$push,omitempty" bson:"$push,omitempty"`
+	Pull  map[string]interface{} `json:"$pull,omitempty" bson:"$pull,omitempty"`
+	Unset []string               `json:"$unset,omitempty" bson:"$unset,omitempty"`
+}
+
+// IsUpdate returns true/false if the giving map uses any of the update
+// operators eg {$inc:{views:1}}.
+func IsUpdate(m map[string]interface{}) bool {
+	for key := range m {
+		if strings.HasPrefix(key, "$") {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ParseUpdate returns the Update for the giving map of update operators. An
+// error is returned if the map mixes plain fields with operators, uses an
+// unknown operator or $inc is given a non numeric value. $unset accepts
+// either a list of field names or a map whose keys are the field names.
+func ParseUpdate(m map[string]interface{}) (*Update, error) {
+	var upd Update
+
+	for op, value := range m {
+		if !strings.HasPrefix(op, "$") {
+			return nil, fmt.Errorf("field %q can not be mixed with update operators", op)
+		}
+
+		if op == OpUnset {
+			fields, err := unsetFields(value)
+			if err != nil {
+				return nil, err
+			}
+
+			upd.Unset = fields
+			continue
+		}
+
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("operator %q expects a map of fields", op)
+		}
+
+		switch op {
+		case OpSet:
+			upd.Set = fields
+		case OpInc:
+			for field, by := range fields {
+				if _, ok := number(by); !ok {
+					return nil, fmt.Errorf("operator %q expects a number for %q", op, field)
+				}
+			}
+
+			upd.Inc = fields
+		case OpPush:
+			upd.Push = fields
+		case OpPull:
+			upd.Pull = fields
+		default:
+			return nil, fmt.Errorf("unknown update operator %q", op)
+		}
+	}
+
+	return &upd, nil
+}
+
+// unsetFields returns the field names of a $unset operator.
+func unsetFields(value interface{}) ([]string, error) {
+	var fields []string
+
+	switch items := value.(type) {
+	case []interface{}:
+		for _, item := range items {
+			field, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("operator %q expects field names", OpUnset)
+			}
+
+			fields = append(fields, field)
+		}
+
+	case map[string]interface{}:
+		for field := range items {
+			fields = append(fields, field)
+		}
+
+	default:
+		return nil, fmt.Errorf("operator %q expects a list of field names", OpUnset)
+	}
+
+	return fields, nil
+}
+
+// Apply applies the update operations to the giving record in the order $set,
+// $inc, $push, $pull and $unset. The record is left untouched if any of the
+// operations fails eg a $inc of a string field.
+func (u *Update) Apply(rec map[string]interface{}) error {
+	cp := CopyMap(rec)
+
+	for field, value := range u.Set {
+		parent, last := fieldParent(cp, field, true)
+		if parent == nil {
+			return fmt.Errorf("field %q is not within a map", field)
+		}
+
+		parent[last] = value
+	}
+
+	for field, by := range u.Inc {
+		parent, last := fieldParent(cp, field, true)
+		if parent == nil {
+			return fmt.Errorf("field %q is not within a map", field)
+		}
+
+		cur, ok := parent[last]
+		if !ok {
+			parent[last] = by
+			continue
+		}
+
+		sum, err := increment(cur, by)
+		if err != nil {
+			return fmt.Errorf("field %q: %s", field, err)
+		}
+
+		parent[last] = sum
+	}
+
+	for field, value := range u.Push {
+		parent, last := fieldParent(cp, field, true)
+		if parent == nil {
+			return fmt.Errorf("field %q is not within a map", field)
+		}
+
+		cur, ok := parent[last]
+		if !ok || cur == nil {
+			parent[last] = []interface{}{value}
+			continue
+		}
+
+		items, ok := cur.([]interface{})
+		if !ok {
+			return fmt.Errorf("field %q is not an array", field)
+		}
+
+		parent[last] = append(items, value)
+	}
+
+	for field, value := range u.Pull {
+		parent, last := fieldParent(cp, field, false)
+		if parent == nil {
+			continue
+		}
+
+		cur, ok := parent[last]
+		if !ok || cur == nil {
+			continue
+		}
+
+		items, ok := cur.([]interface{})
+		if !ok {
+			return fmt.Errorf("field %q is not an array", field)
+		}
+
+		kept := []interface{}{}
+		for _, item := range items {
+			if !sameValue(item, value) {
+				kept = append(kept, item)
+			}
+		}
+
+		parent[last] = kept
+	}
+
+	for _, field := range u.Unset {
+		if parent, last := fieldParent(cp, field, false); parent != nil {
+			delete(parent, last)
+		}
+	}
+
+	// Everything applied cleanly, so swap the new contents into the record.
+	for key := range rec {
+		delete(rec, key)
+	}
+
+	for key, value := range cp {
+		rec[key] = value
+	}
+
+	return nil
+}
+
+//==============================================================================
+
+// fieldParent returns the map holding the last segment of a dotted field
+// along with that segment, creating the missing maps along the way if create
+// is true. A nil map is returned if the path can not be followed.
+func fieldParent(rec map[string]interface{}, field string, create bool) (map[string]interface{}, string) {
+	keys := strings.Split(field, ".")
+	parent := rec
+
+	for _, key := range keys[:len(keys)-1] {
+		next, ok := parent[key]
+		if !ok {
+			if !create {
+				return nil, ""
+			}
+
+			nm := make(map[string]interface{})
+			parent[key] = nm
+			parent = nm
+			continue
+		}
+
+		nm, ok := next.(map[string]interface{})
+		if !ok {
+			return nil, ""
+		}
+
+		parent = nm
+	}
+
+	return parent, keys[len(keys)-1]
+}
+
+// increment returns the sum of the current field value and the giving amount.
+// Integers stay integers of the current value's type, unless either side is a
+// floating point number in which case a float64 is returned.
+func increment(cur, by interface{}) (interface{}, error) {
+	cv, ok := number(cur)
+	if !ok {
+		return nil, fmt.Errorf("can not increment non numeric value %#v", cur)
+	}
+
+	bv, _ := number(by)
+
+	if isInteger(cur) && isInteger(by) {
+		sum := reflect.ValueOf(reflect.ValueOf(cur).Int() + reflect.ValueOf(by).Int())
+		return sum.Convert(reflect.TypeOf(cur)).Interface(), nil
+	}
+
+	return cv + bv, nil
+}
+
+// number returns the float64 form of numeric values.
+func number(v interface{}) (float64, bool) {
+	rv := reflect.ValueOf(v)
+
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+
+	return 0, false
+}
+
+// isInteger returns true/false if the giving value is a signed integer.
+func isInteger(v interface{}) bool {
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+
+	return false
+}
+
+// sameValue returns true/false if both values are equal, comparing numbers by
+// value regardless of their go type.
+func sameValue(a, b interface{}) bool {
+	if av, ok := number(a); ok {
+		bv, ok := number(b)
+		return ok && av == bv
+	}
+
+	return reflect.DeepEqual(a, b)
+}
+
+//==============================================================================
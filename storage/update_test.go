@@ -0,0 +1,95 @@
+package storage_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ardanlabs/kit/tests"
+	"github.com/influx6/coquery/storage"
+)
+
+//==============================================================================
+
+// TestUpdate validates the application of update operators to stored records.
+func TestUpdate(t *testing.T) {
+	t.Logf("Given the need to apply update operators to a stored record")
+	{
+		so := storage.New("store_id")
+		so.Add(map[string]interface{}{
+			"store_id": "30",
+			"views":    4,
+			"rating":   2,
+			"tags":     []interface{}{"a", "b", "a"},
+			"tmp":      true,
+			"stats":    map[string]interface{}{"likes": 1},
+		})
+
+		upd, err := storage.ParseUpdate(map[string]interface{}{
+			"$inc":   map[string]interface{}{"views": 1, "rating": 0.5, "stats.likes": 2, "stats.shares": 1},
+			"$push":  map[string]interface{}{"tags": "c", "labels": "new"},
+			"$pull":  map[string]interface{}{"tags": "a"},
+			"$unset": []interface{}{"tmp"},
+			"$set":   map[string]interface{}{"name": "alex"},
+		})
+		if err != nil {
+			t.Fatalf("\t%s\tShould have parsed the update operators: %s", tests.Failed, err)
+		}
+		t.Logf("\t%s\tShould have parsed the update operators", tests.Success)
+
+		t.Logf("\tWhen giving a valid update")
+		{
+			rec, err := so.Update(map[string]interface{}{"store_id": "30"}, upd)
+			if err != nil {
+				t.Fatalf("\t%s\tShould have updated the record: %s", tests.Failed, err)
+			}
+			t.Logf("\t%s\tShould have updated the record", tests.Success)
+
+			want := map[string]interface{}{
+				"store_id": "30",
+				"name":     "alex",
+				"views":    5,
+				"rating":   2.5,
+				"tags":     []interface{}{"b", "c"},
+				"labels":   []interface{}{"new"},
+				"stats":    map[string]interface{}{"likes": 3, "shares": 1},
+			}
+
+			if !reflect.DeepEqual(rec, want) {
+				t.Fatalf("\t%s\tShould have record %#v: %#v", tests.Failed, want, rec)
+			}
+			t.Logf("\t%s\tShould have record %#v", tests.Success, want)
+
+			stored, _ := so.Get("30")
+			if !reflect.DeepEqual(stored, want) {
+				t.Fatalf("\t%s\tShould have stored record %#v: %#v", tests.Failed, want, stored)
+			}
+			t.Logf("\t%s\tShould have stored the updated record", tests.Success)
+
+			if tainted := so.TaintedRecords(); len(tainted) != 1 || tainted[0] != "30" {
+				t.Fatalf("\t%s\tShould have tainted the record: %v", tests.Failed, tainted)
+			}
+			t.Logf("\t%s\tShould have tainted the record", tests.Success)
+		}
+
+		t.Logf("\tWhen giving an update which can not be applied")
+		{
+			bad, _ := storage.ParseUpdate(map[string]interface{}{
+				"$set": map[string]interface{}{"name": "ruth"},
+				"$inc": map[string]interface{}{"tags": 1},
+			})
+
+			if _, err := so.Update(map[string]interface{}{"store_id": "30"}, bad); err == nil {
+				t.Fatalf("\t%s\tShould have failed to increment an array", tests.Failed)
+			}
+			t.Logf("\t%s\tShould have failed to increment an array", tests.Success)
+
+			stored, _ := so.Get("30")
+			if stored["name"] != "alex" {
+				t.Fatalf("\t%s\tShould have left the record untouched: %#v", tests.Failed, stored)
+			}
+			t.Logf("\t%s\tShould have left the record untouched", tests.Success)
+		}
+	}
+}
+
+//==============================================================================
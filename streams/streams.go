@@ -1,6 +1,7 @@
 package streams
 
 import (
+	"context"
 	"errors"
 	"sync/atomic"
 	"time"
@@ -51,9 +52,23 @@ func ReadResponse(e EventLog, context interface{}, mx time.Duration, rid string,
 // completion timeout.
 var ErrRequestTimout = errors.New("Request Timed Out")
 
+// ErrRequestCancelled is returned when the context of a request is done
+// before its completion.
+var ErrRequestCancelled = errors.New("Request Cancelled")
+
+// noContext provides the context.Context of requests served without one.
+var noContext = context.Background()
+
 // ResponseStream returns a channel responds with a coquery.Response for a specific
 // requests ID (RequestID())
 func ResponseStream(e EventLog, context interface{}, maxWait time.Duration, rid string, in sumex.Streams) (<-chan *coquery.Response, <-chan coquery.ResponseError) {
+	return ResponseStreamContext(noContext, e, context, maxWait, rid, in)
+}
+
+// ResponseStreamContext returns a channel responds with a coquery.Response for
+// a specific requests ID (RequestID()), abandoning the response once the giving
+// context.Context is done.
+func ResponseStreamContext(ctx context.Context, e EventLog, context interface{}, maxWait time.Duration, rid string, in sumex.Streams) (<-chan *coquery.Response, <-chan coquery.ResponseError) {
 	e.Log(context, "ResponseStream", "Started : RequestID[%s]", rid)
 
 	out := make(chan *coquery.Response)
@@ -95,7 +110,12 @@ func ResponseStream(e EventLog, context interface{}, maxWait time.Duration, rid
 				}
 
 				e.Log(context, "ResponseStream.GoRoutine", "Info : Received Response : ID[%s]", res.RequestID())
-				out <- res
+
+				select {
+				case out <- res:
+				case <-ctx.Done():
+				}
+
 				e.Log(context, "ResponseStream.GoRoutine", "Completed")
 				return
 
@@ -116,17 +136,30 @@ func ResponseStream(e EventLog, context interface{}, maxWait time.Duration, rid
 				}
 
 				e.Error(context, "ResponseStream.GoRoutine", res, "Info : Received Error Response : ID[%s]", res.RequestID())
-				outerr <- res
+
+				select {
+				case outerr <- res:
+				case <-ctx.Done():
+				}
+
 				e.Log(context, "ResponseStream.GoRoutine", "Completed")
 				return
 
 			case <-time.After(maxWait):
 				err := &coquery.CoError{Rid: rid, Msg: "Timeout", IError: ErrRequestTimout}
 				e.Error(context, "ResponseStream.GoRoutine", err, "Info : Received Timeout Error Response : ID[%s]", rid)
-				outerr <- err
+
+				select {
+				case outerr <- err:
+				case <-ctx.Done():
+				}
+
 				e.Log(context, "ResponseStream.GoRoutine", "Completed")
 				return
 
+			case <-ctx.Done():
+				e.Log(context, "ResponseStream.GoRoutine", "Completed : Cancelled : ID[%s]", rid)
+				return
 			}
 		}
 	}()
@@ -193,6 +226,16 @@ func (s *StreamOS) Stream(hs sumex.Streams) sumex.Streams {
 // Handle provides the implementation of the Document API that allows
 // using the sumex api.
 func (s *StreamOS) Handle(context interface{}, rqs coquery.RecordRequests, rw coquery.ResponseWriter) {
+	s.HandleContext(noContext, context, rqs, rw)
+}
+
+// HandleContext provides the implementation of the coquery.ContextDocument
+// API, the requests are given the context.Context to pass on to their
+// processors and are abandoned with an error once it is done, freeing the
+// handler from waiting on their responses. Abandoned processors still run to
+// completion, the changes they record into the journal of a rolled back
+// atomic batch are undone as they are recorded.
+func (s *StreamOS) HandleContext(ctx context.Context, context interface{}, rqs coquery.RecordRequests, rw coquery.ResponseWriter) {
 	s.Log.Log(context, "Handle", "Started : Recieved New Requests : Total[%d]", len(rqs))
 
 	total := len(rqs)
@@ -216,8 +259,16 @@ func (s *StreamOS) Handle(context interface{}, rqs coquery.RecordRequests, rw co
 
 		s.Log.Log(context, "Handle", "Info : Request[%s] : Type[%s] : Wait Period [%s]", request.RequestID(), request.RequestName(), wait)
 
+		// Requests of a query which is done are not processed.
+		if cerr := ctx.Err(); cerr != nil {
+			err = &coquery.CoError{Rid: request.RequestID(), Msg: "Cancelled", IError: ErrRequestCancelled}
+			rw.Write(context, nil, err)
+			s.Log.Error(context, "Handle", err, "Completed : Request[%s] : Type[%s] : %s", request.RequestID(), request.RequestName(), cerr)
+			return
+		}
+
 		// Collect the coquery.Response and error channels
-		rs, re := ResponseStream(s.Config.Log, context, wait, request.RequestID(), s.outport)
+		rs, re := ResponseStreamContext(ctx, s.Config.Log, context, wait, request.RequestID(), s.outport)
 
 		// Continuesly send each request into the stream of processor and await
 		// a response from the processor.
@@ -225,11 +276,14 @@ func (s *StreamOS) Handle(context interface{}, rqs coquery.RecordRequests, rw co
 			R:            request,
 			Last:         previous,
 			LastResponse: previousRes,
+			Ctx:          ctx,
 		})
 
 		select {
 		case res = <-rs:
 		case err = <-re:
+		case <-ctx.Done():
+			err = &coquery.CoError{Rid: request.RequestID(), Msg: "Cancelled", IError: ErrRequestCancelled}
 		}
 
 		// // Read the response for this requests and if possible its error.
@@ -251,6 +305,12 @@ func (s *StreamOS) Handle(context interface{}, rqs coquery.RecordRequests, rw co
 			return
 		}
 
+		// A paged response's cursor is carried along the requests after it, so
+		// the final response can report where the next page starts.
+		if res.NextCursor == "" && previousRes != nil {
+			res.NextCursor = previousRes.NextCursor
+		}
+
 		// fmt.Printf("Providing index: %d\n", index)
 
 		// If we passed, send out the response to anyone who cares.
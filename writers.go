@@ -1,6 +1,8 @@
 package coquery
 
 import (
+	"fmt"
+
 	"github.com/influx6/coquery/data"
 	"github.com/influx6/coquery/storage"
 )
@@ -11,6 +13,7 @@ import (
 type BatchResponseWriter struct {
 	Res       ResponseWriter
 	data      data.Parameters
+	grant     *Grant
 	total     int
 	collected int
 }
@@ -44,20 +47,39 @@ func (br *BatchResponseWriter) Write(context interface{}, res *Response, err Res
 	// Add the data response to the response list.
 	if res != nil {
 		br.data = append(br.data, data.Parameter{"data": res.Data})
+		br.grant = mergeGrants(br.grant, res.Grant)
 	} else {
-		br.data = append(br.data, data.Parameter{
+		failed := data.Parameter{
 			"QueryFailed": true,
 			"Error":       err.Error(),
 			"Message":     err.Message(),
-		})
+		}
+
+		if conflict, ok := err.(*ConflictError); ok {
+			failed["Conflict"] = true
+			failed["Version"] = conflict.Version
+		}
+
+		if invalid, ok := err.(*ValidationError); ok {
+			failed["Invalid"] = true
+			failed["Fields"] = invalid.Fields
+		}
+
+		if limit, ok := err.(*LimitError); ok {
+			failed["Limit"] = limit.Limit
+			failed["Max"] = limit.Max
+		}
+
+		br.data = append(br.data, failed)
 	}
 
 	br.collected++
 
 	if br.collected >= br.total {
 		return br.Res.Write(context, &Response{
-			Req:  r,
-			Data: br.data,
+			Req:   r,
+			Data:  br.data,
+			Grant: br.grant,
 		}, nil)
 	}
 
@@ -66,13 +88,85 @@ func (br *BatchResponseWriter) Write(context interface{}, res *Response, err Res
 
 //==============================================================================
 
+// AtomicResponseWriter provides a response writer for the queries of an
+// atomic batch. It holds back their responses until the batch is committed
+// and provides the journal their requests record their changes into.
+type AtomicResponseWriter struct {
+	journal   Journal
+	responses []*Response
+	err       ResponseError
+}
+
+// Journal returns the journal of the batch.
+func (a *AtomicResponseWriter) Journal() *Journal {
+	return &a.journal
+}
+
+// Write keeps the response of a query, only the first error is kept.
+func (a *AtomicResponseWriter) Write(context interface{}, res *Response, err ResponseError) error {
+	if err != nil {
+		if a.err == nil {
+			a.err = err
+		}
+
+		return nil
+	}
+
+	a.responses = append(a.responses, res)
+	return nil
+}
+
+// Failed returns true/false if any query of the batch has failed.
+func (a *AtomicResponseWriter) Failed() bool {
+	return a.err != nil
+}
+
+// Responses returns the responses of the queries in the order they were
+// written.
+func (a *AtomicResponseWriter) Responses() []*Response {
+	return a.responses
+}
+
+// Commit returns the error of the batch if any of its queries failed or did
+// not respond, after rolling back the changes recorded within its journal.
+func (a *AtomicResponseWriter) Commit(context interface{}, rid string, total int) ResponseError {
+	if a.err == nil && len(a.responses) < total {
+		a.err = &CoError{
+			Rid:    rid,
+			Msg:    "Atomic Batch Incomplete",
+			IError: fmt.Errorf("Received %d of %d responses", len(a.responses), total),
+		}
+	}
+
+	if a.err == nil {
+		return nil
+	}
+
+	if err := a.journal.Rollback(); err != nil {
+		return &CoError{
+			Rid:    rid,
+			Msg:    fmt.Sprintf("Atomic Batch Rollback Failed : %s", a.err.Error()),
+			IError: err,
+		}
+	}
+
+	return a.err
+}
+
+//==============================================================================
+
 // JSONResponseWriter provides the coquery API JSON spec writer, which ensures
-// we adequately provide proper response for our API requests.
+// we adequately provide proper response for our API requests. Responses
+// exceeding the MaxRecords or MaxBytes of its limits are replaced with the
+// *LimitError they hit. Requests asking for patches receive those of their
+// deltas if the diffs are a DiffPatcher, limited to the records replied and
+// their fields not hidden for responses served under a Grant.
 type JSONResponseWriter struct {
-	res   ResponseWriter
-	store storage.Store
-	ctx   *data.RequestContext
-	diff  Diffs
+	res    ResponseWriter
+	store  storage.Store
+	ctx    *data.RequestContext
+	diff   Diffs
+	limits *Limits
 }
 
 // Write writes out the json response for the received request.
@@ -81,9 +175,22 @@ func (br *JSONResponseWriter) Write(context interface{}, res *Response, err Resp
 		return br.res.Write(context, nil, err)
 	}
 
-	// Record the diff record and store it for reporting as needed.
-	br.diff.Put(br.store.TaintedRecords())
+	// Record the diff record of changed and removed records and store it for
+	// reporting as needed.
+	changed := append(br.store.TaintedRecords(), br.store.DeletedRecords()...)
+
+	if patcher, ok := br.diff.(DiffPatcher); ok {
+		patcher.PutPatches(changed, br.store.Patches())
+	} else {
+		br.diff.Put(changed)
+	}
+
 	br.store.ClearTainted()
+	br.store.ClearDeleted()
+
+	if lerr := br.limits.CheckResponse(br.ctx.RequestID, res); lerr != nil {
+		return br.res.Write(context, nil, lerr)
+	}
 
 	// Create the map to hold our json response.
 	mdata := make(data.Parameter)
@@ -102,6 +209,10 @@ func (br *JSONResponseWriter) Write(context interface{}, res *Response, err Resp
 	mdata["total"] = len(res.Data)
 	req = res.Req
 
+	if res.NextCursor != "" {
+		mdata["next_cursor"] = res.NextCursor
+	}
+
 	// if req == nil {
 	// 	req = &dupReq{ResponseError: err}
 	// }
@@ -113,13 +224,9 @@ func (br *JSONResponseWriter) Write(context interface{}, res *Response, err Resp
 		}, err)
 	}
 
-	var key string
-	keys := br.diff.Keys()
-	last := len(keys) - 1
-
-	if last > -1 && last < len(keys) {
-		key = keys[last]
-		mdata["delta_id"] = keys[last]
+	key := LatestDiff(br.diff)
+	if key != "" {
+		mdata["delta_id"] = key
 	}
 
 	// If we have no diffing tag then collect all the diffs and use the
@@ -171,6 +278,10 @@ func (br *JSONResponseWriter) Write(context interface{}, res *Response, err Resp
 	mdata["delta_id"] = key
 	mdata["deltas"] = diff
 
+	if patcher, ok := br.diff.(DiffPatcher); ok && br.ctx.Patches {
+		mdata["patches"] = grantedPatches(patchesOf(patcher.PatchesFrom(br.ctx.DiffTag), diff), br.store.Key(), res)
+	}
+
 	return br.res.Write(context, &Response{
 		Req:  res.Req,
 		Data: []data.Parameter{mdata},
@@ -178,3 +289,57 @@ func (br *JSONResponseWriter) Write(context interface{}, res *Response, err Resp
 }
 
 //==============================================================================
+
+// grantedPatches returns the patches of the records replied by the response
+// without the operations changing hidden fields, if it was served under a
+// Grant, else all the patches as every record is readable.
+func grantedPatches(patches map[string]data.Patch, key string, res *Response) map[string]data.Patch {
+	if res.Grant == nil {
+		return patches
+	}
+
+	replied := make(map[string]bool)
+	repliedKeys(replied, key, res.Data)
+
+	found := make(map[string]data.Patch)
+
+	for record, patch := range patches {
+		if replied[record] {
+			found[record] = hidePatch(patch, res.Grant.Hidden)
+		}
+	}
+
+	return found
+}
+
+// repliedKeys adds the keys of the records and the records they hold to the
+// replied keys, including the data of batched responses and the results of
+// response packs.
+func repliedKeys(replied map[string]bool, key string, records data.Parameters) {
+	for _, rec := range records {
+		for _, field := range []string{"results", "data"} {
+			if held, ok := rec[field].(data.Parameters); ok {
+				repliedKeys(replied, key, held)
+			}
+		}
+
+		if val, ok := rec[key]; ok {
+			replied[fmt.Sprintf("%+v", val)] = true
+		}
+	}
+}
+
+// patchesOf returns the patches of the giving records.
+func patchesOf(patches map[string]data.Patch, records []string) map[string]data.Patch {
+	found := make(map[string]data.Patch)
+
+	for _, key := range records {
+		if patch, ok := patches[key]; ok {
+			found[key] = patch
+		}
+	}
+
+	return found
+}
+
+//==============================================================================